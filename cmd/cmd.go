@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"FileNest/common/database"
 	"FileNest/common/glog"
 	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"FileNest/internal/sftpd"
 	"FileNest/router"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,12 +44,90 @@ func Run() {
 		}
 	}()
 
+	// 初始化数据库
+	db, err := database.Install(database.DBConfig{
+		Driver:   config.Database.Driver,
+		Path:     config.Database.SQLitePath,
+		Host:     config.Database.Host,
+		Port:     config.Database.Port,
+		User:     config.Database.User,
+		Password: config.Database.Password,
+		DBName:   config.Database.DBName,
+	})
+	if err != nil {
+		glog.Errorf("初始化数据库失败: %s", err)
+		os.Exit(1)
+	}
+	if err := db.AutoMigrate(
+		&model.User{},
+		&model.APIToken{},
+		&model.ACLEntry{},
+		&model.UserGroup{},
+		&model.UserGroupMember{},
+		&model.FileOwner{},
+		&model.Favorite{},
+		&model.FileHash{},
+	); err != nil {
+		glog.Errorf("数据库迁移失败: %s", err)
+		os.Exit(1)
+	}
+
+	userService := service.NewUserService()
+	if err := userService.EnsureAdmin(); err != nil {
+		glog.Errorf("初始化管理员账号失败: %s", err)
+		os.Exit(1)
+	}
+
+	// 初始化存储，各服务共用同一个驱动
+	store, err := service.NewStorage()
+	if err != nil {
+		glog.Errorf("初始化文件存储失败: %s", err)
+		os.Exit(1)
+	}
+	temp, err := service.NewTempStorage()
+	if err != nil {
+		glog.Errorf("创建临时目录失败: %s", err)
+		os.Exit(1)
+	}
+
+	tokenService := service.NewTokenService()
+	aclService := service.NewACLService()
+	fileService := service.NewFileService(store, temp)
+	uploadGCService := service.NewUploadGCService(store, temp)
+	fetchService := service.NewFetchService(store, temp)
+
+	// 启动时清理一次过期分块，之后定期清理
+	go collectUploadGarbage(uploadGCService)
+
+	// 上次运行时未完成的下载任务无法继续，标记为失败
+	if _, err := fetchService.RecoverFetchJobs(context.Background()); err != nil {
+		glog.Errorf("恢复下载任务失败: %s", err)
+	}
+
+	// 启动 SFTP 服务
+	var sftpServer *sftpd.Server
+	if config.SFTP.Enabled {
+		sftpServer, err = sftpd.NewServer(config.SFTP, fileService, userService)
+		if err != nil {
+			glog.Errorf("初始化 SFTP 服务失败: %s", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := sftpServer.ListenAndServe(); err != nil {
+				glog.Errorf("SFTP 服务异常退出: %s", err)
+			}
+		}()
+	}
+
 	// 监听系统信号
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		sig := <-sigChan
 		glog.Infof("收到系统信号: %v, 开始清理资源...", sig)
+		if sftpServer != nil {
+			sftpServer.Close()
+		}
 		if err := cache.Close(); err != nil {
 			glog.Errorf("关闭 Redis 连接失败: %s", err)
 		}
@@ -51,7 +136,7 @@ func Run() {
 
 	gin.SetMode(gin.ReleaseMode)
 	app := gin.New()
-	router.Install(app)
+	router.Install(app, temp, fileService, userService, tokenService, aclService, uploadGCService, fetchService)
 
 	// 上传大小
 	port := flag.Int("port", 9040, "port")
@@ -60,3 +145,21 @@ func Run() {
 	glog.Infof("starting server on port %d", *port)
 	log.Fatal(app.Run(fmt.Sprintf(":%d", *port)))
 }
+
+// collectUploadGarbage 立即清理一次过期分块，之后按 config.Upload.GCInterval 定期清理
+func collectUploadGarbage(uploadGCService service.UploadGCService) {
+	collect := func() {
+		if _, err := uploadGCService.CollectUploadGarbage(context.Background()); err != nil {
+			glog.Errorf("清理过期分块失败: %s", err)
+		}
+	}
+	collect()
+	if config.Upload.GCInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(config.Upload.GCInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		collect()
+	}
+}
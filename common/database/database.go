@@ -4,16 +4,26 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
+// 支持的数据库类型
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
 // DBConfig 数据库配置结构体
 type DBConfig struct {
+	Driver   string // 数据库类型，为空时使用 MySQL
+	Path     string // SQLite 数据库文件路径
 	Host     string
 	Port     string
 	User     string
@@ -25,13 +35,10 @@ var gormDB *gorm.DB
 
 // Install 初始化数据库连接
 func Install(config DBConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.User,
-		config.Password,
-		config.Host,
-		config.Port,
-		config.DBName,
-	)
+	dialector, err := newDialector(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// 配置 GORM 日志
 	newLogger := logger.New(
@@ -44,7 +51,7 @@ func Install(config DBConfig) (*gorm.DB, error) {
 		},
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger:                                   newLogger,
 		DisableForeignKeyConstraintWhenMigrating: true,
 		NamingStrategy: schema.NamingStrategy{
@@ -70,6 +77,30 @@ func Install(config DBConfig) (*gorm.DB, error) {
 	return gormDB, nil
 }
 
+// newDialector 根据数据库类型创建 GORM 驱动
+func newDialector(config DBConfig) (gorm.Dialector, error) {
+	switch config.Driver {
+	case "", DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			config.User,
+			config.Password,
+			config.Host,
+			config.Port,
+			config.DBName,
+		)
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		// 内嵌数据库，无需单独部署 MySQL
+		if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+		dsn := config.Path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.Driver)
+	}
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	if gormDB == nil {
@@ -1 +1,167 @@
 package middlewares
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 当前用户与 API 令牌在 gin.Context 中的键
+const (
+	CurrentUserKey  = "currentUser"
+	CurrentTokenKey = "currentToken"
+)
+
+// Auth 校验 Authorization: Bearer 登录令牌，并将当前用户写入上下文
+//
+// 只接受交互式登录的令牌，用于账号与令牌管理等不允许脚本调用的接口。
+func Auth(userService service.UserService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := BearerToken(ctx)
+		if token == "" {
+			response.Unauthorized(ctx, "请先登录")
+			return
+		}
+
+		user, err := userService.Authenticate(token)
+		if err != nil {
+			glog.Warnf("访问令牌校验失败: %s, 地址: %s", err, ctx.ClientIP())
+			response.Unauthorized(ctx, err.Error())
+			return
+		}
+
+		setPrincipal(ctx, &auth.Principal{User: user})
+		ctx.Next()
+	}
+}
+
+// AuthWithToken 与 Auth 相同，但同时接受个人 API 令牌，用于文件接口
+func AuthWithToken(userService service.UserService, tokenService service.TokenService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := BearerToken(ctx)
+		if token == "" {
+			response.Unauthorized(ctx, "请先登录")
+			return
+		}
+
+		principal, err := authenticate(userService, tokenService, token)
+		if err != nil {
+			glog.Warnf("访问令牌校验失败: %s, 地址: %s", err, ctx.ClientIP())
+			response.Unauthorized(ctx, err.Error())
+			return
+		}
+
+		setPrincipal(ctx, principal)
+		ctx.Next()
+	}
+}
+
+// BasicAuth 供 WebDAV 使用，同时接受 Basic 认证与 Bearer 令牌
+//
+// Basic 认证的密码也可以是 API 令牌，此时忽略用户名。
+func BasicAuth(userService service.UserService, tokenService service.TokenService, realm string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var (
+			principal *auth.Principal
+			err       error
+		)
+		if token := BearerToken(ctx); token != "" {
+			principal, err = authenticate(userService, tokenService, token)
+		} else if username, password, ok := ctx.Request.BasicAuth(); ok {
+			if strings.HasPrefix(password, model.APITokenPrefix) {
+				principal, err = authenticate(userService, tokenService, password)
+			} else {
+				var user *model.User
+				user, err = userService.VerifyPassword(username, password)
+				principal = &auth.Principal{User: user}
+			}
+		} else {
+			ctx.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if err != nil {
+			glog.Warnf("WebDAV 认证失败: %s, 地址: %s", err, ctx.ClientIP())
+			ctx.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		setPrincipal(ctx, principal)
+		ctx.Next()
+	}
+}
+
+// RequireAdmin 仅允许管理员访问，需在 Auth 之后使用
+func RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := GetCurrentUser(ctx)
+		if user == nil || !user.IsAdmin() {
+			response.Forbidden(ctx, "没有权限")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// GetCurrentUser 获取当前登录用户，未登录时返回 nil
+func GetCurrentUser(ctx *gin.Context) *model.User {
+	value, ok := ctx.Get(CurrentUserKey)
+	if !ok {
+		return nil
+	}
+	user, _ := value.(*model.User)
+	return user
+}
+
+// GetCurrentToken 获取当前请求使用的 API 令牌，交互式登录时返回 nil
+func GetCurrentToken(ctx *gin.Context) *model.APIToken {
+	value, ok := ctx.Get(CurrentTokenKey)
+	if !ok {
+		return nil
+	}
+	token, _ := value.(*model.APIToken)
+	return token
+}
+
+// authenticate 根据令牌前缀选择 API 令牌或登录令牌校验
+func authenticate(userService service.UserService, tokenService service.TokenService, token string) (*auth.Principal, error) {
+	if strings.HasPrefix(token, model.APITokenPrefix) {
+		user, apiToken, err := tokenService.Authenticate(token)
+		if err != nil {
+			return nil, err
+		}
+		return &auth.Principal{User: user, Token: apiToken}, nil
+	}
+
+	user, err := userService.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{User: user}, nil
+}
+
+// setPrincipal 将身份写入 gin.Context 与请求的 context，后者会传递给 FileService
+func setPrincipal(ctx *gin.Context, principal *auth.Principal) {
+	ctx.Set(CurrentUserKey, principal.User)
+	if principal.Token != nil {
+		ctx.Set(CurrentTokenKey, principal.Token)
+	}
+	ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(ctx.Request.Context(), principal))
+}
+
+// BearerToken 从 Authorization 头中取出 Bearer 令牌
+func BearerToken(ctx *gin.Context) string {
+	header := ctx.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"FileNest/internal/model"
+	"context"
+	"fmt"
+	"io/fs"
+)
+
+type principalKey struct{}
+
+// Principal 发起请求的身份
+type Principal struct {
+	User *model.User
+	// Token 使用 API 令牌访问时非空，权限受令牌范围限制
+	Token *model.APIToken
+}
+
+// WithPrincipal 将身份写入 context
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext 获取 context 中的身份，没有时返回 nil（服务内部调用）
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// ForbiddenError 没有权限，可通过 errors.Is(err, fs.ErrPermission) 判断
+type ForbiddenError struct {
+	Path string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("没有权限访问: /%s", e.Path)
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == fs.ErrPermission
+}
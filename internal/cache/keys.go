@@ -7,10 +7,15 @@ import (
 
 const (
 	// 缓存过期时间（秒）
-	FileListExpiration  = 300  // 5分钟
-	FileStatsExpiration = 300  // 5分钟
-	SearchExpiration    = 60   // 1分钟
-	FavoriteExpiration  = 1800 // 30分钟
+	FileListExpiration      = 300   // 5分钟
+	FileStatsExpiration     = 300   // 5分钟
+	SearchExpiration        = 60    // 1分钟
+	FavoriteExpiration      = 1800  // 30分钟
+	TusUploadExpiration     = 86400 // 24小时，足够大文件断点续传
+	UploadSessionExpiration = 86400 // 24小时，收到分块后顺延
+	FetchJobExpiration      = 86400 // 24小时，任务更新后顺延
+	FetchLeaseExpiration    = 30    // 30秒，运行任务的实例定期续期
+	FolderUsageExpiration   = 3600  // 1小时，过期后重新统计，纠正绕过服务修改存储造成的偏差
 )
 
 // 文件列表缓存键
@@ -31,13 +36,45 @@ func SearchKey(keyword string) string {
 // 搜索历史缓存键
 const SearchHistoryKey = "file:search:history"
 
-// 收藏夹缓存键
-const FavoriteKey = "file:favorites"
+// 用户收藏列表缓存键
+func FavoriteListKey(userID uint) string {
+	return fmt.Sprintf("file:favorites:%d", userID)
+}
+
+// 所有用户收藏列表的缓存键模式
+const FavoriteListPattern = "file:favorites:*"
+
+// 上传会话缓存键
+func UploadSessionKey(id string) string {
+	return fmt.Sprintf("file:upload:session:%s", id)
+}
+
+// 上传事件的发布频道，key 为上传会话的缓存键
+func UploadEventChannel(key string) string {
+	return fmt.Sprintf("file:upload:events:%s", key)
+}
 
-// 上传进度缓存键
-func UploadProgressKey(path, fileName string) string {
-	fullPath := filepath.Join(path, fileName)
-	return fmt.Sprintf("file:upload:progress:%s", filepath.Clean(fullPath))
+// 下载任务缓存键
+func FetchJobKey(id string) string {
+	return fmt.Sprintf("file:fetch:%s", id)
+}
+
+// 所有下载任务的缓存键模式
+const FetchJobPattern = "file:fetch:*"
+
+// 下载任务租约缓存键，运行任务的实例退出后过期
+func FetchLeaseKey(id string) string {
+	return fmt.Sprintf("file:fetch-lease:%s", id)
+}
+
+// tus 上传状态缓存键
+func TusUploadKey(id string) string {
+	return fmt.Sprintf("file:upload:tus:%s", id)
+}
+
+// 目录配额用量缓存键，写入、删除与移动时增量更新
+func FolderUsageKey(folder string) string {
+	return fmt.Sprintf("file:quota:folder:%s", folder)
 }
 
 // 获取目录相关的所有缓存键模式
@@ -57,3 +94,18 @@ func GetFileCacheKeys(path string) []string {
 		FileStatsKey(dir),
 	}
 }
+
+// 已吊销的访问令牌缓存键
+func RevokedTokenKey(jti string) string {
+	return fmt.Sprintf("auth:revoked:%s", jti)
+}
+
+// 有效的刷新令牌缓存键，按用户分组以便一次吊销某个用户的全部刷新令牌
+func RefreshTokenKey(userID, jti string) string {
+	return fmt.Sprintf("auth:refresh:%s:%s", userID, jti)
+}
+
+// 某个用户全部刷新令牌的缓存键模式
+func UserRefreshTokenPattern(userID string) string {
+	return fmt.Sprintf("auth:refresh:%s:*", userID)
+}
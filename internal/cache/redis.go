@@ -91,6 +91,35 @@ func Del(keys ...string) error {
 	return nil
 }
 
+// GetDel 获取并删除缓存，可用于一次性令牌
+func GetDel(key string) (string, error) {
+	val, err := redisClient.GetDel(ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		glog.Errorf("获取并删除缓存失败: key=%s, error=%v", key, err)
+	}
+	return val, err
+}
+
+// Exists 判断缓存键是否存在
+func Exists(key string) (bool, error) {
+	n, err := redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		glog.Errorf("查询缓存键失败: key=%s, error=%v", key, err)
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Keys 查找匹配模式的缓存键
+func Keys(pattern string) ([]string, error) {
+	keys, err := redisClient.Keys(ctx, pattern).Result()
+	if err != nil {
+		glog.Errorf("查找缓存键失败: pattern=%s, error=%v", pattern, err)
+		return nil, err
+	}
+	return keys, nil
+}
+
 // DelByPattern 根据模式删除缓存
 func DelByPattern(pattern string) error {
 	keys, err := redisClient.Keys(ctx, pattern).Result()
@@ -136,6 +165,27 @@ func HGetAll(key string) (map[string]string, error) {
 	return val, nil
 }
 
+// hIncrByIfExistsScript 哈希表存在时按 ARGV 中的字段与增量依次累加
+var hIncrByIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+for i = 1, #ARGV, 2 do
+	redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+return 1
+`)
+
+// HIncrByIfExists 哈希表存在时累加字段，values 为交替的字段名与增量；哈希表不存在时不创建，返回 false
+func HIncrByIfExists(key string, values ...interface{}) (bool, error) {
+	n, err := hIncrByIfExistsScript.Run(ctx, redisClient, []string{key}, values...).Int()
+	if err != nil {
+		glog.Errorf("累加哈希表字段失败: key=%s, error=%v", key, err)
+		return false, err
+	}
+	return n == 1, nil
+}
+
 // Expire 设置过期时间
 func Expire(key string, expiration time.Duration) error {
 	if err := redisClient.Expire(ctx, key, expiration).Err(); err != nil {
@@ -145,6 +195,26 @@ func Expire(key string, expiration time.Duration) error {
 	return nil
 }
 
+// Publish 向频道发布消息
+func Publish(channel string, message interface{}) error {
+	if err := redisClient.Publish(ctx, channel, message).Err(); err != nil {
+		glog.Errorf("发布消息失败: channel=%s, error=%v", channel, err)
+		return err
+	}
+	return nil
+}
+
+// Subscribe 订阅频道，确认订阅成功后返回；subCtx 结束后不再接收消息，使用完后需要 Close
+func Subscribe(subCtx context.Context, channels ...string) (*redis.PubSub, error) {
+	pubsub := redisClient.Subscribe(subCtx, channels...)
+	if _, err := pubsub.Receive(subCtx); err != nil {
+		pubsub.Close()
+		glog.Errorf("订阅频道失败: channels=%v, error=%v", channels, err)
+		return nil, err
+	}
+	return pubsub, nil
+}
+
 // Close 关闭 Redis 连接
 func Close() error {
 	if redisClient != nil {
@@ -0,0 +1,10 @@
+package config
+
+type ACLConfig struct {
+	// DefaultPermissions 路径及其所有上级目录都没有 ACL 时，普通用户拥有的权限
+	DefaultPermissions []string `mapstructure:"default_permissions"`
+}
+
+var ACL = &ACLConfig{
+	DefaultPermissions: []string{"read", "write", "delete"},
+}
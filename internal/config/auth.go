@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+type AuthConfig struct {
+	// JWTSecret 令牌签名密钥，为空时启动时随机生成（重启后已签发的令牌失效）
+	JWTSecret       string        `mapstructure:"jwt_secret"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+	// AdminUsername 首次启动时创建的管理员账号
+	AdminUsername string `mapstructure:"admin_username"`
+	// AdminPassword 管理员初始密码，为空时随机生成并打印到日志
+	AdminPassword string `mapstructure:"admin_password"`
+}
+
+var Auth = &AuthConfig{
+	JWTSecret:       "",
+	AccessTokenTTL:  2 * time.Hour,
+	RefreshTokenTTL: 7 * 24 * time.Hour,
+	AdminUsername:   "admin",
+	AdminPassword:   "",
+}
@@ -0,0 +1,23 @@
+package config
+
+type DatabaseConfig struct {
+	// Driver 数据库类型，mysql 或 sqlite
+	Driver   string `mapstructure:"driver"`
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	DBName   string `mapstructure:"db_name"`
+	// SQLitePath 使用 sqlite 时的数据库文件路径
+	SQLitePath string `mapstructure:"sqlite_path"`
+}
+
+var Database = &DatabaseConfig{
+	Driver:     "mysql",
+	Host:       "127.0.0.1",
+	Port:       "3306",
+	User:       "root",
+	Password:   "",
+	DBName:     "filenest",
+	SQLitePath: "data/filenest.db",
+}
@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+type FetchConfig struct {
+	// Workers 同时执行的下载任务数，超出的任务排队等待
+	Workers int `mapstructure:"workers"`
+	// MaxSize 单个下载的最大字节数，大小未知的下载在写入过程中限制；0 表示只受上传策略与配额限制
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxRetries 下载中断后的最大重试次数，重试时通过 Range 请求续传
+	MaxRetries int `mapstructure:"max_retries"`
+	// RetryDelay 第一次重试前的等待时间，之后每次翻倍
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	// ResponseTimeout 等待响应头的超时时间，下载过程本身不限时
+	ResponseTimeout time.Duration `mapstructure:"response_timeout"`
+	// AllowPrivateNetworks 是否允许访问本机与内网地址，默认禁止以免被用来探测内网
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+var Fetch = &FetchConfig{
+	Workers:              2,
+	MaxSize:              10 << 30, // 10 GiB
+	MaxRetries:           5,
+	RetryDelay:           2 * time.Second,
+	ResponseTimeout:      30 * time.Second,
+	AllowPrivateNetworks: false,
+}
@@ -0,0 +1,24 @@
+package config
+
+// QuotaLimit 存储配额，为 0 时不限制
+type QuotaLimit struct {
+	// MaxBytes 最大占用字节数
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// MaxFiles 最大文件数
+	MaxFiles int64 `mapstructure:"max_files"`
+}
+
+type QuotaConfig struct {
+	// User 普通用户默认的配额，按用户上传或复制的文件统计，管理员不受限制
+	User QuotaLimit `mapstructure:"user"`
+	// Users 按用户名覆盖默认配额
+	Users map[string]QuotaLimit `mapstructure:"users"`
+	// Folders 目录配额，键为相对存储根目录的路径，空字符串表示整个存储
+	Folders map[string]QuotaLimit `mapstructure:"folders"`
+}
+
+var Quota = &QuotaConfig{
+	User:    QuotaLimit{},
+	Users:   map[string]QuotaLimit{},
+	Folders: map[string]QuotaLimit{},
+}
@@ -0,0 +1,19 @@
+package config
+
+type SFTPConfig struct {
+	// Enabled 是否启动 SFTP 服务
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
+	// HostKeyPath 主机私钥路径，文件不存在时自动生成
+	HostKeyPath string `mapstructure:"host_key_path"`
+	// AuthorizedKeysDir 公钥目录，每个 FileNest 账号一个以用户名命名的文件，格式与 OpenSSH 的 authorized_keys 相同；
+	// 公钥只能登录其所在文件对应的账号，为空时只允许密码登录
+	AuthorizedKeysDir string `mapstructure:"authorized_keys_dir"`
+}
+
+var SFTP = &SFTPConfig{
+	Enabled:           false,
+	Port:              2022,
+	HostKeyPath:       "./data/sftp_host_ed25519_key",
+	AuthorizedKeysDir: "./data/sftp_authorized_keys",
+}
@@ -0,0 +1,36 @@
+package config
+
+// 存储类型
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+type StorageConfig struct {
+	// Type 存储类型，local 或 s3
+	Type string   `mapstructure:"type"`
+	S3   S3Config `mapstructure:"s3"`
+}
+
+// S3Config S3 兼容对象存储配置（AWS S3、MinIO 等）
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	Bucket    string `mapstructure:"bucket"`
+	Region    string `mapstructure:"region"`
+	// Prefix 对象键前缀，为空时使用整个存储桶
+	Prefix string `mapstructure:"prefix"`
+	UseSSL bool   `mapstructure:"use_ssl"`
+	// PartSize 分片上传的分片大小（字节）
+	PartSize uint64 `mapstructure:"part_size"`
+}
+
+var Storage = &StorageConfig{
+	Type: StorageLocal,
+	S3: S3Config{
+		Endpoint: "127.0.0.1:9000",
+		Bucket:   "filenest",
+		PartSize: 16 * 1024 * 1024,
+	},
+}
@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+// UploadPolicy 上传策略，字段为零值时不限制
+type UploadPolicy struct {
+	// MaxFileSize 单个文件的最大字节数
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// MaxChunks 分块上传的最大分块数
+	MaxChunks int `mapstructure:"max_chunks"`
+	// AllowedExtensions 允许上传的扩展名，如 ".pdf"、".tar.gz"，不区分大小写；为空时不限制
+	AllowedExtensions []string `mapstructure:"allowed_extensions"`
+	// BlockedExtensions 禁止上传的扩展名，优先于 AllowedExtensions
+	BlockedExtensions []string `mapstructure:"blocked_extensions"`
+	// AllowedMIMETypes 允许上传的文件类型，按文件开头的内容识别，支持 "image/*"；为空时不限制
+	AllowedMIMETypes []string `mapstructure:"allowed_mime_types"`
+	// BlockedMIMETypes 禁止上传的文件类型，优先于 AllowedMIMETypes
+	BlockedMIMETypes []string `mapstructure:"blocked_mime_types"`
+}
+
+type UploadConfig struct {
+	// ChunkTTL 分块最后一次写入后保留的时长，超过后由后台任务清理
+	ChunkTTL time.Duration `mapstructure:"chunk_ttl"`
+	// GCInterval 清理过期分块的间隔，为 0 时只在启动时清理一次
+	GCInterval time.Duration `mapstructure:"gc_interval"`
+	// Policy 默认的上传策略
+	Policy UploadPolicy `mapstructure:"policy"`
+	// Folders 按目录覆盖上传策略，键为相对存储根目录的路径，文件所在的最近一个目录生效
+	Folders map[string]UploadPolicy `mapstructure:"folders"`
+	// MaxBatchFiles 一次批量上传的最大文件数
+	MaxBatchFiles int `mapstructure:"max_batch_files"`
+}
+
+var Upload = &UploadConfig{
+	ChunkTTL:   24 * time.Hour,
+	GCInterval: time.Hour,
+	Policy: UploadPolicy{
+		MaxChunks: 10000,
+	},
+	Folders:       map[string]UploadPolicy{},
+	MaxBatchFiles: 1000,
+}
@@ -0,0 +1,13 @@
+package consts
+
+const (
+	// ArchiveFormatZip ZIP 归档
+	ArchiveFormatZip = "zip"
+	// ArchiveFormatTar 不压缩的 tar 归档
+	ArchiveFormatTar = "tar"
+	// ArchiveFormatTarGz gzip 压缩的 tar 归档
+	ArchiveFormatTarGz = "tar.gz"
+
+	// ArchiveManifestName 打包时有文件无法读取，在归档末尾记录这些文件及原因的清单
+	ArchiveManifestName = "FILENEST-ERRORS.txt"
+)
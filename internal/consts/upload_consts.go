@@ -14,4 +14,59 @@ const (
 	TempDir = "./temp"
 	// UploadDir 上传文件目录
 	UploadDir = "./upload"
+	// TusUploadDir 临时目录中存放 tus 上传内容的子目录
+	TusUploadDir = ".tus"
+	// UploadSessionDir 临时目录中存放上传会话分块的子目录
+	UploadSessionDir = ".sessions"
+	// RangeUploadDir 临时目录中存放按 Content-Range 分段上传内容的子目录
+	RangeUploadDir = ".ranges"
+	// FetchDir 临时目录中存放下载任务内容的子目录
+	FetchDir = ".fetch"
+	// MergeTempDir 临时目录中记录合并临时文件位置的子目录
+	MergeTempDir = ".merge"
+	// MaxUploadChunks 单个上传会话的最大分块数
+	MaxUploadChunks = 10000
+	// MergeTempPrefix 合并分块时在目标目录中写入的临时文件名前缀
+	MergeTempPrefix = ".filenest-merge-"
+
+	// UploadStatusUploading 上传中
+	UploadStatusUploading = "uploading"
+	// UploadStatusDone 上传完成
+	UploadStatusDone = "done"
+	// UploadStatusError 上传失败
+	UploadStatusError = "error"
+	// UploadStatusSkipped 批量上传时因同名文件已存在而跳过
+	UploadStatusSkipped = "skipped"
+
+	// ConflictError 同名文件已存在时报错，批量上传的默认处理方式
+	ConflictError = "error"
+	// ConflictOverwrite 覆盖同名文件
+	ConflictOverwrite = "overwrite"
+	// ConflictSkip 跳过同名文件
+	ConflictSkip = "skip"
+	// ConflictRename 自动重命名为 "name (1).ext"
+	ConflictRename = "rename"
+
+	// FetchStatusPending 下载任务排队中
+	FetchStatusPending = "pending"
+	// FetchStatusDownloading 下载中
+	FetchStatusDownloading = "downloading"
+	// FetchStatusCanceled 下载任务已取消
+	FetchStatusCanceled = "canceled"
+
+	// UploadEventStatus 订阅时推送的当前状态
+	UploadEventStatus = "status"
+	// UploadEventChunkReceived 收到一个分块
+	UploadEventChunkReceived = "chunk-received"
+	// UploadEventMergeProgress 合并进度
+	UploadEventMergeProgress = "merge-progress"
+	// UploadEventCompleted 上传完成
+	UploadEventCompleted = "completed"
+	// UploadEventError 上传失败
+	UploadEventError = "error"
+
+	// ChecksumMD5 MD5 摘要
+	ChecksumMD5 = "md5"
+	// ChecksumSHA256 SHA-256 摘要，未指定算法时使用
+	ChecksumSHA256 = "sha256"
 )
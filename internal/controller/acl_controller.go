@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/common/middlewares"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ACLController struct {
+	aclService service.ACLService
+}
+
+func NewACLController(aclService service.ACLService) *ACLController {
+	return &ACLController{
+		aclService: aclService,
+	}
+}
+
+// GetACL 获取路径的 ACL
+func (h *ACLController) GetACL(ctx *gin.Context) {
+	path := ctx.Query("path")
+	user := middlewares.GetCurrentUser(ctx)
+	glog.Infof("收到获取 ACL 请求，用户: %s, 路径: %s", user.Username, path)
+
+	info, err := h.aclService.GetACL(user, path)
+	if err != nil {
+		glog.Errorf("获取 ACL 失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, info)
+}
+
+// SetACL 设置路径的 ACL
+func (h *ACLController) SetACL(ctx *gin.Context) {
+	var req struct {
+		Path    string `json:"path"`
+		Entries []struct {
+			SubjectType string `json:"subjectType"` // user 或 group
+			SubjectID   uint   `json:"subjectId"`
+			SubjectName string `json:"subjectName"` // 与 subjectId 二选一
+			// Permissions 权限或角色，如 ["read", "write"] 或 ["editor"]
+			Permissions []string `json:"permissions"`
+		} `json:"entries"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	user := middlewares.GetCurrentUser(ctx)
+	glog.Infof("收到设置 ACL 请求，用户: %s, 路径: %s, 条目数: %d", user.Username, req.Path, len(req.Entries))
+
+	entries := make([]model.ACLEntry, 0, len(req.Entries))
+	for _, e := range req.Entries {
+		entries = append(entries, model.ACLEntry{
+			SubjectType: e.SubjectType,
+			SubjectID:   e.SubjectID,
+			SubjectName: e.SubjectName,
+			Permissions: strings.Join(e.Permissions, ","),
+		})
+	}
+
+	if err := h.aclService.SetACL(user, req.Path, entries); err != nil {
+		glog.Errorf("设置 ACL 失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// RemoveACL 删除路径的 ACL，恢复继承
+func (h *ACLController) RemoveACL(ctx *gin.Context) {
+	path := ctx.Query("path")
+	user := middlewares.GetCurrentUser(ctx)
+	glog.Infof("收到删除 ACL 请求，用户: %s, 路径: %s", user.Username, path)
+
+	if err := h.aclService.RemoveACL(user, path); err != nil {
+		glog.Errorf("删除 ACL 失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
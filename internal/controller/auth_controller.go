@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/common/middlewares"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct {
+	userService service.UserService
+}
+
+func NewAuthController(userService service.UserService) *AuthController {
+	return &AuthController{
+		userService: userService,
+	}
+}
+
+// Login 登录
+func (h *AuthController) Login(ctx *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+	glog.Infof("收到登录请求，用户名: %s, 地址: %s", req.Username, ctx.ClientIP())
+
+	tokens, user, err := h.userService.Login(req.Username, req.Password)
+	if err != nil {
+		glog.Warnf("登录失败: %s, 用户名: %s", err, req.Username)
+		response.Error(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, map[string]interface{}{
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"tokenType":    tokens.TokenType,
+		"expiresIn":    tokens.ExpiresIn,
+		"user":         user,
+	})
+}
+
+// Refresh 刷新令牌
+func (h *AuthController) Refresh(ctx *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		response.Error(ctx, "刷新令牌不能为空")
+		return
+	}
+
+	tokens, err := h.userService.Refresh(req.RefreshToken)
+	if err != nil {
+		glog.Warnf("刷新令牌失败: %s", err)
+		response.Unauthorized(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, tokens)
+}
+
+// Logout 退出登录
+func (h *AuthController) Logout(ctx *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	// 刷新令牌可选，请求体为空时只吊销访问令牌
+	_ = ctx.ShouldBindJSON(&req)
+
+	if err := h.userService.Logout(middlewares.BearerToken(ctx), req.RefreshToken); err != nil {
+		glog.Errorf("退出登录失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+
+	glog.Infof("用户退出登录: %s", middlewares.GetCurrentUser(ctx).Username)
+	response.Success(ctx, nil)
+}
+
+// Me 获取当前登录用户
+func (h *AuthController) Me(ctx *gin.Context) {
+	response.Success(ctx, middlewares.GetCurrentUser(ctx))
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"FileNest/internal/dav"
+	"FileNest/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+// DavMethods WebDAV 使用的 HTTP 方法
+var DavMethods = []string{
+	"OPTIONS", "GET", "HEAD", "POST", "PUT", "DELETE",
+	"MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "PROPFIND", "PROPPATCH",
+}
+
+type DavController struct {
+	handler *webdav.Handler
+}
+
+func NewDavController(fileService service.FileService, prefix string) *DavController {
+	return &DavController{
+		handler: dav.NewHandler(fileService, prefix),
+	}
+}
+
+// ServeDAV 处理 WebDAV 请求
+func (h *DavController) ServeDAV(ctx *gin.Context) {
+	h.handler.ServeHTTP(ctx.Writer, ctx.Request)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FetchController struct {
+	fetchService service.FetchService
+}
+
+func NewFetchController(fetchService service.FetchService) *FetchController {
+	return &FetchController{
+		fetchService: fetchService,
+	}
+}
+
+// CreateFetchJob 创建从 URL 下载文件的后台任务
+func (h *FetchController) CreateFetchJob(ctx *gin.Context) {
+	var req struct {
+		URL      string `json:"url"`
+		Path     string `json:"path"`
+		FileName string `json:"fileName"` // 为空时使用 URL 中的文件名
+		Override bool   `json:"override"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	glog.Infof("收到创建下载任务请求，地址: %s, 路径: %s, 文件名: %s, 是否覆盖: %v", req.URL, req.Path, req.FileName, req.Override)
+
+	job, err := h.fetchService.CreateFetchJob(ctx.Request.Context(), req.URL, req.Path, req.FileName, req.Override)
+	if err != nil {
+		glog.Errorf("创建下载任务失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, job)
+}
+
+// GetFetchJob 获取下载任务的状态与进度
+func (h *FetchController) GetFetchJob(ctx *gin.Context) {
+	id := ctx.Query("id")
+	glog.Infof("收到获取下载任务请求，任务: %s", id)
+
+	job, err := h.fetchService.GetFetchJob(ctx.Request.Context(), id)
+	if err != nil {
+		glog.Errorf("获取下载任务失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, job)
+}
+
+// CancelFetchJob 取消下载任务
+func (h *FetchController) CancelFetchJob(ctx *gin.Context) {
+	id := ctx.Query("id")
+	glog.Infof("收到取消下载任务请求，任务: %s", id)
+
+	job, err := h.fetchService.CancelFetchJob(ctx.Request.Context(), id)
+	if err != nil {
+		glog.Errorf("取消下载任务失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, job)
+}
@@ -3,18 +3,27 @@ package controller
 import (
 	"FileNest/common/glog"
 	"FileNest/internal/consts"
+	"FileNest/internal/model"
 	"FileNest/internal/service"
+	"FileNest/internal/utils/download"
 	"FileNest/internal/utils/response"
+	"errors"
 	"fmt"
 	"io"
-	"net/url"
-	"os"
-	"path/filepath"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// sseHeartbeatInterval 没有事件时发送心跳的间隔，避免连接被代理超时断开
+const sseHeartbeatInterval = 15 * time.Second
+
 type FileController struct {
 	fileService service.FileService
 }
@@ -30,7 +39,7 @@ func (h *FileController) GetFileList(ctx *gin.Context) {
 	path := ctx.Query("path")
 	glog.Infof("收到获取文件列表请求，路径: %s", path)
 
-	list, err := h.fileService.GetFileList(path)
+	list, err := h.fileService.GetFileList(ctx.Request.Context(), path)
 	if err != nil {
 		glog.Errorf("获取文件列表失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -41,20 +50,110 @@ func (h *FileController) GetFileList(ctx *gin.Context) {
 }
 
 // DownloadFile 下载文件
+//
+// 缓存与范围请求的处理见 download.ServeFile。disposition 为 inline 时浏览器直接预览（如 PDF、图片、视频），默认为 attachment。
 func (h *FileController) DownloadFile(ctx *gin.Context) {
 	path := ctx.Query("path")
-	absPath, err := h.fileService.DownloadFile(path)
+	disposition := ctx.DefaultQuery("disposition", download.Attachment)
+	if disposition != download.Attachment && disposition != download.Inline {
+		response.Error(ctx, fmt.Sprintf("不支持的 disposition: %s", disposition))
+		return
+	}
+	if info, err := h.fileService.StatFile(ctx.Request.Context(), path); err == nil && info.IsDir() {
+		h.downloadArchive(ctx, path)
+		return
+	}
+
+	file, info, err := h.fileService.DownloadFile(ctx.Request.Context(), path)
 	if err != nil {
 		response.Error(ctx, err.Error())
 		return
 	}
-	// 对文件名进行 URL 编码，并设置 Content-Disposition 响应头
-	fileName := filepath.Base(absPath)
-	encodedFileName := url.QueryEscape(fileName)
-	ctx.Set("Content-Type", "application/octet-stream")
-	ctx.Set("Content-Disposition", "attachment; filename*=UTF-8''"+encodedFileName)
+	defer file.Close()
+
+	download.ServeFile(ctx.Writer, ctx.Request, file, info, disposition)
+}
+
+// downloadArchive 将文件夹打包下载，format 参数为 zip（默认）、tar 或 tar.gz
+func (h *FileController) downloadArchive(ctx *gin.Context, dirPath string) {
+	format := ctx.DefaultQuery("format", consts.ArchiveFormatZip)
+	// 根目录没有名称，使用应用名
+	dirName := path.Base(strings.Trim(dirPath, "/"))
+	if dirName == "." {
+		dirName = "FileNest"
+	}
+	glog.Infof("收到打包下载请求，路径: %s, 格式: %s", dirPath, format)
+
+	streamArchive(ctx, dirName, format, func(w io.Writer) error {
+		return h.fileService.ArchiveDir(ctx.Request.Context(), dirPath, format, w)
+	})
+}
+
+// DownloadArchive 将选中的多个文件或文件夹打包为一个归档下载
+//
+// 参数可以是 JSON 或表单：paths 为要下载的路径，
+// format 为 zip（默认）、tar 或 tar.gz，name 为归档的文件名（不含扩展名），默认为 FileNest。
+func (h *FileController) DownloadArchive(ctx *gin.Context) {
+	var req struct {
+		Paths  []string `json:"paths" form:"paths"`
+		Format string   `json:"format" form:"format"`
+		Name   string   `json:"name" form:"name"`
+	}
+
+	if err := ctx.ShouldBind(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+	if req.Format == "" {
+		req.Format = consts.ArchiveFormatZip
+	}
+	if req.Name == "" {
+		req.Name = "FileNest"
+	}
+	glog.Infof("收到批量下载请求，路径: %v, 格式: %s", req.Paths, req.Format)
+
+	streamArchive(ctx, req.Name, req.Format, func(w io.Writer) error {
+		return h.fileService.ArchivePaths(ctx.Request.Context(), req.Paths, req.Format, w)
+	})
+}
+
+// streamArchive 以附件形式发送 write 生成的 format 格式归档，name 为不含扩展名的文件名
+//
+// write 在写入前校验失败时返回 JSON 错误；已开始发送后出错通常是客户端断开，只记录日志。
+func streamArchive(ctx *gin.Context, name, format string, write func(w io.Writer) error) {
+	contentType, ok := archiveContentTypes[format]
+	if !ok {
+		response.Error(ctx, fmt.Sprintf("不支持的归档格式: %s", format))
+		return
+	}
+
+	// 边打包边发送，大小未知，不设置 Content-Length
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", download.ContentDisposition(download.Attachment, name+"."+format))
+	ctx.Header("X-Accel-Buffering", "no")
+	if ctx.Request.Method == http.MethodHead {
+		ctx.Status(http.StatusOK)
+		return
+	}
+	if err := write(ctx.Writer); err != nil {
+		if ctx.Writer.Written() {
+			glog.Errorf("打包下载中断: %s", err)
+			return
+		}
+		for _, header := range []string{"Content-Type", "Content-Disposition", "X-Accel-Buffering"} {
+			ctx.Writer.Header().Del(header)
+		}
+		glog.Errorf("打包下载失败: %s", err)
+		response.Error(ctx, err.Error())
+	}
+}
 
-	ctx.File(absPath)
+// archiveContentTypes 归档格式对应的 Content-Type
+var archiveContentTypes = map[string]string{
+	consts.ArchiveFormatZip:   "application/zip",
+	consts.ArchiveFormatTar:   "application/x-tar",
+	consts.ArchiveFormatTarGz: "application/gzip",
 }
 
 // CreateFolder 创建文件夹
@@ -64,7 +163,7 @@ func (h *FileController) CreateFolder(ctx *gin.Context) {
 		response.Error(ctx, "path is empty")
 		return
 	}
-	err := h.fileService.CreateFolder(path)
+	err := h.fileService.CreateFolder(ctx.Request.Context(), path)
 	if err != nil {
 		response.Error(ctx, err.Error())
 		return
@@ -78,7 +177,7 @@ func (h *FileController) DeleteFile(ctx *gin.Context) {
 	force := ctx.Query("force") == "true"
 	glog.Infof("收到删除文件请求，路径: %s, 强制删除: %v", path, force)
 
-	err := h.fileService.DeleteFile(path, force)
+	err := h.fileService.DeleteFile(ctx.Request.Context(), path, force)
 	if err != nil {
 		glog.Errorf("删除文件失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -107,125 +206,230 @@ func (h *FileController) UploadFile(ctx *gin.Context) {
 	override := ctx.PostForm("override") == "true"
 	glog.Infof("收到文件上传请求，文件名: %s, 路径: %s, 是否覆盖: %v", fileName, path, override)
 
-	// 规范化路径
-	path = filepath.Clean(path)
-	if path == "." {
-		path = ""
+	src, err := file.Open()
+	if err != nil {
+		glog.Errorf("读取上传文件失败: %s", err)
+		response.Error(ctx, "读取上传文件失败")
+		return
 	}
+	defer src.Close()
 
-	// 确保目标目录存在
-	uploadPath := filepath.Join(consts.UploadDir, path)
-	if err := os.MkdirAll(uploadPath, os.ModePerm); err != nil {
-		glog.Errorf("创建目标目录失败: %s, 路径: %s", err, uploadPath)
-		response.Error(ctx, "创建目标目录失败")
+	// 保存文件
+	filePath, err := h.fileService.SaveFile(ctx.Request.Context(), path, fileName, src, override)
+	if err != nil {
+		glog.Errorf("保存文件失败: %s", err)
+		response.Error(ctx, err.Error())
 		return
 	}
 
-	// 检查目标目录是否有写入权限
-	if err := h.checkDirectoryWritePermission(uploadPath); err != nil {
-		glog.Errorf("目标目录无写入权限: %s, 路径: %s", err, uploadPath)
-		response.Error(ctx, "目标目录无写入权限")
+	glog.Infof("文件上传成功: %s", filePath)
+	response.Success(ctx, map[string]string{
+		"path": filePath,
+	})
+}
+
+// UploadFolder 批量上传文件，按相对路径在目标目录下重建目录结构
+//
+// 表单中每个 files 对应一个 paths 字段作为相对路径（如 webkitRelativePath），
+// 缺少时使用文件名中的路径；conflict 为同名文件的处理方式：error（默认）、overwrite、skip 或 rename。
+func (h *FileController) UploadFolder(ctx *gin.Context) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		glog.Errorf("解析上传表单失败: %s", err)
+		response.Error(ctx, "解析上传表单失败")
 		return
 	}
 
-	// 检查文件上传前置条件
-	if err := h.fileService.UploadFile(path, fileName, 1, override); err != nil {
-		glog.Errorf("文件上传前置检查失败: %s", err)
-		response.Error(ctx, err.Error())
-		return
+	path := ctx.PostForm("path")
+	conflict := ctx.PostForm("conflict")
+	if conflict == "" && ctx.PostForm("override") == "true" {
+		conflict = consts.ConflictOverwrite
+	}
+	files, paths := form.File["files"], form.Value["paths"]
+	glog.Infof("收到批量上传请求，路径: %s, 文件数: %d, 同名文件处理方式: %s", path, len(files), conflict)
+
+	entries := make([]*model.FolderUploadEntry, len(files))
+	for i, file := range files {
+		relativePath := ""
+		if i < len(paths) {
+			relativePath = paths[i]
+		}
+		if relativePath == "" {
+			relativePath = multipartFilePath(file)
+		}
+		entries[i] = &model.FolderUploadEntry{
+			RelativePath: relativePath,
+			Size:         file.Size,
+			Open: func() (io.ReadCloser, error) {
+				return file.Open()
+			},
+		}
 	}
 
-	// 保存文件
-	filePath := filepath.Join(uploadPath, fileName)
-	if err = ctx.SaveUploadedFile(file, filePath); err != nil {
-		glog.Errorf("保存文件失败: %s, 路径: %s", err, filePath)
-		response.Error(ctx, "保存文件失败")
+	results, err := h.fileService.UploadFolder(ctx.Request.Context(), path, entries, conflict)
+	if err != nil {
+		glog.Errorf("批量上传失败: %s", err)
+		response.Error(ctx, err.Error())
 		return
 	}
+	response.Success(ctx, results)
+}
 
-	// 设置文件权限
-	if err = os.Chmod(filePath, 0644); err != nil {
-		glog.Warnf("设置文件权限失败: %s, 路径: %s", err, filePath)
+// multipartFilePath 表单中文件的原始文件名，可能包含目录；mime/multipart 只保留最后一级
+func multipartFilePath(file *multipart.FileHeader) string {
+	if _, params, err := mime.ParseMediaType(file.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return params["filename"]
 	}
-
-	glog.Infof("文件上传成功: %s", filePath)
-	response.Success(ctx, map[string]string{
-		"path": filepath.Join(path, fileName),
-	})
+	return file.Filename
 }
 
-// UploadChunk 上传文件分块
-func (h *FileController) UploadChunk(ctx *gin.Context) {
-	file, err := ctx.FormFile("file")
-	if err != nil {
-		glog.Errorf("获取上传文件失败: %s", err)
-		response.Error(ctx, "获取上传文件失败")
+// UploadContent 以请求体作为文件内容上传，无需 multipart 编码
+//
+// 带 Content-Range 时按分段上传，响应中的 received 与 Range 头为已接收的字节，客户端据此续传；
+// "Content-Range: bytes */<总大小>" 且没有请求体时查询已接收的字节数，总大小未知时上传的内容已全部接收则保存为文件。
+func (h *FileController) UploadContent(ctx *gin.Context) {
+	filePath := ctx.Query("path")
+	override := ctx.Query("override") == "true"
+	contentRange := ctx.GetHeader("Content-Range")
+	glog.Infof("收到文件内容上传请求，路径: %s, 范围: %s, 是否覆盖: %v", filePath, contentRange, override)
+
+	if filePath == "" {
+		response.Error(ctx, "文件路径不能为空")
 		return
 	}
 
-	fileName := ctx.PostForm("fileName")
-	if fileName == "" {
-		fileName = file.Filename
+	if contentRange == "" {
+		dir, fileName := path.Split(filePath)
+		savedPath, err := h.fileService.SaveFile(ctx.Request.Context(), dir, fileName, ctx.Request.Body, override)
+		if err != nil {
+			glog.Errorf("保存文件失败: %s", err)
+			response.Error(ctx, err.Error())
+			return
+		}
+		glog.Infof("文件上传成功: %s", savedPath)
+		response.Success(ctx, map[string]string{
+			"path": savedPath,
+		})
+		return
 	}
 
-	path := ctx.PostForm("path")
-	override := ctx.PostForm("override") == "true"
-	chunkIndex := ctx.PostForm("chunkIndex")
-	totalChunks := ctx.PostForm("totalChunks")
-
-	// 转换chunkIndex和totalChunks为整数
-	chunkIndexInt, err := strconv.Atoi(chunkIndex)
+	start, end, size, err := parseContentRange(contentRange)
 	if err != nil {
-		glog.Errorf("分块索引格式错误: %s", err)
-		response.Error(ctx, "分块索引格式错误")
+		response.Error(ctx, err.Error())
 		return
 	}
-
-	totalChunksInt, err := strconv.Atoi(totalChunks)
+	var reader io.Reader
+	if end >= 0 {
+		reader = io.LimitReader(ctx.Request.Body, end-start+1)
+	}
+	state, err := h.fileService.SaveFileRange(ctx.Request.Context(), filePath, start, size, reader, override)
+	var mismatch *model.RangeMismatchError
+	if errors.As(err, &mismatch) {
+		setReceivedRange(ctx, mismatch.Received)
+	} else if state != nil && !state.Completed {
+		setReceivedRange(ctx, state.Received)
+	}
 	if err != nil {
-		glog.Errorf("总分块数格式错误: %s", err)
-		response.Error(ctx, "总分块数格式错误")
+		glog.Errorf("保存上传内容失败: %s", err)
+		response.Error(ctx, err.Error())
 		return
 	}
+	response.Success(ctx, state)
+}
 
-	glog.Infof("收到文件分块上传请求，文件名: %s, 路径: %s, 是否覆盖: %v, 分块索引: %d, 总分块数: %d",
-		fileName, path, override, chunkIndexInt, totalChunksInt)
+// parseContentRange 解析 "bytes <start>-<end>/<size>" 形式的 Content-Range，size 为 "*" 时返回 -1；
+// "bytes */<size>" 表示查询已接收的字节数，返回的 end 为 -1
+func parseContentRange(value string) (start, end, size int64, err error) {
+	invalid := fmt.Errorf("Content-Range 格式错误: %s", value)
+	spec, ok := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	rangeSpec, sizeSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	size = -1
+	if sizeSpec != "*" {
+		if size, err = strconv.ParseInt(sizeSpec, 10, 64); err != nil || size < 0 {
+			return 0, 0, 0, invalid
+		}
+	}
+	if rangeSpec == "*" {
+		return 0, -1, size, nil
+	}
+	startSpec, endSpec, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	start, err1 := strconv.ParseInt(startSpec, 10, 64)
+	end, err2 := strconv.ParseInt(endSpec, 10, 64)
+	if err1 != nil || err2 != nil || start < 0 || end < start || (size >= 0 && end >= size) {
+		return 0, 0, 0, invalid
+	}
+	return start, end, size, nil
+}
 
-	// 规范化路径
-	path = filepath.Clean(path)
-	if path == "." {
-		path = ""
+// setReceivedRange 通过 Range 头告知客户端已接收的字节
+func setReceivedRange(ctx *gin.Context, received int64) {
+	if received > 0 {
+		ctx.Header("Range", fmt.Sprintf("bytes=0-%d", received-1))
 	}
+}
 
-	// 确保临时目录存在
-	tempDir := filepath.Join(consts.TempDir, path, fileName)
-	if err := os.MkdirAll(tempDir, os.ModePerm); err != nil {
-		glog.Errorf("创建临时目录失败: %s, 路径: %s", err, tempDir)
-		response.Error(ctx, "创建临时目录失败")
+// UploadEvents 以 Server-Sent Events 推送上传会话 sessionId 的进度
+//
+// 事件名为事件类型（status、chunk-received、merge-progress、completed、error），推送完成或失败事件后关闭连接。
+func (h *FileController) UploadEvents(ctx *gin.Context) {
+	sessionID := ctx.Query("sessionId")
+	glog.Infof("收到订阅上传事件请求，会话: %s", sessionID)
+
+	if sessionID == "" {
+		response.Error(ctx, "会话 ID 不能为空")
 		return
 	}
 
-	// 保存分块文件
-	chunkPath := filepath.Join(tempDir, fmt.Sprintf("chunk_%d", chunkIndexInt))
-	if err = ctx.SaveUploadedFile(file, chunkPath); err != nil {
-		glog.Errorf("保存分块文件失败: %s, 路径: %s", err, chunkPath)
-		response.Error(ctx, "保存分块文件失败")
+	events, err := h.fileService.SubscribeUploadEvents(ctx.Request.Context(), sessionID)
+	if err != nil {
+		glog.Errorf("订阅上传事件失败: %s", err)
+		response.Error(ctx, err.Error())
 		return
 	}
 
-	response.Success(ctx, map[string]interface{}{
-		"chunkIndex": chunkIndexInt,
-		"path":       filepath.Join(path, fileName),
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// 避免反向代理缓冲事件
+	ctx.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			// 注释行保持连接，不会触发客户端事件
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
 	})
 }
 
-// MergeChunks 合并文件分块
-func (h *FileController) MergeChunks(ctx *gin.Context) {
+// CreateUploadSession 创建分块上传会话
+func (h *FileController) CreateUploadSession(ctx *gin.Context) {
 	var req struct {
-		FileName    string `json:"fileName"`
-		Path        string `json:"path"`
-		TotalChunks int    `json:"totalChunks"`
-		Override    bool   `json:"override"`
+		FileName      string `json:"fileName"`
+		Path          string `json:"path"`
+		Size          int64  `json:"size"`      // 文件总大小
+		ChunkSize     int64  `json:"chunkSize"` // 分块大小
+		Override      bool   `json:"override"`
+		HashAlgorithm string `json:"hashAlgorithm"` // md5 或 sha256，默认 sha256
+		FileHash      string `json:"fileHash"`      // 期望的文件摘要，为空时不校验
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -234,102 +438,175 @@ func (h *FileController) MergeChunks(ctx *gin.Context) {
 		return
 	}
 
-	glog.Infof("收到合并文件请求，文件名: %s, 路径: %s, 总分块数: %d, 是否覆盖: %v",
-		req.FileName, req.Path, req.TotalChunks, req.Override)
+	glog.Infof("收到创建上传会话请求，文件名: %s, 路径: %s, 大小: %d, 分块大小: %d, 是否覆盖: %v",
+		req.FileName, req.Path, req.Size, req.ChunkSize, req.Override)
 
-	// 规范化路径
-	req.Path = filepath.Clean(req.Path)
-	if req.Path == "." {
-		req.Path = ""
+	session, err := h.fileService.CreateUploadSession(ctx.Request.Context(), req.Path, req.FileName, req.Size, req.ChunkSize, req.Override, formChecksum(req.HashAlgorithm, req.FileHash))
+	if err != nil {
+		glog.Errorf("创建上传会话失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
 	}
+	response.Success(ctx, session)
+}
+
+// GetUploadSession 获取上传会话，客户端据此跳过已上传的分块
+func (h *FileController) GetUploadSession(ctx *gin.Context) {
+	id := ctx.Query("id")
+	glog.Infof("收到获取上传会话请求，会话: %s", id)
 
-	// 检查文件上传前置条件
-	if err := h.fileService.UploadFile(req.Path, req.FileName, req.TotalChunks, req.Override); err != nil {
-		glog.Errorf("文件上传前置检查失败: %s", err)
+	session, err := h.fileService.GetUploadSession(ctx.Request.Context(), id)
+	if err != nil {
+		glog.Errorf("获取上传会话失败: %s", err)
 		response.Error(ctx, err.Error())
 		return
 	}
+	response.Success(ctx, session)
+}
+
+// GetUploadStatus 获取上传会话的进度与已接收的分块，用于断点续传
+func (h *FileController) GetUploadStatus(ctx *gin.Context) {
+	sessionID := ctx.Query("sessionId")
+	glog.Infof("收到获取上传进度请求，会话: %s", sessionID)
 
-	// 确保目标目录存在
-	uploadPath := filepath.Join(consts.UploadDir, req.Path)
-	if err := os.MkdirAll(uploadPath, os.ModePerm); err != nil {
-		glog.Errorf("创建目标目录失败: %s, 路径: %s", err, uploadPath)
-		response.Error(ctx, "创建目标目录失败")
+	session, err := h.fileService.GetUploadSession(ctx.Request.Context(), sessionID)
+	if err != nil {
+		glog.Errorf("获取上传进度失败: %s", err)
+		response.Error(ctx, err.Error())
 		return
 	}
+	response.Success(ctx, session)
+}
 
-	// 检查目标目录是否有写入权限
-	if err := h.checkDirectoryWritePermission(uploadPath); err != nil {
-		glog.Errorf("目标目录无写入权限: %s, 路径: %s", err, uploadPath)
-		response.Error(ctx, "目标目录无写入权限")
+// UploadSessionChunk 上传会话的分块
+func (h *FileController) UploadSessionChunk(ctx *gin.Context) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		glog.Errorf("获取上传文件失败: %s", err)
+		response.Error(ctx, "获取上传文件失败")
 		return
 	}
 
-	// 合并文件
-	tempDir := filepath.Join(consts.TempDir, req.Path, req.FileName)
-	targetFile := filepath.Join(uploadPath, req.FileName)
-
-	// 创建目标文件
-	outFile, err := os.Create(targetFile)
+	id := ctx.PostForm("sessionId")
+	chunkIndex, err := strconv.Atoi(ctx.PostForm("chunkIndex"))
 	if err != nil {
-		glog.Errorf("创建目标文件失败: %s, 路径: %s", err, targetFile)
-		response.Error(ctx, "创建目标文件失败")
+		glog.Errorf("分块索引格式错误: %s", err)
+		response.Error(ctx, "分块索引格式错误")
 		return
 	}
-	defer outFile.Close()
+	checksum := formChecksum(ctx.PostForm("hashAlgorithm"), ctx.PostForm("hash"))
 
-	// 按顺序合并分块
-	for i := 0; i < req.TotalChunks; i++ {
-		chunkPath := filepath.Join(tempDir, fmt.Sprintf("chunk_%d", i))
+	glog.Infof("收到会话分块上传请求，会话: %s, 分块索引: %d", id, chunkIndex)
 
-		// 读取分块文件
-		chunkFile, err := os.Open(chunkPath)
-		if err != nil {
-			glog.Errorf("打开分块文件失败: %s, 路径: %s", err, chunkPath)
-			response.Error(ctx, "打开分块文件失败")
-			return
-		}
+	src, err := file.Open()
+	if err != nil {
+		glog.Errorf("读取分块文件失败: %s", err)
+		response.Error(ctx, "读取分块文件失败")
+		return
+	}
+	defer src.Close()
 
-		// 复制分块内容到目标文件
-		if _, err = io.Copy(outFile, chunkFile); err != nil {
-			chunkFile.Close()
-			glog.Errorf("复制分块内容失败: %s, 路径: %s", err, chunkPath)
-			response.Error(ctx, "复制分块内容失败")
-			return
-		}
-		chunkFile.Close()
+	if err := h.fileService.SaveSessionChunk(ctx.Request.Context(), id, chunkIndex, src, checksum); err != nil {
+		glog.Errorf("保存分块文件失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
 
-		// 删除分块文件
-		if err = os.Remove(chunkPath); err != nil {
-			glog.Warnf("删除分块文件失败: %s, 路径: %s", err, chunkPath)
-		}
+	response.Success(ctx, map[string]interface{}{
+		"sessionId":  id,
+		"chunkIndex": chunkIndex,
+	})
+}
+
+// MergeUploadSession 合并上传会话的分块
+func (h *FileController) MergeUploadSession(ctx *gin.Context) {
+	var req struct {
+		SessionID     string `json:"sessionId"`
+		HashAlgorithm string `json:"hashAlgorithm"` // md5 或 sha256，默认 sha256
+		FileHash      string `json:"fileHash"`      // 期望的文件摘要，为空时使用创建会话时提供的摘要
 	}
 
-	// 清理临时目录
-	if err := os.RemoveAll(tempDir); err != nil {
-		glog.Warnf("清理临时目录失败: %s, 路径: %s", err, tempDir)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
 	}
 
-	// 清除相关缓存
-	h.fileService.ClearFileCache(req.Path)
+	glog.Infof("收到合并上传会话请求，会话: %s", req.SessionID)
 
-	// 设置文件权限
-	if err = os.Chmod(targetFile, 0644); err != nil {
-		glog.Warnf("设置文件权限失败: %s, 路径: %s", err, targetFile)
+	filePath, digest, err := h.fileService.MergeUploadSession(ctx.Request.Context(), req.SessionID, formChecksum(req.HashAlgorithm, req.FileHash))
+	if err != nil {
+		glog.Errorf("合并文件失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
 	}
 
-	glog.Infof("文件合并成功: %s", targetFile)
+	glog.Infof("文件合并成功: %s, %s: %s", filePath, digest.Algorithm, digest.Value)
 	response.Success(ctx, map[string]string{
-		"path": filepath.Join(req.Path, req.FileName),
+		"path":          filePath,
+		"hashAlgorithm": digest.Algorithm,
+		"hash":          digest.Value,
 	})
 }
 
+// AbortUploadSession 取消上传会话
+func (h *FileController) AbortUploadSession(ctx *gin.Context) {
+	id := ctx.Query("id")
+	glog.Infof("收到取消上传会话请求，会话: %s", id)
+
+	if err := h.fileService.AbortUploadSession(ctx.Request.Context(), id); err != nil {
+		glog.Errorf("取消上传会话失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// InstantUpload 秒传，服务端已有相同内容时无需再上传文件
+func (h *FileController) InstantUpload(ctx *gin.Context) {
+	var req struct {
+		FileName string `json:"fileName"`
+		Path     string `json:"path"`
+		Hash     string `json:"hash"` // 文件的 SHA-256
+		Size     int64  `json:"size"`
+		Override bool   `json:"override"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	glog.Infof("收到秒传请求，文件名: %s, 路径: %s, 大小: %d, 摘要: %s", req.FileName, req.Path, req.Size, req.Hash)
+
+	filePath, err := h.fileService.InstantUpload(ctx.Request.Context(), req.Path, req.FileName, req.Hash, req.Size, req.Override)
+	if err != nil {
+		glog.Errorf("秒传失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, map[string]interface{}{
+		"uploaded": filePath != "",
+		"path":     filePath,
+	})
+}
+
+// formChecksum 由请求参数构造摘要，未提供摘要时返回 nil
+func formChecksum(algorithm, value string) *model.Checksum {
+	if value == "" {
+		return nil
+	}
+	return &model.Checksum{Algorithm: algorithm, Value: value}
+}
+
 // GetFileStats 获取文件统计信息
 func (h *FileController) GetFileStats(ctx *gin.Context) {
 	path := ctx.Query("path")
 	glog.Infof("收到获取文件统计信息请求，路径: %s", path)
 
-	stats, err := h.fileService.GetFileStats(path)
+	stats, err := h.fileService.GetFileStats(ctx.Request.Context(), path)
 	if err != nil {
 		glog.Errorf("获取文件统计信息失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -351,7 +628,7 @@ func (h *FileController) SearchFiles(ctx *gin.Context) {
 		return
 	}
 
-	files, err := h.fileService.SearchFiles(keyword)
+	files, err := h.fileService.SearchFiles(ctx.Request.Context(), keyword)
 	if err != nil {
 		glog.Errorf("搜索文件失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -373,7 +650,7 @@ func (h *FileController) AddFavorite(ctx *gin.Context) {
 		return
 	}
 
-	err := h.fileService.AddFavorite(path)
+	err := h.fileService.AddFavorite(ctx.Request.Context(), path)
 	if err != nil {
 		glog.Errorf("添加收藏失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -395,7 +672,7 @@ func (h *FileController) RemoveFavorite(ctx *gin.Context) {
 		return
 	}
 
-	err := h.fileService.RemoveFavorite(path)
+	err := h.fileService.RemoveFavorite(ctx.Request.Context(), path)
 	if err != nil {
 		glog.Errorf("取消收藏失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -410,7 +687,7 @@ func (h *FileController) RemoveFavorite(ctx *gin.Context) {
 func (h *FileController) GetFavorites(ctx *gin.Context) {
 	glog.Info("收到获取收藏列表请求")
 
-	favorites, err := h.fileService.GetFavorites()
+	favorites, err := h.fileService.GetFavorites(ctx.Request.Context())
 	if err != nil {
 		glog.Errorf("获取收藏列表失败: %s", err)
 		response.Error(ctx, err.Error())
@@ -434,7 +711,7 @@ func (h *FileController) RenameFile(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.fileService.RenameFile(oldPath, newName); err != nil {
+	if err := h.fileService.RenameFile(ctx.Request.Context(), oldPath, newName); err != nil {
 		glog.Errorf("重命名失败: %s", err)
 		response.Error(ctx, err.Error())
 		return
@@ -457,7 +734,7 @@ func (h *FileController) CopyFile(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.fileService.CopyFile(srcPath, destPath); err != nil {
+	if err := h.fileService.CopyFile(ctx.Request.Context(), srcPath, destPath); err != nil {
 		glog.Errorf("复制失败: %s", err)
 		response.Error(ctx, err.Error())
 		return
@@ -480,7 +757,7 @@ func (h *FileController) MoveFile(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.fileService.MoveFile(srcPath, destPath); err != nil {
+	if err := h.fileService.MoveFile(ctx.Request.Context(), srcPath, destPath); err != nil {
 		glog.Errorf("移动失败: %s", err)
 		response.Error(ctx, err.Error())
 		return
@@ -489,17 +766,3 @@ func (h *FileController) MoveFile(ctx *gin.Context) {
 	glog.Info("移动成功")
 	response.Success(ctx, nil)
 }
-
-// checkDirectoryWritePermission 检查目录是否有写入权限
-func (h *FileController) checkDirectoryWritePermission(dir string) error {
-	// 创建临时文件
-	tempFile := filepath.Join(dir, ".write_test")
-	f, err := os.Create(tempFile)
-	if err != nil {
-		return err
-	}
-	f.Close()
-
-	// 删除临时文件
-	return os.Remove(tempFile)
-}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupController struct {
+	aclService service.ACLService
+}
+
+func NewGroupController(aclService service.ACLService) *GroupController {
+	return &GroupController{
+		aclService: aclService,
+	}
+}
+
+// CreateGroup 创建用户组（管理员）
+func (h *GroupController) CreateGroup(ctx *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	group, err := h.aclService.CreateGroup(req.Name)
+	if err != nil {
+		glog.Errorf("创建用户组失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, group)
+}
+
+// ListGroups 获取用户组列表
+func (h *GroupController) ListGroups(ctx *gin.Context) {
+	groups, err := h.aclService.ListGroups()
+	if err != nil {
+		glog.Errorf("获取用户组列表失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, groups)
+}
+
+// AddMember 添加用户组成员（管理员）
+func (h *GroupController) AddMember(ctx *gin.Context) {
+	var req struct {
+		GroupID uint `json:"groupId"`
+		UserID  uint `json:"userId"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+	glog.Infof("收到添加用户组成员请求，用户组: %d, 用户: %d", req.GroupID, req.UserID)
+
+	if err := h.aclService.AddGroupMember(req.GroupID, req.UserID); err != nil {
+		glog.Errorf("添加用户组成员失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// RemoveMember 移除用户组成员（管理员）
+func (h *GroupController) RemoveMember(ctx *gin.Context) {
+	var req struct {
+		GroupID uint `form:"groupId"`
+		UserID  uint `form:"userId"`
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+	glog.Infof("收到移除用户组成员请求，用户组: %d, 用户: %d", req.GroupID, req.UserID)
+
+	if err := h.aclService.RemoveGroupMember(req.GroupID, req.UserID); err != nil {
+		glog.Errorf("移除用户组成员失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
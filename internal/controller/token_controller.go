@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/common/middlewares"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TokenController struct {
+	tokenService service.TokenService
+}
+
+func NewTokenController(tokenService service.TokenService) *TokenController {
+	return &TokenController{
+		tokenService: tokenService,
+	}
+}
+
+// CreateToken 创建 API 令牌
+func (h *TokenController) CreateToken(ctx *gin.Context) {
+	var req struct {
+		Name       string   `json:"name"`
+		Scopes     []string `json:"scopes"`
+		PathPrefix string   `json:"pathPrefix"`
+		ExpiresIn  int64    `json:"expiresIn"` // 有效期（秒），0 表示永不过期
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	user := middlewares.GetCurrentUser(ctx)
+	glog.Infof("收到创建 API 令牌请求，用户: %s, 名称: %s, 权限: %v, 目录: %s", user.Username, req.Name, req.Scopes, req.PathPrefix)
+
+	plain, token, err := h.tokenService.CreateToken(user.ID, req.Name, req.Scopes, req.PathPrefix, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		glog.Errorf("创建 API 令牌失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, map[string]interface{}{
+		"token": plain,
+		"info":  token,
+	})
+}
+
+// ListTokens 获取当前用户的 API 令牌列表
+func (h *TokenController) ListTokens(ctx *gin.Context) {
+	tokens, err := h.tokenService.ListTokens(middlewares.GetCurrentUser(ctx).ID)
+	if err != nil {
+		glog.Errorf("获取 API 令牌列表失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, tokens)
+}
+
+// RevokeToken 吊销 API 令牌
+func (h *TokenController) RevokeToken(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, "令牌 ID 格式错误")
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(middlewares.GetCurrentUser(ctx).ID, uint(id)); err != nil {
+		glog.Errorf("吊销 API 令牌失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"FileNest/internal/service"
+	"FileNest/internal/storage"
+	"FileNest/internal/tus"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TusController struct {
+	handler *tus.Handler
+}
+
+func NewTusController(fileService service.FileService, temp storage.Driver, prefix string) *TusController {
+	return &TusController{
+		handler: tus.NewHandler(fileService, temp, prefix),
+	}
+}
+
+// ServeTus 处理 tus 断点续传请求
+func (h *TusController) ServeTus(ctx *gin.Context) {
+	h.handler.ServeHTTP(ctx.Writer, ctx.Request)
+}
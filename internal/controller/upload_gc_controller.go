@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UploadGCController struct {
+	uploadGCService service.UploadGCService
+}
+
+func NewUploadGCController(uploadGCService service.UploadGCService) *UploadGCController {
+	return &UploadGCController{
+		uploadGCService: uploadGCService,
+	}
+}
+
+// GetUploadGC 获取最近一次清理过期分块的结果
+func (h *UploadGCController) GetUploadGC(ctx *gin.Context) {
+	response.Success(ctx, h.uploadGCService.LastUploadGC(ctx.Request.Context()))
+}
+
+// RunUploadGC 立即清理过期分块
+func (h *UploadGCController) RunUploadGC(ctx *gin.Context) {
+	glog.Infof("收到清理过期分块请求")
+
+	report, err := h.uploadGCService.CollectUploadGarbage(ctx.Request.Context())
+	if err != nil {
+		glog.Errorf("清理过期分块失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, report)
+}
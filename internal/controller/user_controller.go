@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"FileNest/common/glog"
+	"FileNest/common/middlewares"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserController struct {
+	userService service.UserService
+}
+
+func NewUserController(userService service.UserService) *UserController {
+	return &UserController{
+		userService: userService,
+	}
+}
+
+// CreateUser 创建用户（管理员）
+func (h *UserController) CreateUser(ctx *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+	glog.Infof("收到创建用户请求，用户名: %s, 角色: %s", req.Username, req.Role)
+
+	user, err := h.userService.CreateUser(req.Username, req.Password, req.Role)
+	if err != nil {
+		glog.Errorf("创建用户失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, user)
+}
+
+// ListUsers 获取用户列表（管理员）
+func (h *UserController) ListUsers(ctx *gin.Context) {
+	users, err := h.userService.ListUsers()
+	if err != nil {
+		glog.Errorf("获取用户列表失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, users)
+}
+
+// ChangePassword 修改当前用户密码
+func (h *UserController) ChangePassword(ctx *gin.Context) {
+	var req struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		glog.Errorf("解析请求参数失败: %s", err)
+		response.Error(ctx, "解析请求参数失败")
+		return
+	}
+
+	user := middlewares.GetCurrentUser(ctx)
+	if err := h.userService.ChangePassword(user.ID, req.OldPassword, req.NewPassword); err != nil {
+		glog.Errorf("修改密码失败: %s", err)
+		response.Error(ctx, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
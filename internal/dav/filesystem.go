@@ -0,0 +1,316 @@
+package dav
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"FileNest/internal/storage"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem 基于 FileService 的 WebDAV 文件系统
+//
+// 所有操作都经过 FileService，与 REST 接口共享路径校验和缓存失效逻辑。
+type FileSystem struct {
+	fileService service.FileService
+}
+
+// NewFileSystem 创建 WebDAV 文件系统
+func NewFileSystem(fileService service.FileService) *FileSystem {
+	return &FileSystem{fileService: fileService}
+}
+
+// NewHandler 创建挂载在 prefix 下的 WebDAV 处理器
+func NewHandler(fileService service.FileService, prefix string) *webdav.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: NewFileSystem(fileService),
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				glog.Warnf("WebDAV 请求失败: %s %s, error: %s", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+}
+
+func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = clientPath(name)
+	if _, err := f.fileService.StatFile(ctx, name); err == nil {
+		return os.ErrExist
+	}
+	if _, err := f.fileService.StatFile(ctx, path.Dir(name)); err != nil {
+		return err
+	}
+	return f.fileService.CreateFolder(ctx, name)
+}
+
+func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = clientPath(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 {
+		return f.openForWrite(ctx, name, flag)
+	}
+
+	info, err := f.fileService.StatFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{ctx: ctx, fileService: f.fileService, name: name, info: info}, nil
+	}
+
+	file, info, err := f.fileService.DownloadFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &readFile{File: file, info: info}, nil
+}
+
+func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	name = clientPath(name)
+	if name == "" {
+		return os.ErrPermission
+	}
+	if _, err := f.fileService.StatFile(ctx, name); err != nil {
+		return err
+	}
+	return f.fileService.DeleteFile(ctx, name, true)
+}
+
+func (f *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = clientPath(oldName), clientPath(newName)
+	if oldName == "" || newName == "" {
+		return os.ErrPermission
+	}
+	if _, err := f.fileService.StatFile(ctx, newName); err == nil {
+		return os.ErrExist
+	}
+	return f.fileService.MoveFile(ctx, oldName, newName)
+}
+
+func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return f.fileService.StatFile(ctx, clientPath(name))
+}
+
+// openForWrite 写入先落到本地临时文件，Close 时再通过 FileService 保存
+func (f *FileSystem) openForWrite(ctx context.Context, name string, flag int) (webdav.File, error) {
+	if name == "" {
+		return nil, os.ErrPermission
+	}
+
+	info, err := f.fileService.StatFile(ctx, name)
+	if err == nil {
+		if info.IsDir() {
+			return nil, os.ErrExist
+		}
+		if flag&os.O_EXCL != 0 {
+			return nil, os.ErrExist
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	} else if flag&os.O_CREATE == 0 {
+		return nil, err
+	}
+
+	// WebDAV 要求父目录必须存在
+	if _, err := f.fileService.StatFile(ctx, path.Dir(name)); err != nil {
+		return nil, err
+	}
+	// 提前校验权限、配额与上传策略，写入时按允许的大小截止，避免先把超出的内容落到临时文件
+	dirName, fileName := path.Split(name)
+	limit, err := f.fileService.CheckUploadLimit(ctx, dirName, fileName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "filenest-dav-*")
+	if err != nil {
+		return nil, err
+	}
+
+	file := &writeFile{file: tmp, ctx: ctx, fileService: f.fileService, name: name, limit: limit}
+	// 未截断时保留原有内容
+	if info != nil && flag&os.O_TRUNC == 0 {
+		if err := f.copyExisting(ctx, name, file); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func (f *FileSystem) copyExisting(ctx context.Context, name string, dst *writeFile) error {
+	src, _, err := f.fileService.DownloadFile(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	_, err = dst.Seek(0, io.SeekStart)
+	return err
+}
+
+// clientPath 将 WebDAV 路径转换为 FileService 使用的相对路径
+func clientPath(name string) string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// readFile 只读文件
+type readFile struct {
+	storage.File
+	info fs.FileInfo
+}
+
+func (f *readFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *readFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// dir 目录
+type dir struct {
+	ctx         context.Context
+	fileService service.FileService
+	name        string
+	info        fs.FileInfo
+	entries     []fs.FileInfo
+	loaded      bool
+	offset      int
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.offset = 0
+		return 0, nil
+	}
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.fileService.ReadDir(d.ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+// writeFile 可写文件，内容暂存在本地临时文件中
+//
+// 不嵌入 *os.File，避免 io.Copy 通过 ReadFrom 绕过 Write 中的大小限制。
+type writeFile struct {
+	file        *os.File
+	ctx         context.Context
+	fileService service.FileService
+	name        string
+	limit       *model.UploadLimit
+	err         error // 写入超出上限的错误，此时 Close 不再保存
+	closed      bool
+}
+
+func (f *writeFile) Read(p []byte) (int, error) {
+	return f.file.Read(p)
+}
+
+func (f *writeFile) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+func (f *writeFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *writeFile) Stat() (fs.FileInfo, error) {
+	return f.file.Stat()
+}
+
+// Write 写入后的文件大小超出允许的上限时拒绝写入
+func (f *writeFile) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if f.err = f.limit.Check(offset + int64(len(p))); f.err != nil {
+		return 0, f.err
+	}
+	return f.file.Write(p)
+}
+
+// Close 将临时文件内容保存到存储，并清理临时文件
+func (f *writeFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	defer os.Remove(f.file.Name())
+	defer f.file.Close()
+
+	if f.err != nil {
+		return f.err
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dirName, fileName := path.Split(f.name)
+	_, err := f.fileService.SaveFile(f.ctx, dirName, fileName, f.file, true)
+	return err
+}
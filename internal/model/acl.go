@@ -0,0 +1,54 @@
+package model
+
+import "FileNest/common/model"
+
+// 路径权限
+const (
+	PermRead   = "read"   // 列表、搜索、下载
+	PermWrite  = "write"  // 上传、创建文件夹、作为复制或移动的目标
+	PermDelete = "delete" // 删除、重命名、作为移动的源
+	PermShare  = "share"  // 修改该路径的 ACL
+)
+
+// ACL 授权对象类型
+const (
+	SubjectUser  = "user"
+	SubjectGroup = "group"
+)
+
+// ACLRoles 预置角色对应的权限
+var ACLRoles = map[string][]string{
+	"viewer":  {PermRead},
+	"editor":  {PermRead, PermWrite},
+	"manager": {PermRead, PermWrite, PermDelete, PermShare},
+}
+
+// ACLEntry 路径上的一条授权，对子路径继承生效
+type ACLEntry struct {
+	model.BaseEntity
+	Path        string `gorm:"size:768;index" json:"path"` // 授权路径
+	SubjectType string `gorm:"size:16" json:"subjectType"` // 授权对象类型，user 或 group
+	SubjectID   uint   `json:"subjectId"`                  // 用户或用户组 ID
+	SubjectName string `gorm:"-" json:"subjectName"`       // 用户名或用户组名
+	Permissions string `gorm:"size:64" json:"permissions"` // 权限，逗号分隔
+}
+
+// ACLInfo 路径的 ACL 详情
+type ACLInfo struct {
+	Path          string     `json:"path"`          // 查询的路径
+	Entries       []ACLEntry `json:"entries"`       // 该路径自身的 ACL
+	InheritedFrom *string    `json:"inheritedFrom"` // 生效的 ACL 所在路径，为空时使用默认权限
+	Effective     []string   `json:"effective"`     // 当前用户在该路径上的权限
+}
+
+// UserGroup 用户组
+type UserGroup struct {
+	model.BaseEntity
+	Name string `gorm:"size:64;uniqueIndex" json:"name"` // 组名
+}
+
+// UserGroupMember 用户组成员
+type UserGroupMember struct {
+	GroupID uint `gorm:"primaryKey" json:"groupId"`
+	UserID  uint `gorm:"primaryKey;index" json:"userId"`
+}
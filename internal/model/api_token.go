@@ -0,0 +1,45 @@
+package model
+
+import (
+	"FileNest/common/model"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix API 令牌前缀，用于与登录令牌区分
+const APITokenPrefix = "fnt_"
+
+// API 令牌权限范围
+const (
+	ScopeRead   = "read"   // 列表、搜索、下载
+	ScopeUpload = "upload" // 上传文件、创建文件夹
+	ScopeWrite  = "write"  // 包含 upload，并允许删除、重命名、移动
+)
+
+// APIToken 个人 API 令牌
+type APIToken struct {
+	model.BaseEntity
+	UserID      uint       `gorm:"index" json:"userId"`          // 所属用户
+	Name        string     `gorm:"size:64" json:"name"`          // 令牌名称
+	TokenHash   string     `gorm:"size:64;uniqueIndex" json:"-"` // 令牌的 SHA-256
+	TokenPrefix string     `gorm:"size:16" json:"tokenPrefix"`   // 令牌前几位，便于识别
+	Scopes      string     `gorm:"size:64" json:"scopes"`        // 权限范围，逗号分隔
+	PathPrefix  string     `gorm:"size:1024" json:"pathPrefix"`  // 限制访问的目录，为空时不限制
+	ExpiresAt   *time.Time `json:"expiresAt"`                    // 过期时间，为空时永不过期
+	LastUsedAt  *time.Time `json:"lastUsedAt"`                   // 最近使用时间
+}
+
+// HasScope 判断令牌是否具有指定权限
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s == scope || (s == ScopeWrite && scope == ScopeUpload) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsPath 判断路径是否在令牌限制的目录内
+func (t *APIToken) AllowsPath(path string) bool {
+	return t.PathPrefix == "" || path == t.PathPrefix || strings.HasPrefix(path, t.PathPrefix+"/")
+}
@@ -2,11 +2,12 @@ package model
 
 import "time"
 
-// Favorite 文件收藏
+// Favorite 文件收藏，同一用户对同一路径只保留一条
 type Favorite struct {
-	ID         int64     `json:"id"`         // 收藏ID
-	Name       string    `json:"name"`       // 文件名
-	Path       string    `json:"path"`       // 文件路径
-	IsDir      bool      `json:"isDir"`      // 是否是目录
-	CreateTime time.Time `json:"createTime"` // 创建时间
+	ID         int64     `gorm:"primaryKey" json:"id"`                                    // 收藏ID
+	UserID     uint      `gorm:"uniqueIndex:idx_favorite_user_path" json:"-"`             // 所属用户
+	Name       string    `gorm:"size:255" json:"name"`                                    // 文件名
+	Path       string    `gorm:"size:760;uniqueIndex:idx_favorite_user_path" json:"path"` // 文件路径
+	IsDir      bool      `json:"isDir"`                                                   // 是否是目录
+	CreateTime time.Time `json:"createTime"`                                              // 创建时间
 }
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// FetchJob 从 URL 下载文件到存储的后台任务
+type FetchJob struct {
+	ID        string    `json:"id"`                 // 任务 ID
+	UserID    uint      `json:"-"`                  // 创建任务的用户，内部调用时为 0
+	URL       string    `json:"url"`                // 下载地址
+	Path      string    `json:"path"`               // 目标目录
+	FileName  string    `json:"fileName"`           // 文件名
+	Override  bool      `json:"override"`           // 是否覆盖同名文件
+	Status    string    `json:"status"`             // 任务状态
+	Size      int64     `json:"size"`               // 文件总大小，未知时为 -1
+	Received  int64     `json:"received"`           // 已下载的字节数
+	Progress  int       `json:"progress"`           // 下载进度（0-100），大小未知时为 0
+	Attempts  int       `json:"attempts"`           // 已尝试的次数
+	Error     string    `json:"error,omitempty"`    // 失败原因
+	FilePath  string    `json:"filePath,omitempty"` // 完成后的文件路径
+	Checksum  *Checksum `json:"checksum,omitempty"` // 完成后文件的 SHA-256
+	CreatedAt time.Time `json:"createdAt"`          // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"`          // 最近一次更新时间
+}
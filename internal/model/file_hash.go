@@ -0,0 +1,15 @@
+package model
+
+import (
+	"FileNest/common/model"
+	"time"
+)
+
+// FileHash 文件内容的 SHA-256 索引，用于秒传时查找相同内容的文件
+type FileHash struct {
+	model.BaseEntity
+	Path    string    `gorm:"size:768;uniqueIndex" json:"path"`                // 文件路径
+	Hash    string    `gorm:"size:64;index:idx_file_hash_content" json:"hash"` // 十六进制 SHA-256
+	Size    int64     `gorm:"index:idx_file_hash_content" json:"size"`         // 文件大小（字节）
+	ModTime time.Time `json:"modTime"`                                         // 计算摘要时文件的修改时间，用于识别在服务外被修改的文件
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"FileNest/common/model"
+	"fmt"
+)
+
+// FileOwner 文件归属，记录文件由哪个用户写入，用于统计用户的存储用量
+type FileOwner struct {
+	model.BaseEntity
+	Path   string `gorm:"size:768;uniqueIndex" json:"path"` // 文件路径
+	UserID uint   `gorm:"index" json:"userId"`              // 写入文件的用户
+	Size   int64  `json:"size"`                             // 文件大小（字节）
+}
+
+// QuotaUsage 配额用量，Max 为 0 表示不限制
+type QuotaUsage struct {
+	Path      string `json:"path,omitempty"` // 目录配额所在的目录
+	UsedBytes int64  `json:"usedBytes"`      // 已用字节数
+	MaxBytes  int64  `json:"maxBytes"`       // 字节数上限
+	UsedFiles int64  `json:"usedFiles"`      // 已有文件数
+	MaxFiles  int64  `json:"maxFiles"`       // 文件数上限
+}
+
+// QuotaInfo 当前用户与所在目录的配额用量
+type QuotaInfo struct {
+	User   *QuotaUsage `json:"user,omitempty"`   // 当前用户的配额
+	Folder *QuotaUsage `json:"folder,omitempty"` // 路径所在的最近一个目录配额
+}
+
+// QuotaExceededError 写入后会超出配额
+type QuotaExceededError struct {
+	Scope string // 超出的配额，如 "用户 bob"、"目录 videos"
+	Limit string // 超出的限制，如 "容量上限 1073741824 字节"
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("超出存储配额: %s 的%s", e.Scope, e.Limit)
+}
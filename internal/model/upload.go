@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ChunkInfo 已接收的文件分块
+type ChunkInfo struct {
+	Index int   `json:"index"` // 分块索引
+	Size  int64 `json:"size"`  // 分块大小（字节）
+}
+
+// Checksum 文件或分块的摘要
+type Checksum struct {
+	Algorithm string `json:"algorithm"` // 摘要算法，md5 或 sha256
+	Value     string `json:"value"`     // 十六进制摘要
+}
+
+// ChecksumMismatchError 上传内容与客户端提供的摘要不一致
+type ChecksumMismatchError struct {
+	Target   string // 校验的对象，如 "分块 3"、"文件 docs/a.zip"
+	Expected Checksum
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s 校验失败: %s 应为 %s，实际为 %s", e.Target, e.Expected.Algorithm, e.Expected.Value, e.Actual)
+}
+
+// UploadSession 分块上传会话，分块按会话 ID 存放，不同用户同时上传同名文件互不影响
+type UploadSession struct {
+	ID             string      `json:"id"`                 // 会话 ID
+	UserID         uint        `json:"-"`                  // 创建会话的用户，内部调用时为 0
+	Path           string      `json:"path"`               // 目标目录
+	FileName       string      `json:"fileName"`           // 文件名
+	Override       bool        `json:"override"`           // 是否覆盖同名文件
+	Size           int64       `json:"size"`               // 文件总大小（字节）
+	ChunkSize      int64       `json:"chunkSize"`          // 分块大小，最后一个分块可以更小
+	TotalChunks    int         `json:"totalChunks"`        // 总分块数
+	Checksum       *Checksum   `json:"checksum,omitempty"` // 期望的文件摘要
+	Status         string      `json:"status"`             // 会话状态
+	Error          string      `json:"error,omitempty"`    // 失败原因
+	FilePath       string      `json:"filePath,omitempty"` // 合并完成后的文件路径
+	Chunks         []ChunkInfo `json:"chunks"`             // 已接收的分块，按索引排序
+	ReceivedChunks int         `json:"receivedChunks"`     // 已接收的分块数
+	ReceivedSize   int64       `json:"receivedSize"`       // 已接收的字节数
+	Progress       int         `json:"progress"`           // 上传进度（0-100）
+	CreatedAt      time.Time   `json:"createdAt"`          // 创建时间
+	ExpiresAt      time.Time   `json:"expiresAt"`          // 过期时间，每次收到分块后顺延
+}
+
+// UploadGCReport 一次清理过期分块的结果
+type UploadGCReport struct {
+	StartedAt  time.Time `json:"startedAt"`  // 开始时间
+	FinishedAt time.Time `json:"finishedAt"` // 结束时间
+	ChunkDirs  int       `json:"chunkDirs"`  // 清理的分块上传目录数
+	Sessions   int       `json:"sessions"`   // 清理的上传会话数
+	TusUploads int       `json:"tusUploads"` // 清理的 tus 上传数
+	MergeTemps int       `json:"mergeTemps"` // 清理的合并临时文件数
+	Files      int       `json:"files"`      // 删除的分块数
+	Bytes      int64     `json:"bytes"`      // 回收的字节数
+	Failed     int       `json:"failed"`     // 删除失败的分块数，详见日志
+}
+
+// UploadPolicyError 上传不符合上传策略
+type UploadPolicyError struct {
+	Reason string // 不符合的规则，如 "不允许上传 .exe 文件"
+}
+
+func (e *UploadPolicyError) Error() string {
+	return fmt.Sprintf("不符合上传策略: %s", e.Reason)
+}
+
+// UploadLimit 上传允许写入的最大文件大小，取剩余配额与上传策略大小上限中较小的一个
+type UploadLimit struct {
+	MaxBytes int64 // 文件最大字节数，-1 表示不限制
+	Err      error // 超出 MaxBytes 时返回的错误
+}
+
+// Check 文件写到 size 字节时是否超出上限
+func (l *UploadLimit) Check(size int64) error {
+	if l.MaxBytes >= 0 && size > l.MaxBytes {
+		return l.Err
+	}
+	return nil
+}
+
+// RangeUpload 按 Content-Range 分段上传的状态
+type RangeUpload struct {
+	Path      string    `json:"path"`               // 文件路径
+	Size      int64     `json:"size"`               // 文件总大小，未知时为 -1
+	Received  int64     `json:"received"`           // 已连续接收的字节数
+	Completed bool      `json:"completed"`          // 是否已接收完整并保存
+	Checksum  *Checksum `json:"checksum,omitempty"` // 保存后文件的摘要
+}
+
+// RangeMismatchError 分段的起始位置与已接收的字节数不一致
+type RangeMismatchError struct {
+	Offset   int64 // 分段的起始位置
+	Received int64 // 已接收的字节数
+}
+
+func (e *RangeMismatchError) Error() string {
+	return fmt.Sprintf("上传位置不连续: 分段从 %d 字节开始，已接收 %d 字节", e.Offset, e.Received)
+}
+
+// UploadEvent 上传进度事件，通过 SSE 推送给订阅的客户端
+type UploadEvent struct {
+	Type           string    `json:"type"`               // 事件类型，见 consts.UploadEvent*
+	Status         string    `json:"status,omitempty"`   // 上传状态
+	Progress       int       `json:"progress"`           // 进度（0-100），合并进度事件中为合并的进度
+	Chunk          *int      `json:"chunk,omitempty"`    // 收到的分块索引
+	ReceivedChunks int       `json:"receivedChunks"`     // 已接收的分块数
+	TotalChunks    int       `json:"totalChunks"`        // 总分块数
+	ReceivedSize   int64     `json:"receivedSize"`       // 已接收的字节数
+	Path           string    `json:"path,omitempty"`     // 上传完成后的文件路径
+	Checksum       *Checksum `json:"checksum,omitempty"` // 上传完成后文件的摘要
+	Error          string    `json:"error,omitempty"`    // 失败原因
+	Time           time.Time `json:"time"`               // 事件时间
+}
+
+// FolderUploadEntry 批量上传中的一个文件
+type FolderUploadEntry struct {
+	RelativePath string                        // 相对于目标目录的路径，如 "photos/2024/a.jpg"
+	Size         int64                         // 文件大小，未知时为 0
+	Open         func() (io.ReadCloser, error) // 打开文件内容，保存时才调用
+}
+
+// FolderUploadResult 批量上传中一个文件的结果
+type FolderUploadResult struct {
+	RelativePath string `json:"relativePath"`    // 请求中的相对路径
+	Path         string `json:"path,omitempty"`  // 保存后的文件路径，重命名时与相对路径不同
+	Status       string `json:"status"`          // done、skipped 或 error
+	Error        string `json:"error,omitempty"` // 失败原因
+}
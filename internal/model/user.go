@@ -0,0 +1,32 @@
+package model
+
+import "FileNest/common/model"
+
+// 用户角色
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User 用户
+type User struct {
+	model.BaseEntity
+	Username     string `gorm:"size:64;uniqueIndex" json:"username"` // 用户名
+	PasswordHash string `gorm:"size:255" json:"-"`                   // 密码哈希
+	Role         string `gorm:"size:16" json:"role"`                 // 角色
+	Disabled     bool   `json:"disabled"`                            // 是否禁用
+	TokenVersion uint   `gorm:"not null;default:0" json:"-"`         // 令牌版本，修改密码时递增，使已签发的令牌全部失效
+}
+
+// IsAdmin 是否是管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// TokenPair 登录或刷新后返回的令牌
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"` // 访问令牌有效期（秒）
+}
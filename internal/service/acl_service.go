@@ -0,0 +1,28 @@
+package service
+
+import (
+	"FileNest/common/database"
+	"FileNest/internal/model"
+	"FileNest/internal/service/impl"
+)
+
+type ACLService interface {
+	// GetACL 获取路径上的 ACL 与当前用户的有效权限
+	GetACL(user *model.User, path string) (*model.ACLInfo, error)
+	// SetACL 替换路径上的 ACL，需要该路径的 share 权限
+	SetACL(user *model.User, path string, entries []model.ACLEntry) error
+	// RemoveACL 删除路径上的 ACL，恢复为继承上级目录
+	RemoveACL(user *model.User, path string) error
+	// CreateGroup 创建用户组
+	CreateGroup(name string) (*model.UserGroup, error)
+	// ListGroups 获取用户组列表
+	ListGroups() ([]model.UserGroup, error)
+	// AddGroupMember 添加用户组成员
+	AddGroupMember(groupID, userID uint) error
+	// RemoveGroupMember 移除用户组成员
+	RemoveGroupMember(groupID, userID uint) error
+}
+
+func NewACLService() ACLService {
+	return impl.NewACLServiceImpl(database.GetDB())
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"FileNest/internal/model"
+	"FileNest/internal/service/impl"
+	"FileNest/internal/storage"
+	"context"
+)
+
+// FetchService 从 URL 下载文件的后台任务
+type FetchService interface {
+	// CreateFetchJob 创建从 URL 下载文件到 path 的后台任务，fileName 为空时使用 URL 中的文件名
+	CreateFetchJob(ctx context.Context, url, path, fileName string, override bool) (*model.FetchJob, error)
+	// GetFetchJob 获取下载任务的状态与进度
+	GetFetchJob(ctx context.Context, id string) (*model.FetchJob, error)
+	// CancelFetchJob 取消下载任务并删除已下载的内容
+	CancelFetchJob(ctx context.Context, id string) (*model.FetchJob, error)
+	// RecoverFetchJobs 将运行实例已退出的下载任务标记为失败，返回处理的任务数
+	RecoverFetchJobs(ctx context.Context) (int, error)
+}
+
+// NewFetchService 创建下载任务服务，store 与 temp 应与文件服务使用的存储相同
+func NewFetchService(store storage.Driver, temp storage.Driver) FetchService {
+	return impl.NewFetchServiceImpl(newFileServiceImpl(store, temp))
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"FileNest/common/database"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
 	"FileNest/internal/model"
 	"FileNest/internal/service/impl"
+	"FileNest/internal/storage"
+	"context"
+	"io"
+	"io/fs"
 )
 
 /**
@@ -10,34 +17,90 @@ import (
   @date: 2024/9/28
 **/
 
+// FileService 文件服务
+//
+// ctx 中携带发起请求的身份（见 auth.WithPrincipal），用于校验访问权限。
 type FileService interface {
-	GetFileList(path string) ([]model.FileInfo, error)
-	UploadFile(path, fileName string, totalChunks int, override bool) error
-	CreateDir(path string) error
-	DeleteFile(path string, force bool) error
+	GetFileList(ctx context.Context, path string) ([]model.FileInfo, error)
+	// StatFile 获取文件或目录信息
+	StatFile(ctx context.Context, path string) (fs.FileInfo, error)
+	// ReadDir 读取目录下的直接子项（不经过缓存）
+	ReadDir(ctx context.Context, path string) ([]fs.FileInfo, error)
+	// CheckUpload 校验上传前置条件（权限、同名文件、配额），不写入任何内容
+	CheckUpload(ctx context.Context, path, fileName string, size int64, override bool) error
+	// CheckUploadLimit 与 CheckUpload 相同，并返回允许写入的最大文件大小，供先接收内容再保存的方式在写入时截止
+	CheckUploadLimit(ctx context.Context, path, fileName string, override bool) (*model.UploadLimit, error)
+	// SaveFile 保存上传的文件，返回文件路径
+	SaveFile(ctx context.Context, path, fileName string, reader io.Reader, override bool) (string, error)
+	// UploadFolder 在 path 下按相对路径批量保存文件并重建目录结构，conflict 为同名文件的处理方式，返回每个文件的结果
+	UploadFolder(ctx context.Context, path string, entries []*model.FolderUploadEntry, conflict string) ([]*model.FolderUploadResult, error)
+	// SaveFileRange 保存从 offset 开始的一段内容，size 为文件总大小（未知时为 -1），接收完整后保存为文件；
+	// reader 为 nil 时只返回已接收的状态，已接收的大小等于 size 时保存为文件
+	SaveFileRange(ctx context.Context, filePath string, offset, size int64, reader io.Reader, override bool) (*model.RangeUpload, error)
+	// CreateUploadSession 创建分块上传会话，checksum 为期望的文件摘要，可以为 nil
+	CreateUploadSession(ctx context.Context, path, fileName string, size, chunkSize int64, override bool, checksum *model.Checksum) (*model.UploadSession, error)
+	// GetUploadSession 获取上传会话及已接收的分块
+	GetUploadSession(ctx context.Context, id string) (*model.UploadSession, error)
+	// SaveSessionChunk 保存上传会话的分块，checksum 不为 nil 时校验分块内容
+	SaveSessionChunk(ctx context.Context, id string, chunkIndex int, reader io.Reader, checksum *model.Checksum) error
+	// MergeUploadSession 合并上传会话的分块，返回文件路径与文件摘要
+	MergeUploadSession(ctx context.Context, id string, checksum *model.Checksum) (string, *model.Checksum, error)
+	// AbortUploadSession 取消上传会话并删除已接收的分块
+	AbortUploadSession(ctx context.Context, id string) error
+	// SubscribeUploadEvents 订阅上传会话的进度事件，通道在完成、失败或 ctx 结束后关闭
+	SubscribeUploadEvents(ctx context.Context, sessionID string) (<-chan *model.UploadEvent, error)
+	// InstantUpload 秒传，已有相同内容的文件时直接创建副本并返回文件路径，否则返回空路径
+	InstantUpload(ctx context.Context, path, fileName, contentHash string, size int64, override bool) (string, error)
+	CreateDir(ctx context.Context, path string) error
+	DeleteFile(ctx context.Context, path string, force bool) error
 	// DownloadFile 下载
-	DownloadFile(path string) (string, error)
-	CreateFolder(path string) error
-	RemoveFile(path string, force bool) error
-	GetFileStats(path string) (*model.FileStats, error)
+	DownloadFile(ctx context.Context, path string) (storage.File, fs.FileInfo, error)
+	// ArchiveDir 将文件夹打包为 format 格式（zip、tar 或 tar.gz）写入 w，校验失败时不写入任何内容
+	ArchiveDir(ctx context.Context, path, format string, w io.Writer) error
+	// ArchivePaths 将多个文件或文件夹打包为一个归档写入 w，同名项按顺序加上序号，校验失败时不写入任何内容
+	ArchivePaths(ctx context.Context, paths []string, format string, w io.Writer) error
+	CreateFolder(ctx context.Context, path string) error
+	RemoveFile(ctx context.Context, path string, force bool) error
+	GetFileStats(ctx context.Context, path string) (*model.FileStats, error)
 	// SearchFiles 搜索文件
-	SearchFiles(keyword string) ([]model.FileInfo, error)
+	SearchFiles(ctx context.Context, keyword string) ([]model.FileInfo, error)
 	// AddFavorite 添加收藏
-	AddFavorite(filePath string) error
+	AddFavorite(ctx context.Context, filePath string) error
 	// RemoveFavorite 取消收藏
-	RemoveFavorite(filePath string) error
+	RemoveFavorite(ctx context.Context, filePath string) error
 	// GetFavorites 获取收藏列表
-	GetFavorites() ([]model.Favorite, error)
+	GetFavorites(ctx context.Context) ([]model.Favorite, error)
 	// RenameFile 重命名文件或文件夹
-	RenameFile(oldPath string, newName string) error
+	RenameFile(ctx context.Context, oldPath string, newName string) error
 	// CopyFile 复制文件或文件夹
-	CopyFile(srcPath string, destPath string) error
+	CopyFile(ctx context.Context, srcPath string, destPath string) error
 	// MoveFile 移动文件或文件夹
-	MoveFile(srcPath string, destPath string) error
+	MoveFile(ctx context.Context, srcPath string, destPath string) error
 	// ClearFileCache 清除缓存
-	ClearFileCache(path string) error
+	ClearFileCache(ctx context.Context, path string) error
 }
 
-func NewFileService() FileService {
-	return &impl.FileServiceImpl{}
+// NewFileService 使用 store 与 temp 创建文件服务，两者由 NewStorage 与 NewTempStorage 创建
+func NewFileService(store storage.Driver, temp storage.Driver) FileService {
+	return newFileServiceImpl(store, temp)
+}
+
+func newFileServiceImpl(store storage.Driver, temp storage.Driver) *impl.FileServiceImpl {
+	db := database.GetDB()
+	return impl.NewFileServiceImpl(store, temp, impl.NewACLServiceImpl(db), impl.NewQuotaServiceImpl(db), impl.NewFavoriteServiceImpl(db), impl.NewFileHashServiceImpl(db))
+}
+
+// NewStorage 按 config.Storage 创建文件存储，配置错误或无法连接时返回错误
+func NewStorage() (storage.Driver, error) {
+	return storage.New(config.Storage)
+}
+
+// NewTempStorage 创建存放分块、上传会话、tus 上传等未完成内容的临时存储
+func NewTempStorage() (storage.Driver, error) {
+	return storage.NewLocalDriver(consts.TempDir)
+}
+
+// NewFileServiceWithStorage 使用指定的存储驱动创建文件服务，不校验 ACL、不统计用户配额，也不支持收藏与秒传
+func NewFileServiceWithStorage(store storage.Driver, temp storage.Driver) FileService {
+	return impl.NewFileServiceImpl(store, temp, nil, nil, nil, nil)
 }
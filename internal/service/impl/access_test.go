@@ -0,0 +1,116 @@
+package impl
+
+import (
+	"FileNest/internal/auth"
+	"FileNest/internal/model"
+	"context"
+	"errors"
+	"testing"
+)
+
+// setupLockedTree 创建 shared 目录，其中 locked 子目录只允许 alice 读取，hidden 子目录不允许 alice 读取
+func setupLockedTree(t *testing.T, env *testEnv) (*model.User, context.Context) {
+	t.Helper()
+	admin, err := env.users.CreateUser("root", "password1", model.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, ctx := env.createUser(t, "alice")
+
+	env.writeFile(t, "shared/a.txt", "a")
+	env.writeFile(t, "shared/locked/secret.txt", "secret")
+	env.writeFile(t, "shared/hidden/private.txt", "private")
+	env.writeFile(t, "open/b.txt", "b")
+
+	entries := func(permissions string) []model.ACLEntry {
+		return []model.ACLEntry{{SubjectType: model.SubjectUser, SubjectID: alice.ID, Permissions: permissions}}
+	}
+	if err := env.acl.SetACL(admin, "shared/locked", entries("viewer")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.acl.SetACL(admin, "shared/hidden", entries("write,delete")); err != nil {
+		t.Fatal(err)
+	}
+	return alice, ctx
+}
+
+func assertForbidden(t *testing.T, err error, path string) {
+	t.Helper()
+	var forbidden *auth.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		t.Fatalf("应返回 ForbiddenError, 实际 %v", err)
+	}
+	if forbidden.Path != path {
+		t.Errorf("被拒绝的路径 = %q, 期望 %q", forbidden.Path, path)
+	}
+}
+
+func TestForceDeleteChecksSubtree(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+
+	assertForbidden(t, env.service.DeleteFile(ctx, "shared", true), "shared/locked")
+	if !env.exists("shared/locked/secret.txt") || !env.exists("shared/a.txt") {
+		t.Fatal("被拒绝的删除不应删除任何内容")
+	}
+
+	if err := env.service.DeleteFile(ctx, "open", true); err != nil {
+		t.Fatalf("没有受限子项的目录应允许删除: %v", err)
+	}
+	if env.exists("open") {
+		t.Fatal("open 应已被删除")
+	}
+}
+
+func TestMoveAndRenameCheckSubtree(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+
+	assertForbidden(t, env.service.MoveFile(ctx, "shared", "open"), "shared/locked")
+	assertForbidden(t, env.service.RenameFile(ctx, "shared", "renamed"), "shared/locked")
+	if !env.exists("shared/locked/secret.txt") || env.exists("open/shared") || env.exists("renamed") {
+		t.Fatal("被拒绝的移动不应改变文件树")
+	}
+
+	if err := env.service.MoveFile(ctx, "shared/a.txt", "open"); err != nil {
+		t.Fatalf("移动普通文件失败: %v", err)
+	}
+	if got := env.readFile(t, "open/a.txt"); got != "a" {
+		t.Fatalf("移动后内容 = %q", got)
+	}
+}
+
+func TestCopyChecksSubtree(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+
+	assertForbidden(t, env.service.CopyFile(ctx, "shared", "open"), "shared/hidden")
+	if env.exists("open/shared") {
+		t.Fatal("被拒绝的复制不应创建任何内容")
+	}
+
+	// 只读的子目录可以被复制
+	if err := env.service.CopyFile(ctx, "shared/locked", "open"); err != nil {
+		t.Fatalf("复制可读目录失败: %v", err)
+	}
+	if got := env.readFile(t, "open/locked/secret.txt"); got != "secret" {
+		t.Fatalf("复制后内容 = %q", got)
+	}
+}
+
+func TestAdminBypassesSubtreeACL(t *testing.T) {
+	env := newTestEnv(t)
+	setupLockedTree(t, env)
+	admin, err := env.users.GetUser("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{User: admin})
+
+	if err := env.service.DeleteFile(ctx, "shared", true); err != nil {
+		t.Fatalf("管理员应不受 ACL 限制: %v", err)
+	}
+	if env.exists("shared") {
+		t.Fatal("shared 应已被删除")
+	}
+}
@@ -0,0 +1,431 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// allPermissions 管理员拥有全部权限
+var allPermissions = []string{model.PermRead, model.PermWrite, model.PermDelete, model.PermShare}
+
+type ACLServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewACLServiceImpl 创建 ACL 服务
+func NewACLServiceImpl(db *gorm.DB) *ACLServiceImpl {
+	return &ACLServiceImpl{db: db}
+}
+
+// GetACL 获取路径上的 ACL 与当前用户的有效权限
+func (s *ACLServiceImpl) GetACL(user *model.User, filePath string) (*model.ACLInfo, error) {
+	filePath, err := sandbox.Clean(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := s.evaluator(user, filePath)
+	if err != nil {
+		return nil, err
+	}
+	effective, inheritedFrom := evaluator.permissions(filePath)
+	if len(effective) == 0 {
+		return nil, &auth.ForbiddenError{Path: filePath}
+	}
+
+	entries := evaluator.entries[filePath]
+	s.fillSubjectNames(entries)
+	if entries == nil {
+		entries = []model.ACLEntry{}
+	}
+
+	return &model.ACLInfo{
+		Path:          filePath,
+		Entries:       entries,
+		InheritedFrom: inheritedFrom,
+		Effective:     effective,
+	}, nil
+}
+
+// SetACL 替换路径上的 ACL，需要该路径的 share 权限
+func (s *ACLServiceImpl) SetACL(user *model.User, filePath string, entries []model.ACLEntry) error {
+	filePath, err := sandbox.Clean(filePath)
+	if err != nil {
+		return err
+	}
+	if err := s.requireShare(user, filePath); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("ACL 不能为空，如需恢复继承请删除 ACL")
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		entry.Path = filePath
+		if err := s.resolveSubject(entry); err != nil {
+			return err
+		}
+		permissions, err := normalizePermissions(entry.Permissions)
+		if err != nil {
+			return err
+		}
+		entry.Permissions = permissions
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("path = ?", filePath).Delete(&model.ACLEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entries).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存 ACL 失败: %w", err)
+	}
+
+	glog.Infof("设置 ACL 成功, 用户: %s, 路径: /%s, 条目数: %d", user.Username, filePath, len(entries))
+	return nil
+}
+
+// RemoveACL 删除路径上的 ACL，恢复为继承上级目录
+func (s *ACLServiceImpl) RemoveACL(user *model.User, filePath string) error {
+	filePath, err := sandbox.Clean(filePath)
+	if err != nil {
+		return err
+	}
+	if err := s.requireShare(user, filePath); err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Where("path = ?", filePath).Delete(&model.ACLEntry{}).Error; err != nil {
+		return fmt.Errorf("删除 ACL 失败: %w", err)
+	}
+	glog.Infof("删除 ACL 成功, 用户: %s, 路径: /%s", user.Username, filePath)
+	return nil
+}
+
+// CreateGroup 创建用户组
+func (s *ACLServiceImpl) CreateGroup(name string) (*model.UserGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return nil, errors.New("用户组名称不能为空且长度不超过 64")
+	}
+
+	var count int64
+	if err := s.db.Model(&model.UserGroup{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("查询用户组失败: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("用户组已存在: %s", name)
+	}
+
+	group := &model.UserGroup{Name: name}
+	if err := s.db.Create(group).Error; err != nil {
+		return nil, fmt.Errorf("创建用户组失败: %w", err)
+	}
+	glog.Infof("创建用户组成功: %s", name)
+	return group, nil
+}
+
+// ListGroups 获取用户组列表
+func (s *ACLServiceImpl) ListGroups() ([]model.UserGroup, error) {
+	var groups []model.UserGroup
+	if err := s.db.Order("id").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("查询用户组失败: %w", err)
+	}
+	return groups, nil
+}
+
+// AddGroupMember 添加用户组成员
+func (s *ACLServiceImpl) AddGroupMember(groupID, userID uint) error {
+	if err := s.db.First(&model.UserGroup{}, groupID).Error; err != nil {
+		return fmt.Errorf("用户组不存在: %d", groupID)
+	}
+	if err := s.db.First(&model.User{}, userID).Error; err != nil {
+		return fmt.Errorf("用户不存在: %d", userID)
+	}
+
+	member := model.UserGroupMember{GroupID: groupID, UserID: userID}
+	var count int64
+	if err := s.db.Model(&model.UserGroupMember{}).Where(&member).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询用户组成员失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := s.db.Create(&member).Error; err != nil {
+		return fmt.Errorf("添加用户组成员失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveGroupMember 移除用户组成员
+func (s *ACLServiceImpl) RemoveGroupMember(groupID, userID uint) error {
+	err := s.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&model.UserGroupMember{}).Error
+	if err != nil {
+		return fmt.Errorf("移除用户组成员失败: %w", err)
+	}
+	return nil
+}
+
+// movePath 文件或文件夹移动后，让其自身及子路径上的 ACL 跟随移动
+func (s *ACLServiceImpl) movePath(oldPath, newPath string) error {
+	entries, err := s.subtreeEntries(oldPath)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			moved := newPath + strings.TrimPrefix(entry.Path, oldPath)
+			if err := tx.Model(&model.ACLEntry{}).Where("id = ?", entry.ID).Update("path", moved).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removePath 文件或文件夹删除后，清理其自身及子路径上的 ACL，避免同名新文件继承
+func (s *ACLServiceImpl) removePath(filePath string) error {
+	entries, err := s.subtreeEntries(filePath)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return s.db.Unscoped().Where("id IN ?", ids).Delete(&model.ACLEntry{}).Error
+}
+
+// subtreeEntries 查询路径自身及其子路径上的 ACL
+func (s *ACLServiceImpl) subtreeEntries(filePath string) ([]model.ACLEntry, error) {
+	var entries []model.ACLEntry
+	query := s.db.Model(&model.ACLEntry{})
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询 ACL 失败: %w", err)
+	}
+	return entries, nil
+}
+
+// evaluator 加载用户的用户组与 paths 所有上级目录上的 ACL；paths 为空时加载全部 ACL
+func (s *ACLServiceImpl) evaluator(user *model.User, paths ...string) (*aclEvaluator, error) {
+	evaluator := &aclEvaluator{
+		user:    user,
+		groups:  make(map[uint]bool),
+		entries: make(map[string][]model.ACLEntry),
+	}
+
+	var members []model.UserGroupMember
+	if err := s.db.Where("user_id = ?", user.ID).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("查询用户组失败: %w", err)
+	}
+	for _, member := range members {
+		evaluator.groups[member.GroupID] = true
+	}
+
+	query := s.db.Model(&model.ACLEntry{})
+	if len(paths) > 0 {
+		var ancestors []string
+		for _, p := range paths {
+			ancestors = append(ancestors, ancestorPaths(p)...)
+		}
+		query = query.Where("path IN ?", ancestors)
+	}
+	var entries []model.ACLEntry
+	if err := query.Order("id").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询 ACL 失败: %w", err)
+	}
+	for _, entry := range entries {
+		evaluator.entries[entry.Path] = append(evaluator.entries[entry.Path], entry)
+	}
+	return evaluator, nil
+}
+
+// requireShare 校验用户是否可以修改路径的 ACL
+func (s *ACLServiceImpl) requireShare(user *model.User, filePath string) error {
+	evaluator, err := s.evaluator(user, filePath)
+	if err != nil {
+		return err
+	}
+	if !evaluator.allows(filePath, model.PermShare) {
+		return &auth.ForbiddenError{Path: filePath}
+	}
+	return nil
+}
+
+// resolveSubject 根据名称或 ID 确定授权对象
+func (s *ACLServiceImpl) resolveSubject(entry *model.ACLEntry) error {
+	switch entry.SubjectType {
+	case model.SubjectUser:
+		var user model.User
+		query := s.db.Model(&model.User{})
+		if entry.SubjectName != "" {
+			query = query.Where("username = ?", entry.SubjectName)
+		} else {
+			query = query.Where("id = ?", entry.SubjectID)
+		}
+		if err := query.First(&user).Error; err != nil {
+			return fmt.Errorf("用户不存在: %s", subjectLabel(entry))
+		}
+		entry.SubjectID, entry.SubjectName = user.ID, user.Username
+	case model.SubjectGroup:
+		var group model.UserGroup
+		query := s.db.Model(&model.UserGroup{})
+		if entry.SubjectName != "" {
+			query = query.Where("name = ?", entry.SubjectName)
+		} else {
+			query = query.Where("id = ?", entry.SubjectID)
+		}
+		if err := query.First(&group).Error; err != nil {
+			return fmt.Errorf("用户组不存在: %s", subjectLabel(entry))
+		}
+		entry.SubjectID, entry.SubjectName = group.ID, group.Name
+	default:
+		return fmt.Errorf("未知的授权对象类型: %s", entry.SubjectType)
+	}
+	return nil
+}
+
+// fillSubjectNames 填充 ACL 条目中的用户名与用户组名
+func (s *ACLServiceImpl) fillSubjectNames(entries []model.ACLEntry) {
+	for i := range entries {
+		entry := &entries[i]
+		switch entry.SubjectType {
+		case model.SubjectUser:
+			var user model.User
+			if err := s.db.Unscoped().First(&user, entry.SubjectID).Error; err == nil {
+				entry.SubjectName = user.Username
+			}
+		case model.SubjectGroup:
+			var group model.UserGroup
+			if err := s.db.Unscoped().First(&group, entry.SubjectID).Error; err == nil {
+				entry.SubjectName = group.Name
+			}
+		}
+	}
+}
+
+// aclEvaluator 某个用户视角下的 ACL
+type aclEvaluator struct {
+	user    *model.User
+	groups  map[uint]bool
+	entries map[string][]model.ACLEntry
+}
+
+// permissions 返回用户在 filePath 上的权限，以及生效 ACL 所在的路径
+//
+// 从 filePath 向上查找，最近一个设置了 ACL 的路径决定权限；都没有时使用默认权限。
+func (e *aclEvaluator) permissions(filePath string) ([]string, *string) {
+	for _, p := range ancestorPaths(filePath) {
+		entries, ok := e.entries[p]
+		if !ok {
+			continue
+		}
+		from := p
+		if e.user.IsAdmin() {
+			return allPermissions, &from
+		}
+		var permissions []string
+		for _, entry := range entries {
+			if (entry.SubjectType == model.SubjectUser && entry.SubjectID == e.user.ID) ||
+				(entry.SubjectType == model.SubjectGroup && e.groups[entry.SubjectID]) {
+				permissions = mergePermissions(permissions, strings.Split(entry.Permissions, ","))
+			}
+		}
+		return permissions, &from
+	}
+	if e.user.IsAdmin() {
+		return allPermissions, nil
+	}
+	return config.ACL.DefaultPermissions, nil
+}
+
+// allows 判断是否拥有 permission 权限，permission 为空时只要求拥有任意权限
+func (e *aclEvaluator) allows(filePath, permission string) bool {
+	permissions, _ := e.permissions(filePath)
+	if permission == "" {
+		return len(permissions) > 0
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestorPaths 返回路径自身及所有上级目录，最后一项为根目录 ""
+func ancestorPaths(filePath string) []string {
+	paths := []string{filePath}
+	for filePath != "" {
+		filePath = path.Dir(filePath)
+		if filePath == "." {
+			filePath = ""
+		}
+		paths = append(paths, filePath)
+	}
+	return paths
+}
+
+// normalizePermissions 校验并去重权限列表，同时支持角色名
+func normalizePermissions(permissions string) (string, error) {
+	var result []string
+	for _, p := range strings.Split(permissions, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if role, ok := model.ACLRoles[p]; ok {
+			result = mergePermissions(result, role)
+			continue
+		}
+		switch p {
+		case model.PermRead, model.PermWrite, model.PermDelete, model.PermShare:
+			result = mergePermissions(result, []string{p})
+		default:
+			return "", fmt.Errorf("未知的权限: %s", p)
+		}
+	}
+	return strings.Join(result, ","), nil
+}
+
+func mergePermissions(permissions []string, add []string) []string {
+	for _, p := range add {
+		exists := false
+		for _, existing := range permissions {
+			if existing == p {
+				exists = true
+				break
+			}
+		}
+		if !exists && p != "" {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+func subjectLabel(entry *model.ACLEntry) string {
+	if entry.SubjectName != "" {
+		return entry.SubjectName
+	}
+	return fmt.Sprint(entry.SubjectID)
+}
+
+// escapeLike 转义 LIKE 语句中的通配符，配合 ESCAPE '!' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
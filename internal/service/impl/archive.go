@@ -0,0 +1,344 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveDir 将文件夹 dirPath 打包为 format 格式写入 w，归档中的路径相对于该文件夹
+//
+// 边遍历边写入，不生成临时文件，内存占用与文件夹大小无关。校验失败时不会写入任何内容；
+// 没有读权限或读取失败的文件不中断打包，记录在归档末尾的 consts.ArchiveManifestName 中。
+func (s *FileServiceImpl) ArchiveDir(ctx context.Context, dirPath, format string, w io.Writer) error {
+	if !validArchiveFormat(format) {
+		return fmt.Errorf("不支持的归档格式: %s", format)
+	}
+	dirPath, err := sandbox.Clean(dirPath)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, model.PermRead, dirPath); err != nil {
+		return err
+	}
+	info, err := s.storage.Stat(dirPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("文件夹不存在: %s", dirPath)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("不是文件夹: %s", dirPath)
+	}
+
+	glog.Infof("开始打包文件夹: %s, 格式: %s", dirPath, format)
+	report, err := s.writeArchive(ctx, format, w, []archiveRoot{{path: dirPath}})
+	if err != nil {
+		glog.Errorf("打包文件夹失败: %s, 路径: %s", err, dirPath)
+		return err
+	}
+	glog.Infof("打包文件夹完成: %s, 文件: %d, 大小: %d, 失败: %d", dirPath, report.files, report.bytes, report.failed)
+	return nil
+}
+
+// ArchivePaths 将多个文件或文件夹打包为 format 格式写入 w，每一项以其名称位于归档根部
+//
+// 每个路径都与下载一样校验路径与读权限，任一路径不合法时不写入任何内容；重复的路径只打包一次，
+// 名称相同的项按请求中的顺序依次命名为 "name (1).ext"、"name (2).ext"。
+func (s *FileServiceImpl) ArchivePaths(ctx context.Context, paths []string, format string, w io.Writer) error {
+	if !validArchiveFormat(format) {
+		return fmt.Errorf("不支持的归档格式: %s", format)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("没有要下载的文件")
+	}
+
+	roots := make([]archiveRoot, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	names := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		p, err := sandbox.Clean(p)
+		if err != nil {
+			return err
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if err := s.authorize(ctx, model.PermRead, p); err != nil {
+			return err
+		}
+		if _, err := s.storage.Stat(p); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("文件不存在: %s", p)
+			}
+			return err
+		}
+
+		base := path.Base(p)
+		if p == "" {
+			base = "FileNest"
+		}
+		name := base
+		for n := 1; names[name]; n++ {
+			name = numberedName(base, n)
+		}
+		names[name] = true
+		roots = append(roots, archiveRoot{path: p, name: name})
+	}
+
+	glog.Infof("开始打包下载: %d 项, 格式: %s", len(roots), format)
+	report, err := s.writeArchive(ctx, format, w, roots)
+	if err != nil {
+		glog.Errorf("打包下载失败: %s", err)
+		return err
+	}
+	glog.Infof("打包下载完成，文件: %d, 大小: %d, 失败: %d", report.files, report.bytes, report.failed)
+	return nil
+}
+
+// archiveRoot 写入归档的一个文件或目录，name 为其在归档中的名称，为空时目录的内容直接位于归档根部
+type archiveRoot struct {
+	path string
+	name string
+}
+
+// archiveReport 一次打包的统计
+type archiveReport struct {
+	files  int
+	bytes  int64
+	failed int
+}
+
+// writeArchive 依次将 roots 写入 format 格式的归档
+//
+// 没有读权限或无法读取的项记录在归档末尾的清单中；写入 w 失败（如客户端断开）时立即返回。
+func (s *FileServiceImpl) writeArchive(ctx context.Context, format string, w io.Writer, roots []archiveRoot) (*archiveReport, error) {
+	allowed, err := s.accessChecker(ctx, model.PermRead)
+	if err != nil {
+		return nil, err
+	}
+	aw, err := newArchiveWriter(format, w)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &archiveReport{}
+	var failures []string
+	fail := func(name string, err error) {
+		report.failed++
+		failures = append(failures, fmt.Sprintf("%s\t%s", name, err))
+		glog.Warnf("打包时跳过: %s, 原因: %s", name, err)
+	}
+	for _, root := range roots {
+		err := s.storage.Walk(root.path, func(p string, info fs.FileInfo, err error) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			p = filepath.ToSlash(p)
+			name := archiveEntryName(root, p)
+			if err != nil {
+				fail(name, err)
+				return nil
+			}
+			if !info.IsDir() && isMergeTemp(info.Name()) {
+				// 正在合并的临时文件
+				return nil
+			}
+			if !allowed(p) {
+				fail(name, errors.New("没有读取权限"))
+				if info.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if name == "" {
+					return nil
+				}
+				return aw.WriteDir(name, info.ModTime())
+			}
+
+			file, err := s.storage.Open(p)
+			if err != nil {
+				fail(name, err)
+				return nil
+			}
+			defer file.Close()
+			src := &archiveSource{reader: file}
+			if err := aw.WriteFile(name, info.Size(), info.ModTime(), src); err != nil {
+				if src.err == nil {
+					return err
+				}
+				// 已写入的部分内容保留在归档中，在清单中说明
+				fail(name, src.err)
+				return nil
+			}
+			report.files++
+			report.bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(failures) > 0 {
+		manifest := "以下文件未能打包:\n" + strings.Join(failures, "\n") + "\n"
+		if err := aw.WriteFile(consts.ArchiveManifestName, int64(len(manifest)), time.Now(), strings.NewReader(manifest)); err != nil {
+			return nil, err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// archiveEntryName p 在归档中的名称
+func archiveEntryName(root archiveRoot, p string) string {
+	if p == root.path {
+		return root.name
+	}
+	rel := p
+	if root.path != "" {
+		rel = strings.TrimPrefix(p, root.path+"/")
+	}
+	return path.Join(root.name, rel)
+}
+
+// validArchiveFormat 是否为支持的归档格式
+func validArchiveFormat(format string) bool {
+	switch format {
+	case consts.ArchiveFormatZip, consts.ArchiveFormatTar, consts.ArchiveFormatTarGz:
+		return true
+	}
+	return false
+}
+
+// archiveSource 记录读取文件时的错误，用于区分读取失败与写入失败
+type archiveSource struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *archiveSource) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// archiveWriter 归档格式的写入器，名称以 "/" 分隔
+type archiveWriter interface {
+	// WriteDir 写入目录项
+	WriteDir(name string, modTime time.Time) error
+	// WriteFile 写入文件项，从 reader 读取 size 字节
+	WriteFile(name string, size int64, modTime time.Time, reader io.Reader) error
+	// Close 写入归档的结尾，不关闭底层的 io.Writer
+	Close() error
+}
+
+// newArchiveWriter 创建 format 格式的归档写入器
+func newArchiveWriter(format string, w io.Writer) (archiveWriter, error) {
+	switch format {
+	case consts.ArchiveFormatZip:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	case consts.ArchiveFormatTar:
+		return &tarArchiveWriter{tw: tar.NewWriter(w)}, nil
+	case consts.ArchiveFormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{tw: tar.NewWriter(gz), gz: gz}, nil
+	}
+	return nil, fmt.Errorf("不支持的归档格式: %s", format)
+}
+
+// zipArchiveWriter ZIP 归档，文件大小与 CRC 写在内容之后，无需预先读取文件
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) WriteDir(name string, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name + "/", Modified: modTime}
+	header.SetMode(fs.ModeDir | 0755)
+	_, err := a.zw.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchiveWriter) WriteFile(name string, size int64, modTime time.Time, reader io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+	header.SetMode(0644)
+	writer, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, reader)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.zw.Close()
+}
+
+// tarArchiveWriter tar 归档，gz 不为 nil 时使用 gzip 压缩
+type tarArchiveWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (a *tarArchiveWriter) WriteDir(name string, modTime time.Time) error {
+	return a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755, ModTime: modTime})
+}
+
+func (a *tarArchiveWriter) WriteFile(name string, size int64, modTime time.Time, reader io.Reader) error {
+	if err := a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0644, ModTime: modTime}); err != nil {
+		return err
+	}
+	written, err := io.Copy(a.tw, io.LimitReader(reader, size))
+	if err == nil && written < size {
+		err = io.ErrUnexpectedEOF
+		if source, ok := reader.(*archiveSource); ok {
+			source.err = fmt.Errorf("文件在打包过程中变小: %d/%d 字节", written, size)
+		}
+	}
+	if written < size {
+		// 头部已声明大小，用 0 补齐以保持归档结构完整
+		if _, padErr := io.CopyN(a.tw, zeroReader{}, size-written); padErr != nil {
+			return padErr
+		}
+	}
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.gz != nil {
+		return a.gz.Close()
+	}
+	return nil
+}
+
+// zeroReader 无限读出 0
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
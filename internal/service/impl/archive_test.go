@@ -0,0 +1,195 @@
+package impl
+
+import (
+	"FileNest/internal/consts"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readArchive 解开 format 格式的归档，返回每一项的内容，目录以 / 结尾且内容为空
+func readArchive(t *testing.T, format string, data []byte) map[string]string {
+	t.Helper()
+	entries := make(map[string]string)
+	if format == consts.ArchiveFormatZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("解析 zip 失败: %v", err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("读取 %s 失败: %v", f.Name, err)
+			}
+			entries[f.Name] = string(b)
+		}
+		return entries
+	}
+
+	var reader io.Reader = bytes.NewReader(data)
+	if format == consts.ArchiveFormatTarGz {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			t.Fatalf("解析 gzip 失败: %v", err)
+		}
+		reader = gz
+	}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("解析 tar 失败: %v", err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", header.Name, err)
+		}
+		entries[header.Name] = string(b)
+	}
+}
+
+func TestArchiveDir(t *testing.T) {
+	env := newTestEnv(t)
+	env.writeFile(t, "docs/a.txt", "hello")
+	env.writeFile(t, "docs/sub/b.txt", "world")
+	env.writeFile(t, "docs/"+consts.MergeTempPrefix+"c.txt", "partial")
+	env.writeFile(t, "other.txt", "other")
+	if err := env.store.Mkdir("docs/empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"a.txt":     "hello",
+		"empty/":    "",
+		"sub/":      "",
+		"sub/b.txt": "world",
+	}
+	for _, format := range []string{consts.ArchiveFormatZip, consts.ArchiveFormatTar, consts.ArchiveFormatTarGz} {
+		var buf bytes.Buffer
+		if err := env.service.ArchiveDir(context.Background(), "docs", format, &buf); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got := readArchive(t, format, buf.Bytes()); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s 归档内容 = %v, 期望 %v", format, got, want)
+		}
+	}
+}
+
+func TestArchiveDirRejectsInvalidRequest(t *testing.T) {
+	env := newTestEnv(t)
+	env.writeFile(t, "docs/a.txt", "hello")
+	ctx := context.Background()
+
+	cases := []struct {
+		path   string
+		format string
+	}{
+		{"docs", "rar"},
+		{"docs/a.txt", consts.ArchiveFormatZip},
+		{"missing", consts.ArchiveFormatZip},
+		{"../docs", consts.ArchiveFormatZip},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := env.service.ArchiveDir(ctx, c.path, c.format, &buf); err == nil {
+			t.Errorf("打包 %s (%s) 应失败", c.path, c.format)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("打包 %s (%s) 失败时写入了 %d 字节", c.path, c.format, buf.Len())
+		}
+	}
+}
+
+func TestArchiveDirSkipsUnreadable(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+
+	var buf bytes.Buffer
+	if err := env.service.ArchiveDir(ctx, "shared", consts.ArchiveFormatZip, &buf); err != nil {
+		t.Fatal(err)
+	}
+	entries := readArchive(t, consts.ArchiveFormatZip, buf.Bytes())
+	if entries["a.txt"] != "a" || entries["locked/secret.txt"] != "secret" {
+		t.Errorf("有读权限的文件未打包: %v", entries)
+	}
+	for name := range entries {
+		if strings.HasPrefix(name, "hidden") {
+			t.Errorf("没有读权限的 %s 不应打包", name)
+		}
+	}
+	manifest, ok := entries[consts.ArchiveManifestName]
+	if !ok || !strings.Contains(manifest, "hidden\t没有读取权限") {
+		t.Errorf("清单中应记录跳过的目录: %q", manifest)
+	}
+
+	// 没有读权限的文件夹本身不能打包
+	buf.Reset()
+	err := env.service.ArchiveDir(ctx, "shared/hidden", consts.ArchiveFormatZip, &buf)
+	assertForbidden(t, err, "shared/hidden")
+	if buf.Len() > 0 {
+		t.Errorf("没有权限时写入了 %d 字节", buf.Len())
+	}
+}
+
+func TestArchivePaths(t *testing.T) {
+	env := newTestEnv(t)
+	env.writeFile(t, "docs/a.txt", "docs")
+	env.writeFile(t, "docs/sub/b.txt", "b")
+	env.writeFile(t, "other/a.txt", "other")
+	env.writeFile(t, "more/a.txt", "more")
+
+	var buf bytes.Buffer
+	paths := []string{"docs/a.txt", "other/a.txt", "docs/sub", "docs/a.txt", "more/a.txt"}
+	if err := env.service.ArchivePaths(context.Background(), paths, consts.ArchiveFormatTar, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a.txt":     "docs",
+		"a (1).txt": "other",
+		"a (2).txt": "more",
+		"sub/":      "",
+		"sub/b.txt": "b",
+	}
+	if got := readArchive(t, consts.ArchiveFormatTar, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("归档内容 = %v, 期望 %v", got, want)
+	}
+}
+
+func TestArchivePathsRejectsInvalidPath(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+
+	for _, paths := range [][]string{
+		nil,
+		{"shared/a.txt", "missing.txt"},
+		{"shared/a.txt", "../etc"},
+		{"shared/a.txt", "shared/hidden"},
+	} {
+		var buf bytes.Buffer
+		if err := env.service.ArchivePaths(ctx, paths, consts.ArchiveFormatZip, &buf); err == nil {
+			t.Errorf("打包 %v 应失败", paths)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("打包 %v 失败时写入了 %d 字节", paths, buf.Len())
+		}
+	}
+
+	// 任一路径没有读权限时整个请求被拒绝
+	err := env.service.ArchivePaths(ctx, []string{"shared/a.txt", "shared/hidden"}, consts.ArchiveFormatZip, io.Discard)
+	assertForbidden(t, err, "shared/hidden")
+}
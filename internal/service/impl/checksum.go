@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// normalizeChecksum 校验客户端提供的摘要，统一为小写；未提供摘要时返回 nil
+func normalizeChecksum(checksum *model.Checksum) (*model.Checksum, error) {
+	if checksum == nil || checksum.Value == "" {
+		return nil, nil
+	}
+	algorithm := strings.ToLower(strings.TrimSpace(checksum.Algorithm))
+	if algorithm == "" {
+		algorithm = consts.ChecksumSHA256
+	}
+	digest, err := newDigest(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	value := strings.ToLower(strings.TrimSpace(checksum.Value))
+	if raw, err := hex.DecodeString(value); err != nil || len(raw) != digest.Size() {
+		return nil, fmt.Errorf("摘要格式错误: %s", checksum.Value)
+	}
+	return &model.Checksum{Algorithm: algorithm, Value: value}, nil
+}
+
+// newDigest 按算法名创建摘要计算器
+func newDigest(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case consts.ChecksumMD5:
+		return md5.New(), nil
+	case consts.ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("不支持的摘要算法: %s", algorithm)
+	}
+}
+
+// verifyChecksum 比较计算出的摘要与期望值，expected 为 nil 时不校验
+func verifyChecksum(target string, expected *model.Checksum, digest hash.Hash) error {
+	if expected == nil {
+		return nil
+	}
+	actual := hex.EncodeToString(digest.Sum(nil))
+	if actual != expected.Value {
+		return &model.ChecksumMismatchError{Target: target, Expected: *expected, Actual: actual}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package impl
+
+import (
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sumOf 计算 content 的摘要
+func sumOf(algorithm, content string) *model.Checksum {
+	digest, _ := newDigest(algorithm)
+	digest.Write([]byte(content))
+	return &model.Checksum{Algorithm: algorithm, Value: hex.EncodeToString(digest.Sum(nil))}
+}
+
+// assertChecksumMismatch 断言 err 为摘要不一致错误
+func assertChecksumMismatch(t *testing.T, err error, action string) {
+	t.Helper()
+	var mismatch *model.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("%s 应返回摘要不一致错误, 实际 %v", action, err)
+	}
+}
+
+func TestSessionChunkChecksumMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	session, err := env.service.CreateUploadSession(ctx, "docs", "a.txt", 8, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.service.SaveSessionChunk(ctx, session.ID, 0, strings.NewReader("1234"), sumOf(consts.ChecksumMD5, "1234")); err != nil {
+		t.Fatalf("摘要正确的分块保存失败: %v", err)
+	}
+	// 传输中损坏的分块被拒绝，已接收的分块保留
+	err = env.service.SaveSessionChunk(ctx, session.ID, 1, strings.NewReader("56x8"), sumOf(consts.ChecksumMD5, "5678"))
+	assertChecksumMismatch(t, err, "保存损坏的分块")
+	status, err := env.service.GetUploadSession(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Chunks) != 1 || status.Chunks[0].Index != 0 || status.ReceivedSize != 4 {
+		t.Fatalf("校验失败后的会话状态 = %+v", status)
+	}
+	if _, err := env.temp.Stat(filepath.Join(sessionDir(session.ID), chunkName(1))); err == nil {
+		t.Error("校验失败的分块不应保留")
+	}
+
+	if err := env.service.SaveSessionChunk(ctx, session.ID, 1, strings.NewReader("5678"), sumOf(consts.ChecksumSHA256, "5678")); err != nil {
+		t.Fatalf("重新上传分块失败: %v", err)
+	}
+	if _, _, err := env.service.MergeUploadSession(ctx, session.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.readFile(t, "docs/a.txt"); got != "12345678" {
+		t.Errorf("合并后内容 = %q", got)
+	}
+
+	bad := &model.Checksum{Algorithm: consts.ChecksumMD5, Value: "not-hex"}
+	other, err := env.service.CreateUploadSession(ctx, "docs", "b.txt", 4, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.SaveSessionChunk(ctx, other.ID, 0, strings.NewReader("1234"), bad); err == nil {
+		t.Error("格式错误的摘要应被拒绝")
+	}
+}
+
+func TestMergeChecksumMismatchKeepsChunks(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	content := "hello, checksum"
+	expected := sumOf(consts.ChecksumSHA256, content+"!")
+	session, err := env.service.CreateUploadSession(ctx, "docs", "a.txt", int64(len(content)), 8, false, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < session.TotalChunks; i++ {
+		end := min((i+1)*8, len(content))
+		if err := env.service.SaveSessionChunk(ctx, session.ID, i, strings.NewReader(content[i*8:end]), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 整个文件的摘要与创建会话时提供的不一致，合并失败但分块保留
+	_, _, err = env.service.MergeUploadSession(ctx, session.ID, nil)
+	assertChecksumMismatch(t, err, "合并摘要不一致的文件")
+	if env.exists("docs/a.txt") {
+		t.Error("校验失败的文件不应保存")
+	}
+	if entries, _ := env.store.List("docs"); len(entries) != 0 {
+		t.Errorf("校验失败后目标目录中留有 %d 项", len(entries))
+	}
+	status, err := env.service.GetUploadSession(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != consts.UploadStatusUploading || status.ReceivedChunks != session.TotalChunks || status.Error == "" {
+		t.Fatalf("校验失败后的会话状态 = %+v", status)
+	}
+
+	// 合并时提供的摘要优先于创建会话时的摘要，返回按该算法计算的摘要
+	md5Sum := md5.Sum([]byte(content))
+	want := &model.Checksum{Algorithm: consts.ChecksumMD5, Value: hex.EncodeToString(md5Sum[:])}
+	filePath, checksum, err := env.service.MergeUploadSession(ctx, session.ID, want)
+	if err != nil {
+		t.Fatalf("使用正确摘要合并失败: %v", err)
+	}
+	if filePath != "docs/a.txt" || *checksum != *want {
+		t.Errorf("合并结果 = %q, %+v", filePath, checksum)
+	}
+	if _, err := env.temp.Stat(sessionDir(session.ID)); err == nil {
+		t.Error("合并成功后分块应删除")
+	}
+
+	// 摘要索引始终使用 SHA-256
+	sha := sha256.Sum256([]byte(content))
+	hashes, err := env.service.hashes.find(hex.EncodeToString(sha[:]), int64(len(content)))
+	if err != nil || len(hashes) != 1 || hashes[0].Path != "docs/a.txt" {
+		t.Errorf("摘要索引 = %+v, %v", hashes, err)
+	}
+}
@@ -0,0 +1,187 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/storage"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// InstantUpload 秒传：存储中已有 SHA-256 与大小都相同的文件时，直接在目标位置创建副本并返回文件路径
+//
+// 没有可用的副本时返回空路径，客户端继续正常上传。只会使用当前用户有读权限的文件作为来源，
+// 避免仅凭摘要获取无权访问的内容。
+func (s *FileServiceImpl) InstantUpload(ctx context.Context, path, fileName, contentHash string, size int64, override bool) (string, error) {
+	checksum, err := normalizeChecksum(&model.Checksum{Algorithm: consts.ChecksumSHA256, Value: contentHash})
+	if err != nil {
+		return "", err
+	}
+	if checksum == nil {
+		return "", fmt.Errorf("文件摘要不能为空")
+	}
+	if size < 0 {
+		return "", fmt.Errorf("文件大小不合法: %d", size)
+	}
+	if err := s.CheckUpload(ctx, path, fileName, size, override); err != nil {
+		return "", err
+	}
+	if s.hashes == nil {
+		return "", nil
+	}
+
+	path, _ = sandbox.Clean(path)
+	fileName, _ = sandbox.CleanName(fileName)
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	source, err := s.findDuplicate(ctx, checksum.Value, size)
+	if err != nil || source == "" {
+		return "", err
+	}
+	if source == filePath {
+		// 目标文件的内容已经相同
+		return filePath, nil
+	}
+	if err := s.checkSourceType(source, filePath); err != nil {
+		return "", err
+	}
+
+	if _, err := s.prepareUpload(ctx, path, fileName, override, size); err != nil {
+		return "", err
+	}
+	defer s.trackFolderUsage(filePath)()
+	if err := s.placeDuplicate(source, filePath); err != nil {
+		return "", fmt.Errorf("秒传失败: %s", err)
+	}
+	s.recordHash(filePath, checksum.Value)
+	s.recordUsage(ctx, filePath)
+
+	// 清除相关缓存
+	s.clearFileRelatedCache(filePath)
+
+	glog.Infof("秒传成功: %s -> %s", source, filePath)
+	return filePath, nil
+}
+
+// findDuplicate 查找内容相同且当前用户可读的文件，索引与文件不一致时移除该索引
+func (s *FileServiceImpl) findDuplicate(ctx context.Context, contentHash string, size int64) (string, error) {
+	candidates, err := s.hashes.find(contentHash, size)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range candidates {
+		info, err := s.storage.Stat(candidate.Path)
+		if err != nil || info.IsDir() || info.Size() != size || info.ModTime().Unix() != candidate.ModTime.Unix() {
+			// 文件已被删除或在服务外被修改
+			if err := s.hashes.removePath(candidate.Path); err != nil {
+				glog.Warnf("移除失效的文件摘要失败: %s, 路径: %s", err, candidate.Path)
+			}
+			continue
+		}
+		if s.authorize(ctx, model.PermRead, candidate.Path) != nil {
+			continue
+		}
+		return candidate.Path, nil
+	}
+	return "", nil
+}
+
+// checkSourceType 按源文件的内容校验目标位置的上传策略
+func (s *FileServiceImpl) checkSourceType(srcPath, destPath string) error {
+	file, err := s.storage.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %s", err)
+	}
+	defer file.Close()
+	head, _, err := peekHead(file)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %s", err)
+	}
+	return checkUploadType(destPath, head)
+}
+
+// placeDuplicate 在目标位置创建源文件的副本，存储支持时使用硬链接
+func (s *FileServiceImpl) placeDuplicate(srcPath, destPath string) error {
+	if linker, ok := s.storage.(storage.Linker); ok {
+		err := linker.Link(srcPath, destPath)
+		if err == nil {
+			return nil
+		}
+		// 跨设备或文件系统不支持硬链接时退回到复制
+		glog.Warnf("创建硬链接失败，改为复制: %s, 路径: %s -> %s", err, srcPath, destPath)
+	}
+	return s.copyFileContent(srcPath, destPath)
+}
+
+// recordHash 记录写入完成的文件的 SHA-256
+func (s *FileServiceImpl) recordHash(filePath, contentHash string) {
+	if s.hashes == nil {
+		return
+	}
+	info, err := s.storage.Stat(filePath)
+	if err == nil {
+		err = s.hashes.record([]model.FileHash{{Path: filePath, Hash: contentHash, Size: info.Size(), ModTime: info.ModTime()}})
+	}
+	if err != nil {
+		glog.Errorf("记录文件摘要失败: %s, 路径: %s", err, filePath)
+	}
+}
+
+// copyHashes 复制完成后，为目标路径下的文件沿用源文件的摘要
+func (s *FileServiceImpl) copyHashes(srcPath, destPath string) {
+	if s.hashes == nil {
+		return
+	}
+	sources, err := s.hashes.subtreeHashes(srcPath)
+	if err != nil || len(sources) == 0 {
+		if err != nil {
+			glog.Errorf("复制文件摘要失败: %s, 路径: %s -> %s", err, srcPath, destPath)
+		}
+		return
+	}
+	bySrc := make(map[string]model.FileHash, len(sources))
+	for _, h := range sources {
+		bySrc[h.Path] = h
+	}
+
+	var hashes []model.FileHash
+	err = s.storage.Walk(destPath, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		path = filepath.ToSlash(path)
+		src, ok := bySrc[srcPath+strings.TrimPrefix(path, destPath)]
+		if !ok || src.Size != info.Size() {
+			return nil
+		}
+		// 源文件在服务外被修改过时索引已失效
+		if srcInfo, err := s.storage.Stat(src.Path); err == nil && srcInfo.ModTime().Unix() == src.ModTime.Unix() {
+			hashes = append(hashes, model.FileHash{Path: path, Hash: src.Hash, Size: src.Size, ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err == nil {
+		err = s.hashes.record(hashes)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		glog.Errorf("复制文件摘要失败: %s, 路径: %s -> %s", err, srcPath, destPath)
+	}
+}
+
+// moveHashes 文件移动或重命名后同步摘要索引
+func (s *FileServiceImpl) moveHashes(oldPath, newPath string) {
+	if s.hashes == nil {
+		return
+	}
+	if err := s.hashes.movePath(oldPath, newPath); err != nil {
+		glog.Errorf("同步文件摘要失败: %s, 路径: %s -> %s", err, oldPath, newPath)
+	}
+}
@@ -0,0 +1,137 @@
+package impl
+
+import (
+	"FileNest/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sha256Hex 计算 content 的 SHA-256
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// saveIndexed 通过文件服务保存文件，写入时记录摘要索引
+func saveIndexed(t *testing.T, env *testEnv, dir, name, content string) {
+	t.Helper()
+	if _, err := env.service.SaveFile(context.Background(), dir, name, strings.NewReader(content), false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstantUploadHitAndMiss(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	content := "installer bytes"
+	saveIndexed(t, env, "docs", "setup.bin", content)
+
+	filePath, err := env.service.InstantUpload(ctx, "other", "setup.bin", sha256Hex(content), int64(len(content)), false)
+	if err != nil || filePath != "other/setup.bin" {
+		t.Fatalf("秒传结果 = %q, %v", filePath, err)
+	}
+	if got := env.readFile(t, "other/setup.bin"); got != content {
+		t.Errorf("秒传后内容 = %q", got)
+	}
+	// 本地存储使用硬链接
+	src, _ := os.Stat(filepath.Join(env.store.Root(), "docs", "setup.bin"))
+	dst, _ := os.Stat(filepath.Join(env.store.Root(), "other", "setup.bin"))
+	if !os.SameFile(src, dst) {
+		t.Error("本地存储的秒传应使用硬链接")
+	}
+
+	// 同名文件已存在且不覆盖时失败
+	if _, err := env.service.InstantUpload(ctx, "other", "setup.bin", sha256Hex(content), int64(len(content)), false); err == nil {
+		t.Error("同名文件已存在时秒传应失败")
+	}
+
+	// 摘要或大小不同时未命中，客户端继续正常上传
+	for _, c := range []struct {
+		hash string
+		size int64
+	}{
+		{sha256Hex("other bytes"), int64(len(content))},
+		{sha256Hex(content), int64(len(content)) + 1},
+	} {
+		filePath, err := env.service.InstantUpload(ctx, "more", "setup.bin", c.hash, c.size, false)
+		if err != nil || filePath != "" {
+			t.Errorf("未命中时秒传结果 = %q, %v", filePath, err)
+		}
+	}
+	if env.exists("more/setup.bin") {
+		t.Error("未命中时不应创建文件")
+	}
+	if _, err := env.service.InstantUpload(ctx, "more", "setup.bin", "xyz", 1, false); err == nil {
+		t.Error("格式错误的摘要应被拒绝")
+	}
+}
+
+func TestInstantUploadSkipsStaleIndex(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	content := "dataset"
+	saveIndexed(t, env, "docs", "a.csv", content)
+
+	// 服务外修改了文件，索引不再可信
+	env.writeFile(t, "docs/a.csv", "DATASET")
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(env.store.Root(), "docs", "a.csv"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath, err := env.service.InstantUpload(ctx, "other", "a.csv", sha256Hex(content), int64(len(content)), false)
+	if err != nil || filePath != "" {
+		t.Fatalf("索引失效时秒传结果 = %q, %v", filePath, err)
+	}
+	if hashes, _ := env.service.hashes.find(sha256Hex(content), int64(len(content))); len(hashes) != 0 {
+		t.Errorf("失效的索引应被移除: %+v", hashes)
+	}
+}
+
+func TestInstantUploadChecksACL(t *testing.T) {
+	env := newTestEnv(t)
+	admin, err := env.users.CreateUser("root", "password1", model.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, ctx := env.createUser(t, "alice")
+	content := "private bytes"
+	saveIndexed(t, env, "shared/hidden", "secret.bin", content)
+	entries := func(permissions string) []model.ACLEntry {
+		return []model.ACLEntry{{SubjectType: model.SubjectUser, SubjectID: alice.ID, Permissions: permissions}}
+	}
+	if err := env.acl.SetACL(admin, "shared/hidden", entries("write")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.acl.SetACL(admin, "shared/readonly", entries("viewer")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有读权限的文件不能作为来源，仅凭摘要无法获取其内容
+	filePath, err := env.service.InstantUpload(ctx, "mine", "secret.bin", sha256Hex(content), int64(len(content)), false)
+	if err != nil || filePath != "" {
+		t.Fatalf("来源不可读时秒传结果 = %q, %v", filePath, err)
+	}
+	if env.exists("mine/secret.bin") {
+		t.Error("来源不可读时不应创建文件")
+	}
+
+	// 目标位置没有写权限时拒绝
+	_, err = env.service.InstantUpload(ctx, "shared/readonly", "a.bin", sha256Hex(content), int64(len(content)), false)
+	assertForbidden(t, err, "shared/readonly/a.bin")
+
+	// 获得读权限后命中
+	if err := env.acl.SetACL(admin, "shared/hidden", entries("viewer")); err != nil {
+		t.Fatal(err)
+	}
+	filePath, err = env.service.InstantUpload(ctx, "mine", "secret.bin", sha256Hex(content), int64(len(content)), false)
+	if err != nil || filePath != "mine/secret.bin" {
+		t.Fatalf("来源可读时秒传结果 = %q, %v", filePath, err)
+	}
+}
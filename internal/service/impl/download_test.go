@@ -0,0 +1,121 @@
+package impl
+
+import (
+	"FileNest/internal/utils/download"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveDownload 按下载接口的方式发送 filePath，header 为附加的请求头
+func serveDownload(t *testing.T, env *testEnv, ctx context.Context, filePath, disposition string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	file, info, err := env.service.DownloadFile(ctx, filePath)
+	if err != nil {
+		t.Fatalf("下载 %s 失败: %v", filePath, err)
+	}
+	defer file.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/file/download", nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	download.ServeFile(w, r, file, info, disposition)
+	return w
+}
+
+func TestDownloadConditionalRequests(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	env.writeFile(t, "docs/report.pdf", "0123456789")
+
+	w := serveDownload(t, env, ctx, "docs/report.pdf", download.Attachment, nil)
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" || etag == "" || lastModified == "" {
+		t.Fatalf("下载响应 = %d %q, ETag %q, Last-Modified %q", w.Code, w.Body.String(), etag, lastModified)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=report.pdf" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if again := serveDownload(t, env, ctx, "docs/report.pdf", download.Attachment, nil); again.Header().Get("ETag") != etag {
+		t.Errorf("文件未修改时 ETag 应保持不变: %q, %q", etag, again.Header().Get("ETag"))
+	}
+
+	// 缓存仍然有效时返回 304 且不发送内容
+	for _, header := range []map[string]string{
+		{"If-None-Match": etag},
+		{"If-None-Match": `"other", ` + etag},
+		{"If-Modified-Since": lastModified},
+	} {
+		if w := serveDownload(t, env, ctx, "docs/report.pdf", download.Attachment, header); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%v: 响应 = %d, %d 字节", header, w.Code, w.Body.Len())
+		}
+	}
+
+	// 文件被覆盖后 ETag 改变，旧的缓存失效
+	if _, err := env.service.SaveFile(ctx, "docs", "report.pdf", strings.NewReader("new content"), true); err != nil {
+		t.Fatal(err)
+	}
+	w = serveDownload(t, env, ctx, "docs/report.pdf", download.Attachment, map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusOK || w.Body.String() != "new content" || w.Header().Get("ETag") == etag {
+		t.Errorf("覆盖后的响应 = %d %q, ETag %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.writeFile(t, "video.mp4", "0123456789")
+	etag := serveDownload(t, env, ctx, "video.mp4", download.Inline, nil).Header().Get("ETag")
+
+	w := serveDownload(t, env, ctx, "video.mp4", download.Inline, map[string]string{"Range": "bytes=2-5"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" || w.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Errorf("单段范围响应 = %d %q, Content-Range %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+
+	w = serveDownload(t, env, ctx, "video.mp4", download.Inline, map[string]string{"Range": "bytes=0-1,8-9"})
+	body := w.Body.String()
+	if w.Code != http.StatusPartialContent || !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") ||
+		!strings.Contains(body, "Content-Range: bytes 0-1/10") || !strings.Contains(body, "\r\n\r\n01\r\n") ||
+		!strings.Contains(body, "Content-Range: bytes 8-9/10") || !strings.Contains(body, "\r\n\r\n89\r\n") {
+		t.Errorf("多段范围响应 = %d %q\n%s", w.Code, w.Header().Get("Content-Type"), body)
+	}
+
+	// If-Range 与当前 ETag 一致时返回范围，否则返回完整内容
+	w = serveDownload(t, env, ctx, "video.mp4", download.Inline, map[string]string{"Range": "bytes=5-", "If-Range": etag})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "56789" {
+		t.Errorf("If-Range 一致时的响应 = %d %q", w.Code, w.Body.String())
+	}
+	w = serveDownload(t, env, ctx, "video.mp4", download.Inline, map[string]string{"Range": "bytes=5-", "If-Range": `"stale"`})
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("If-Range 不一致时的响应 = %d %q", w.Code, w.Body.String())
+	}
+
+	if w := serveDownload(t, env, ctx, "video.mp4", download.Inline, map[string]string{"Range": "bytes=20-30"}); w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("超出范围的响应 = %d", w.Code)
+	}
+}
+
+func TestDownloadInlineAndAccess(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+	env.writeFile(t, "shared/预览.html", "<script>alert(1)</script>")
+
+	w := serveDownload(t, env, ctx, "shared/预览.html", download.Inline, nil)
+	if got := w.Header().Get("Content-Disposition"); got != "inline; filename*=utf-8''%E9%A2%84%E8%A7%88.html" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if w.Header().Get("Content-Security-Policy") != "sandbox" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("预览时的安全响应头 = %v", w.Header())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+
+	// 没有读权限的文件不能下载
+	_, _, err := env.service.DownloadFile(ctx, "shared/hidden/private.txt")
+	assertForbidden(t, err, "shared/hidden/private.txt")
+}
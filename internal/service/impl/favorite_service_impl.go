@@ -0,0 +1,95 @@
+package impl
+
+import (
+	"FileNest/internal/model"
+	"fmt"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FavoriteServiceImpl 持久化用户的收藏
+type FavoriteServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewFavoriteServiceImpl 创建收藏服务
+func NewFavoriteServiceImpl(db *gorm.DB) *FavoriteServiceImpl {
+	return &FavoriteServiceImpl{db: db}
+}
+
+// add 添加收藏，路径已收藏时只更新文件信息
+func (s *FavoriteServiceImpl) add(favorite *model.Favorite) error {
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "is_dir"}),
+	}).Create(favorite).Error
+	if err != nil {
+		return fmt.Errorf("保存收藏失败: %w", err)
+	}
+	return nil
+}
+
+// remove 取消收藏
+func (s *FavoriteServiceImpl) remove(userID uint, filePath string) error {
+	err := s.db.Where("user_id = ? AND path = ?", userID, filePath).Delete(&model.Favorite{}).Error
+	if err != nil {
+		return fmt.Errorf("取消收藏失败: %w", err)
+	}
+	return nil
+}
+
+// list 查询用户的收藏，按收藏时间倒序
+func (s *FavoriteServiceImpl) list(userID uint) ([]model.Favorite, error) {
+	favorites := []model.Favorite{}
+	if err := s.db.Where("user_id = ?", userID).Order("create_time DESC, id DESC").Find(&favorites).Error; err != nil {
+		return nil, fmt.Errorf("查询收藏失败: %w", err)
+	}
+	return favorites, nil
+}
+
+// movePath 文件或文件夹移动、重命名后，同步所有用户对其自身及子路径的收藏
+func (s *FavoriteServiceImpl) movePath(oldPath, newPath string) error {
+	favorites, err := s.subtreeFavorites(oldPath)
+	if err != nil || len(favorites) == 0 {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, favorite := range favorites {
+			updates := map[string]interface{}{
+				"path": newPath + strings.TrimPrefix(favorite.Path, oldPath),
+			}
+			if favorite.Path == oldPath {
+				updates["name"] = path.Base(newPath)
+			}
+			if err := tx.Model(&model.Favorite{}).Where("id = ?", favorite.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removePath 文件或文件夹删除后，移除所有用户对其自身及子路径的收藏
+func (s *FavoriteServiceImpl) removePath(filePath string) error {
+	query := s.db.Where("1 = 1")
+	if filePath != "" {
+		query = s.db.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	}
+	return query.Delete(&model.Favorite{}).Error
+}
+
+// subtreeFavorites 查询路径自身及其子路径上的收藏
+func (s *FavoriteServiceImpl) subtreeFavorites(filePath string) ([]model.Favorite, error) {
+	var favorites []model.Favorite
+	query := s.db.Model(&model.Favorite{})
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	}
+	if err := query.Find(&favorites).Error; err != nil {
+		return nil, fmt.Errorf("查询收藏失败: %w", err)
+	}
+	return favorites, nil
+}
@@ -0,0 +1,128 @@
+package impl
+
+import (
+	"FileNest/internal/model"
+	"context"
+	"reflect"
+	"testing"
+)
+
+// favoritePaths 当前用户收藏的路径，按收藏时间倒序
+func favoritePaths(t *testing.T, env *testEnv, ctx context.Context) []string {
+	t.Helper()
+	favorites, err := env.service.GetFavorites(ctx)
+	if err != nil {
+		t.Fatalf("获取收藏失败: %v", err)
+	}
+	paths := []string{}
+	for _, favorite := range favorites {
+		paths = append(paths, favorite.Path)
+	}
+	return paths
+}
+
+func TestFavoritesPerUser(t *testing.T) {
+	env := newTestEnv(t)
+	_, alice := env.createUser(t, "alice")
+	_, bob := env.createUser(t, "bob")
+	env.writeFile(t, "docs/a.txt", "a")
+	env.writeFile(t, "docs/b.txt", "b")
+
+	for _, p := range []string{"docs/a.txt", "docs", "docs/a.txt"} {
+		if err := env.service.AddFavorite(alice, p); err != nil {
+			t.Fatalf("收藏 %s 失败: %v", p, err)
+		}
+	}
+	if err := env.service.AddFavorite(bob, "docs/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一路径只保留一条，用户之间互不影响
+	if got := favoritePaths(t, env, alice); !reflect.DeepEqual(got, []string{"docs", "docs/a.txt"}) {
+		t.Errorf("alice 的收藏 = %v", got)
+	}
+	if got := favoritePaths(t, env, bob); !reflect.DeepEqual(got, []string{"docs/b.txt"}) {
+		t.Errorf("bob 的收藏 = %v", got)
+	}
+
+	if err := env.service.RemoveFavorite(alice, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if got := favoritePaths(t, env, alice); !reflect.DeepEqual(got, []string{"docs/a.txt"}) {
+		t.Errorf("取消收藏后 alice 的收藏 = %v", got)
+	}
+
+	if err := env.service.AddFavorite(alice, "missing.txt"); err == nil {
+		t.Error("不应能收藏不存在的文件")
+	}
+	if err := env.service.AddFavorite(context.Background(), "docs/a.txt"); err == nil {
+		t.Error("未登录时不应能收藏")
+	}
+}
+
+func TestFavoritesFollowRenameMoveAndDelete(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	env.writeFile(t, "docs/sub/a.txt", "a")
+	env.writeFile(t, "docs2/b.txt", "b")
+	env.writeFile(t, "c.txt", "c")
+	for _, p := range []string{"docs/sub/a.txt", "docs2/b.txt", "c.txt"} {
+		if err := env.service.AddFavorite(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 读取一次，确认之后的变化不会返回缓存中的旧列表
+	favoritePaths(t, env, ctx)
+
+	if err := env.service.RenameFile(ctx, "docs", "notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.MoveFile(ctx, "c.txt", "notes"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"notes/c.txt", "docs2/b.txt", "notes/sub/a.txt"}
+	if got := favoritePaths(t, env, ctx); !reflect.DeepEqual(got, want) {
+		t.Errorf("重命名与移动后的收藏 = %v, 期望 %v", got, want)
+	}
+	favorites, _ := env.service.GetFavorites(ctx)
+	if favorites[0].Name != "c.txt" {
+		t.Errorf("移动后的收藏名称 = %q", favorites[0].Name)
+	}
+
+	if err := env.service.DeleteFile(ctx, "notes", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := favoritePaths(t, env, ctx); !reflect.DeepEqual(got, []string{"docs2/b.txt"}) {
+		t.Errorf("删除后的收藏 = %v", got)
+	}
+	var count int64
+	env.db.Model(&model.Favorite{}).Count(&count)
+	if count != 1 {
+		t.Errorf("数据库中剩余 %d 条收藏", count)
+	}
+}
+
+func TestFavoritesHideUnreadable(t *testing.T) {
+	env := newTestEnv(t)
+	admin, err := env.users.CreateUser("root", "password1", model.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, ctx := env.createUser(t, "alice")
+	env.writeFile(t, "shared/a.txt", "a")
+	env.writeFile(t, "shared/b.txt", "b")
+	for _, p := range []string{"shared/a.txt", "shared/b.txt"} {
+		if err := env.service.AddFavorite(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 收藏后失去读权限的文件不再出现在列表中
+	entries := []model.ACLEntry{{SubjectType: model.SubjectUser, SubjectID: alice.ID, Permissions: "write"}}
+	if err := env.acl.SetACL(admin, "shared/b.txt", entries); err != nil {
+		t.Fatal(err)
+	}
+	if got := favoritePaths(t, env, ctx); !reflect.DeepEqual(got, []string{"shared/a.txt"}) {
+		t.Errorf("收藏 = %v, 期望只包含有读权限的文件", got)
+	}
+}
@@ -0,0 +1,637 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/storage"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errFetchJobNotFound 任务不存在、已过期或不属于当前用户
+var errFetchJobNotFound = errors.New("下载任务不存在或已过期")
+
+// errFetchCanceled 任务在其他实例上被取消
+var errFetchCanceled = errors.New("下载任务已取消")
+
+// errFetchAddressBlocked 下载地址解析到本机或内网
+var errFetchAddressBlocked = errors.New("不允许下载本机或内网地址")
+
+// errFetchInterrupted 运行任务的实例已退出
+var errFetchInterrupted = errors.New("下载任务已中断: 服务重启或异常退出，请重新创建任务")
+
+// fetchSaveInterval 下载过程中保存进度的间隔
+const fetchSaveInterval = time.Second
+
+// FetchServiceImpl 从 URL 下载文件的后台任务
+//
+// 任务状态保存在 Redis 中，任一实例都能查询与取消；下载只在创建任务的实例上进行，
+// 运行期间持有定期续期的租约，租约过期的未结束任务视为已中断。
+type FetchServiceImpl struct {
+	// files 下载完成后通过文件服务校验并保存
+	files *FileServiceImpl
+	// temp 存放已下载内容的临时存储
+	temp storage.Driver
+	// client 下载使用的 HTTP 客户端
+	client *http.Client
+	// slots 限制同时进行的下载任务数
+	slots chan struct{}
+
+	// mu 保护 cancels
+	mu sync.Mutex
+	// cancels 本实例上运行中的下载任务的取消函数
+	cancels map[string]context.CancelFunc
+	// running 本实例上运行中的下载任务
+	running sync.WaitGroup
+}
+
+// NewFetchServiceImpl 创建下载任务服务，下载的内容经 files 保存
+func NewFetchServiceImpl(files *FileServiceImpl) *FetchServiceImpl {
+	return &FetchServiceImpl{
+		files:   files,
+		temp:    files.temp,
+		client:  newFetchClient(),
+		slots:   make(chan struct{}, max(config.Fetch.Workers, 1)),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// fetchPermanentError 重试也无法成功的下载错误
+type fetchPermanentError struct {
+	err error
+}
+
+func (e *fetchPermanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fetchPermanentError) Unwrap() error {
+	return e.err
+}
+
+// CreateFetchJob 创建从 URL 下载文件到 path 的后台任务，fileName 为空时使用 URL 中的文件名
+//
+// 创建时即校验权限、上传策略与同名文件；下载在后台以发起者的身份进行，中断后通过 Range 请求续传，
+// 完成后按合并分块的方式保存，与普通上传经过同样的校验。
+func (s *FetchServiceImpl) CreateFetchJob(ctx context.Context, rawURL, path, fileName string, override bool) (*model.FetchJob, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("下载地址不合法: %s", rawURL)
+	}
+	if fileName == "" {
+		fileName = pathBase(u.Path)
+		if fileName == "" {
+			return nil, fmt.Errorf("无法从下载地址确定文件名，请指定文件名")
+		}
+	}
+	if err := s.files.CheckUpload(ctx, path, fileName, 0, override); err != nil {
+		return nil, err
+	}
+	path, _ = sandbox.Clean(path)
+	fileName, _ = sandbox.CleanName(fileName)
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("创建下载任务失败: %s", err)
+	}
+	job := &model.FetchJob{
+		ID:        id,
+		URL:       u.String(),
+		Path:      path,
+		FileName:  fileName,
+		Override:  override,
+		Status:    consts.FetchStatusPending,
+		Size:      -1,
+		CreatedAt: time.Now(),
+	}
+	principal := auth.FromContext(ctx)
+	if principal != nil {
+		job.UserID = principal.User.ID
+	}
+	// 先取得租约，任务状态可见时不会被当作已中断
+	release := s.keepFetchLease(id)
+	if err := s.saveFetchJob(job); err != nil {
+		release()
+		return nil, fmt.Errorf("创建下载任务失败: %s", err)
+	}
+
+	// 任务不随请求结束，只沿用发起者的身份
+	jobCtx, cancel := context.WithCancel(context.Background())
+	if principal != nil {
+		jobCtx = auth.WithPrincipal(jobCtx, principal)
+	}
+	s.mu.Lock()
+	s.cancels[id] = cancel
+	s.mu.Unlock()
+	created := *job
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.runFetchJob(jobCtx, job, release)
+	}()
+
+	glog.Infof("创建下载任务: %s, 地址: %s, 文件: %s", id, job.URL, filepath.Join(path, fileName))
+	return &created, nil
+}
+
+// GetFetchJob 获取下载任务
+func (s *FetchServiceImpl) GetFetchJob(ctx context.Context, id string) (*model.FetchJob, error) {
+	return s.loadFetchJob(ctx, id)
+}
+
+// CancelFetchJob 取消下载任务，已结束的任务不受影响
+func (s *FetchServiceImpl) CancelFetchJob(ctx context.Context, id string) (*model.FetchJob, error) {
+	job, err := s.loadFetchJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != consts.FetchStatusPending && job.Status != consts.FetchStatusDownloading {
+		return job, nil
+	}
+
+	// 任务在其他实例上运行时，由其在保存进度时发现状态已取消
+	job.Status = consts.FetchStatusCanceled
+	key := cache.FetchJobKey(id)
+	if err := cache.HSet(key, "status", job.Status); err != nil {
+		return nil, fmt.Errorf("取消下载任务失败: %s", err)
+	}
+	s.mu.Lock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	glog.Infof("取消下载任务: %s", id)
+	return job, nil
+}
+
+// runFetchJob 执行下载任务，失败时按 config.Fetch 重试，结束后调用 release 释放租约
+func (s *FetchServiceImpl) runFetchJob(ctx context.Context, job *model.FetchJob, release func()) {
+	defer release()
+	defer func() {
+		s.mu.Lock()
+		if cancel, ok := s.cancels[job.ID]; ok {
+			cancel()
+			delete(s.cancels, job.ID)
+		}
+		s.mu.Unlock()
+	}()
+
+	// 等待空闲的下载槽位
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finishFetchJob(job, "", nil, ctx.Err())
+		return
+	}
+	if err := s.checkFetchCanceled(job); err != nil {
+		s.finishFetchJob(job, "", nil, err)
+		return
+	}
+
+	job.Status = consts.FetchStatusDownloading
+	s.saveFetchJob(job)
+
+	tempDir := fetchDir(job.ID)
+	delay := config.Fetch.RetryDelay
+	var err error
+	for {
+		job.Attempts++
+		err = s.fetchOnce(ctx, job, tempDir)
+		var permanent *fetchPermanentError
+		if err == nil || errors.As(err, &permanent) || ctx.Err() != nil || job.Attempts > config.Fetch.MaxRetries {
+			break
+		}
+
+		glog.Warnf("下载中断，%s 后重试: %s, 任务: %s", delay, err, job.ID)
+		job.Error = err.Error()
+		if err = s.checkFetchCanceled(job); err != nil {
+			break
+		}
+		s.saveFetchJob(job)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		delay *= 2
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// 取消时的错误可能已被包装为下载失败
+			err = ctx.Err()
+		}
+		s.finishFetchJob(job, "", nil, err)
+		return
+	}
+
+	filePath, checksum, err := s.files.mergeChunkDir(ctx, tempDir, job.Path, job.FileName,
+		len(s.files.listChunkDir(tempDir)), job.Override, job.Received, nil, "")
+	s.finishFetchJob(job, filePath, checksum, err)
+}
+
+// fetchOnce 下载一次，已下载过内容时通过 Range 请求续传，本次收到的内容保存为一个分块
+func (s *FetchServiceImpl) fetchOnce(ctx context.Context, job *model.FetchJob, tempDir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.URL, nil)
+	if err != nil {
+		return &fetchPermanentError{err: fmt.Errorf("下载地址不合法: %s", err)}
+	}
+	if job.Received > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", job.Received))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errFetchAddressBlocked) {
+			return &fetchPermanentError{err: errFetchAddressBlocked}
+		}
+		return fmt.Errorf("请求下载地址失败: %s", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && job.Received > 0:
+		start, size, ok := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != job.Received {
+			// 返回的范围与请求的不一致，下次重新下载
+			s.restartFetch(job, tempDir)
+			return fmt.Errorf("服务器返回的范围不一致: %s", resp.Header.Get("Content-Range"))
+		}
+		job.Size = size
+	case resp.StatusCode == http.StatusOK:
+		if job.Received > 0 {
+			// 服务器不支持续传，从头下载
+			s.restartFetch(job, tempDir)
+		}
+		job.Size = resp.ContentLength
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return &fetchPermanentError{err: fmt.Errorf("下载失败: %s", resp.Status)}
+	default:
+		return fmt.Errorf("下载失败: %s", resp.Status)
+	}
+
+	// 大小已知时提前校验，避免下载完才发现超出限制；大小未知时在下载过程中按剩余配额限制
+	filePath := filepath.ToSlash(filepath.Join(job.Path, job.FileName))
+	if config.Fetch.MaxSize > 0 && job.Size > config.Fetch.MaxSize {
+		return &fetchPermanentError{err: fmt.Errorf("文件大小超过下载上限 %d 字节", config.Fetch.MaxSize)}
+	}
+	allowance, err := s.files.checkUpload(ctx, job.Path, job.FileName, max(job.Size, job.Received), job.Override)
+	if err != nil {
+		return &fetchPermanentError{err: err}
+	}
+	s.saveFetchJob(job)
+
+	var reader io.Reader = resp.Body
+	if job.Received == 0 {
+		// 开头的内容用于识别文件类型
+		var head []byte
+		if head, reader, err = peekHead(reader); err != nil {
+			return fmt.Errorf("读取下载内容失败: %s", err)
+		}
+		if err := checkUploadType(filePath, head); err != nil {
+			return &fetchPermanentError{err: err}
+		}
+	}
+	reader = limitUploadSize(filePath, allowance.limitReader(reader, job.Received), job.Received)
+	if config.Fetch.MaxSize > 0 {
+		reader = &quotaReader{
+			reader:    reader,
+			remaining: config.Fetch.MaxSize - job.Received,
+			err:       &fetchPermanentError{err: fmt.Errorf("文件大小超过下载上限 %d 字节", config.Fetch.MaxSize)},
+		}
+	}
+
+	chunkPath := filepath.Join(tempDir, chunkName(len(s.files.listChunkDir(tempDir))))
+	writer, err := s.temp.Create(chunkPath)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %s", err)
+	}
+	written, err := io.Copy(writer, &fetchProgressReader{reader: reader, service: s, job: job})
+	if closeErr := writer.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入临时文件失败: %s", closeErr)
+	}
+	if written == 0 {
+		s.temp.Remove(chunkPath)
+	}
+	s.saveFetchJob(job)
+
+	var policyErr *model.UploadPolicyError
+	var quotaErr *model.QuotaExceededError
+	if errors.As(err, &policyErr) || errors.As(err, &quotaErr) || errors.Is(err, errFetchCanceled) {
+		return &fetchPermanentError{err: err}
+	}
+	if err != nil {
+		return err
+	}
+	if job.Size >= 0 && job.Received != job.Size {
+		return fmt.Errorf("连接提前结束: 已下载 %d 字节，共 %d 字节", job.Received, job.Size)
+	}
+	job.Size = job.Received
+	return nil
+}
+
+// restartFetch 丢弃已下载的内容
+func (s *FetchServiceImpl) restartFetch(job *model.FetchJob, tempDir string) {
+	if err := s.temp.RemoveAll(tempDir); err != nil {
+		glog.Warnf("清理下载任务的临时文件失败: %s, 任务: %s", err, job.ID)
+	}
+	job.Received = 0
+}
+
+// finishFetchJob 记录任务结果并清理临时文件
+func (s *FetchServiceImpl) finishFetchJob(job *model.FetchJob, filePath string, checksum *model.Checksum, err error) {
+	switch {
+	case err == nil:
+		job.Status, job.Error = consts.UploadStatusDone, ""
+		job.FilePath, job.Checksum, job.Progress = filePath, checksum, 100
+		glog.Infof("下载任务完成: %s, 文件: %s, 大小: %d", job.ID, filePath, job.Received)
+	case errors.Is(err, context.Canceled) || errors.Is(err, errFetchCanceled):
+		job.Status, job.Error = consts.FetchStatusCanceled, ""
+		glog.Infof("下载任务已取消: %s", job.ID)
+	default:
+		job.Status, job.Error = consts.UploadStatusError, err.Error()
+		glog.Errorf("下载任务失败: %s, 任务: %s", err, job.ID)
+	}
+	if job.Status != consts.UploadStatusDone {
+		if err := s.temp.RemoveAll(fetchDir(job.ID)); err != nil {
+			glog.Warnf("清理下载任务的临时文件失败: %s, 任务: %s", err, job.ID)
+		}
+	}
+	s.saveFetchJob(job)
+}
+
+// checkFetchCanceled 检查任务是否已在其他实例上被取消
+func (s *FetchServiceImpl) checkFetchCanceled(job *model.FetchJob) error {
+	status, _ := cache.HGet(cache.FetchJobKey(job.ID), "status")
+	if status == consts.FetchStatusCanceled {
+		return errFetchCanceled
+	}
+	return nil
+}
+
+// saveFetchJob 保存任务状态并顺延过期时间
+func (s *FetchServiceImpl) saveFetchJob(job *model.FetchJob) error {
+	job.UpdatedAt = time.Now()
+	if job.Size > 0 {
+		job.Progress = int(job.Received * 100 / job.Size)
+	}
+	if job.Status == consts.UploadStatusDone {
+		job.Progress = 100
+	}
+	key := cache.FetchJobKey(job.ID)
+	values := []interface{}{
+		"user_id", job.UserID,
+		"url", job.URL,
+		"path", job.Path,
+		"file_name", job.FileName,
+		"override", boolFlag(job.Override),
+		"status", job.Status,
+		"size", job.Size,
+		"received", job.Received,
+		"progress", job.Progress,
+		"attempts", job.Attempts,
+		"error", job.Error,
+		"file_path", job.FilePath,
+		"created_at", job.CreatedAt.Unix(),
+		"updated_at", job.UpdatedAt.Unix(),
+	}
+	if job.Checksum != nil {
+		values = append(values, "hash_algorithm", job.Checksum.Algorithm, "hash", job.Checksum.Value)
+	}
+	if err := cache.HSet(key, values...); err != nil {
+		glog.Errorf("保存下载任务失败: %s, 任务: %s", err, job.ID)
+		return err
+	}
+	return cache.Expire(key, time.Duration(cache.FetchJobExpiration)*time.Second)
+}
+
+// loadFetchJob 读取当前用户的下载任务，其他用户的任务视为不存在
+func (s *FetchServiceImpl) loadFetchJob(ctx context.Context, id string) (*model.FetchJob, error) {
+	job, err := s.readFetchJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if principal := auth.FromContext(ctx); principal != nil && principal.User.ID != job.UserID {
+		return nil, errFetchJobNotFound
+	}
+	s.checkFetchOrphaned(job)
+	return job, nil
+}
+
+// readFetchJob 读取下载任务，不校验所属用户
+func (s *FetchServiceImpl) readFetchJob(id string) (*model.FetchJob, error) {
+	if !validSessionID(id) {
+		return nil, errFetchJobNotFound
+	}
+	values, err := cache.HGetAll(cache.FetchJobKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("获取下载任务失败: %s", err)
+	}
+	if len(values) == 0 {
+		return nil, errFetchJobNotFound
+	}
+
+	job := &model.FetchJob{
+		ID:       id,
+		URL:      values["url"],
+		Path:     values["path"],
+		FileName: values["file_name"],
+		Override: values["override"] == "1",
+		Status:   values["status"],
+		Error:    values["error"],
+		FilePath: values["file_path"],
+	}
+	userID, _ := strconv.ParseUint(values["user_id"], 10, 64)
+	job.UserID = uint(userID)
+	job.Size, _ = strconv.ParseInt(values["size"], 10, 64)
+	job.Received, _ = strconv.ParseInt(values["received"], 10, 64)
+	job.Progress, _ = strconv.Atoi(values["progress"])
+	job.Attempts, _ = strconv.Atoi(values["attempts"])
+	if values["hash"] != "" {
+		job.Checksum = &model.Checksum{Algorithm: values["hash_algorithm"], Value: values["hash"]}
+	}
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	updatedAt, _ := strconv.ParseInt(values["updated_at"], 10, 64)
+	job.CreatedAt, job.UpdatedAt = time.Unix(createdAt, 0), time.Unix(updatedAt, 0)
+	return job, nil
+}
+
+// RecoverFetchJobs 将运行实例已退出的下载任务标记为失败，返回处理的任务数，服务启动时调用
+//
+// 实例退出后其任务的租约在 cache.FetchLeaseExpiration 内过期，此时仍在续期的任务不受影响，
+// 之后查询这些任务时同样会被标记为失败。
+func (s *FetchServiceImpl) RecoverFetchJobs(ctx context.Context) (int, error) {
+	keys, err := cache.Keys(cache.FetchJobPattern)
+	if err != nil {
+		return 0, fmt.Errorf("查找下载任务失败: %s", err)
+	}
+	recovered := 0
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return recovered, ctx.Err()
+		}
+		job, err := s.readFetchJob(strings.TrimPrefix(key, cache.FetchJobKey("")))
+		if err == nil && s.checkFetchOrphaned(job) {
+			recovered++
+		}
+	}
+	if recovered > 0 {
+		glog.Infof("已将 %d 个中断的下载任务标记为失败", recovered)
+	}
+	return recovered, nil
+}
+
+// checkFetchOrphaned 未结束的任务没有租约时，说明运行它的实例已退出，将其标记为失败并删除已下载的内容，返回是否标记
+func (s *FetchServiceImpl) checkFetchOrphaned(job *model.FetchJob) bool {
+	if job.Status != consts.FetchStatusPending && job.Status != consts.FetchStatusDownloading {
+		return false
+	}
+	if alive, err := cache.Exists(cache.FetchLeaseKey(job.ID)); err != nil || alive {
+		return false
+	}
+	glog.Warnf("下载任务的运行实例已退出: %s", job.ID)
+	s.finishFetchJob(job, "", nil, errFetchInterrupted)
+	return true
+}
+
+// keepFetchLease 取得任务的租约并在运行期间定期续期，返回停止续期并删除租约的函数
+func (s *FetchServiceImpl) keepFetchLease(id string) func() {
+	key := cache.FetchLeaseKey(id)
+	expiration := time.Duration(cache.FetchLeaseExpiration) * time.Second
+	cache.Set(key, 1, expiration)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(expiration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cache.Set(key, 1, expiration)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		cache.Del(key)
+	}
+}
+
+// fetchProgressReader 统计下载的字节数，定期保存进度并检查任务是否已被取消
+type fetchProgressReader struct {
+	reader    io.Reader
+	service   *FetchServiceImpl
+	job       *model.FetchJob
+	lastSaved time.Time
+}
+
+func (r *fetchProgressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.job.Received += int64(n)
+	if time.Since(r.lastSaved) >= fetchSaveInterval {
+		r.lastSaved = time.Now()
+		if cancelErr := r.service.checkFetchCanceled(r.job); cancelErr != nil {
+			return n, cancelErr
+		}
+		r.service.saveFetchJob(r.job)
+	}
+	return n, err
+}
+
+// fetchDir 下载任务的内容在临时存储中的目录
+func fetchDir(id string) string {
+	return filepath.Join(consts.FetchDir, id)
+}
+
+// pathBase URL 路径中的文件名，没有时返回空字符串
+func pathBase(urlPath string) string {
+	name := path.Base(urlPath)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// parseContentRangeStart 解析响应的 Content-Range，返回起始位置与文件总大小（未知时为 -1）
+func parseContentRangeStart(value string) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rangeSpec, sizeSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	startSpec, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startSpec, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size := int64(-1)
+	if sizeSpec != "*" {
+		if size, err = strconv.ParseInt(sizeSpec, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// newFetchClient 创建下载使用的 HTTP 客户端
+//
+// 不使用代理，且在建立连接时按解析后的地址校验，重定向与 DNS 重绑定也无法访问内网。
+// 关闭自动解压，保证续传时的偏移与服务器上的内容一致。
+func newFetchClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   fetchDialControl,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ResponseHeaderTimeout: config.Fetch.ResponseTimeout,
+			TLSHandshakeTimeout:   10 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			DisableCompression:    true,
+		},
+	}
+}
+
+// fetchDialControl 禁止连接本机与内网地址，config.Fetch.AllowPrivateNetworks 为 true 时不限制
+func fetchDialControl(network, address string, _ syscall.RawConn) error {
+	if config.Fetch.AllowPrivateNetworks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return errFetchAddressBlocked
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package impl
+
+import (
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// allowFetchFromTestServer 允许下载本机的测试服务器，并缩短重试间隔，测试结束后恢复
+func allowFetchFromTestServer(t *testing.T) {
+	t.Helper()
+	saved := *config.Fetch
+	config.Fetch.AllowPrivateNetworks = true
+	config.Fetch.RetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { *config.Fetch = saved })
+}
+
+// waitFetchJob 等待任务结束并返回其最终状态
+func waitFetchJob(t *testing.T, env *testEnv, ctx context.Context, id string) *model.FetchJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := env.fetch.GetFetchJob(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != consts.FetchStatusPending && job.Status != consts.FetchStatusDownloading {
+			// 等待后台任务完全退出，避免影响恢复配置与之后的测试
+			env.fetch.running.Wait()
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务未在限定时间内结束: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// streamUnknownSize 分段写出 size 字节且不设置 Content-Length，客户端无法预先知道大小
+func streamUnknownSize(w http.ResponseWriter, r *http.Request, size int) {
+	part := []byte(strings.Repeat("x", 4096))
+	for written := 0; written < size && r.Context().Err() == nil; written += len(part) {
+		w.Write(part[:min(len(part), size-written)])
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestFetchJob(t *testing.T) {
+	env := newTestEnv(t)
+	allowFetchFromTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello world"))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	job, err := env.fetch.CreateFetchJob(ctx, server.URL+"/files/hello.txt", "docs", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFetchJob(t, env, ctx, job.ID)
+	if job.Status != consts.UploadStatusDone || job.FilePath != "docs/hello.txt" || job.Checksum == nil {
+		t.Fatalf("任务结果: %+v", job)
+	}
+	if got := env.readFile(t, "docs/hello.txt"); got != "hello world" {
+		t.Errorf("保存的内容为 %q", got)
+	}
+}
+
+func TestFetchJobResumesWithRange(t *testing.T) {
+	env := newTestEnv(t)
+	allowFetchFromTestServer(t)
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+		if first {
+			// 第一次只发送一半内容就断开连接
+			w.Header().Set("Content-Length", "11")
+			w.Write([]byte("hello "))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello world"))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	job, err := env.fetch.CreateFetchJob(ctx, server.URL+"/hello.txt", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFetchJob(t, env, ctx, job.ID)
+	if job.Status != consts.UploadStatusDone || job.Attempts != 2 {
+		t.Fatalf("任务结果: %+v", job)
+	}
+	if got := env.readFile(t, "hello.txt"); got != "hello world" {
+		t.Errorf("保存的内容为 %q", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ranges) != 2 || ranges[1] != "bytes=6-" {
+		t.Errorf("请求的范围为 %q", ranges)
+	}
+}
+
+func TestFetchJobUnknownSizeCappedByQuota(t *testing.T) {
+	env := newTestEnv(t)
+	allowFetchFromTestServer(t)
+	setQuota(t, config.QuotaConfig{User: config.QuotaLimit{MaxBytes: 10}})
+	_, ctx := env.createUser(t, "alice")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamUnknownSize(w, r, 4<<20)
+	}))
+	defer server.Close()
+
+	job, err := env.fetch.CreateFetchJob(ctx, server.URL+"/big.txt", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFetchJob(t, env, ctx, job.ID)
+	if job.Status != consts.UploadStatusError || !strings.Contains(job.Error, "配额") {
+		t.Fatalf("任务结果: %+v", job)
+	}
+	if job.Attempts != 1 {
+		t.Errorf("超出配额不应重试，实际尝试了 %d 次", job.Attempts)
+	}
+	if job.Received >= 4<<20 {
+		t.Errorf("超出配额后应停止下载，实际下载了 %d 字节", job.Received)
+	}
+	if env.exists("big.txt") {
+		t.Error("超出配额的文件不应保存")
+	}
+	if _, err := env.temp.Stat(fetchDir(job.ID)); err == nil {
+		t.Error("失败后临时内容未删除")
+	}
+}
+
+func TestFetchJobUnknownSizeCappedByMaxSize(t *testing.T) {
+	env := newTestEnv(t)
+	allowFetchFromTestServer(t)
+	config.Fetch.MaxSize = 16
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamUnknownSize(w, r, 4<<20)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	job, err := env.fetch.CreateFetchJob(ctx, server.URL+"/big.txt", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFetchJob(t, env, ctx, job.ID)
+	if job.Status != consts.UploadStatusError || job.Attempts != 1 || !strings.Contains(job.Error, "下载上限") {
+		t.Fatalf("任务结果: %+v", job)
+	}
+	if job.Received >= 4<<20 {
+		t.Errorf("超出下载上限后应停止下载，实际下载了 %d 字节", job.Received)
+	}
+	if env.exists("big.txt") {
+		t.Error("超出下载上限的文件不应保存")
+	}
+}
+
+func TestCancelFetchJob(t *testing.T) {
+	env := newTestEnv(t)
+	allowFetchFromTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamUnknownSize(w, r, 16)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	_, ctx := env.createUser(t, "alice")
+	_, otherCtx := env.createUser(t, "bob")
+
+	job, err := env.fetch.CreateFetchJob(ctx, server.URL+"/slow.txt", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.fetch.CancelFetchJob(otherCtx, job.ID); err == nil {
+		t.Error("其他用户不应能取消任务")
+	}
+	if _, err := env.fetch.CancelFetchJob(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job = waitFetchJob(t, env, ctx, job.ID)
+	if job.Status != consts.FetchStatusCanceled {
+		t.Fatalf("任务结果: %+v", job)
+	}
+	if env.exists("slow.txt") {
+		t.Error("取消的任务不应保存文件")
+	}
+}
+
+func TestRecoverFetchJobs(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// 运行实例已退出的任务：没有租约，临时存储中留有已下载的内容
+	orphaned := &model.FetchJob{ID: "0123456789abcdef0123456789abcdef", URL: "http://example.com/a.txt", FileName: "a.txt", Status: consts.FetchStatusDownloading, Size: -1, Received: 4}
+	if err := env.fetch.saveFetchJob(orphaned); err != nil {
+		t.Fatal(err)
+	}
+	env.writeTemp(t, fetchDir(orphaned.ID)+"/"+chunkName(0), "part")
+
+	// 仍在运行的任务：租约有效
+	running := &model.FetchJob{ID: "fedcba9876543210fedcba9876543210", URL: "http://example.com/b.txt", FileName: "b.txt", Status: consts.FetchStatusPending, Size: -1}
+	release := env.fetch.keepFetchLease(running.ID)
+	defer release()
+	if err := env.fetch.saveFetchJob(running); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := env.fetch.RecoverFetchJobs(ctx)
+	if err != nil || recovered != 1 {
+		t.Fatalf("处理了 %d 个任务: %v", recovered, err)
+	}
+	job, err := env.fetch.GetFetchJob(ctx, orphaned.ID)
+	if err != nil || job.Status != consts.UploadStatusError || job.Error != errFetchInterrupted.Error() {
+		t.Fatalf("中断的任务: %+v, %v", job, err)
+	}
+	if _, err := env.temp.Stat(fetchDir(orphaned.ID)); err == nil {
+		t.Error("中断任务的临时内容未删除")
+	}
+	if job, _ := env.fetch.GetFetchJob(ctx, running.ID); job.Status != consts.FetchStatusPending {
+		t.Errorf("租约有效的任务不应受影响: %+v", job)
+	}
+
+	// 租约过期后查询时同样标记为失败
+	cache.Del(cache.FetchLeaseKey(running.ID))
+	if job, _ := env.fetch.GetFetchJob(ctx, running.ID); job.Status != consts.UploadStatusError {
+		t.Errorf("租约过期的任务: %+v", job)
+	}
+}
@@ -0,0 +1,81 @@
+package impl
+
+import (
+	"FileNest/internal/model"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FileHashServiceImpl 维护文件内容的摘要索引
+type FileHashServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewFileHashServiceImpl 创建文件摘要索引服务
+func NewFileHashServiceImpl(db *gorm.DB) *FileHashServiceImpl {
+	return &FileHashServiceImpl{db: db}
+}
+
+// record 记录文件的摘要，同一路径只保留最新一条
+func (s *FileHashServiceImpl) record(hashes []model.FileHash) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hash", "size", "mod_time", "updated_at"}),
+	}).CreateInBatches(hashes, 100).Error
+}
+
+// find 查询内容相同的文件
+func (s *FileHashServiceImpl) find(hash string, size int64) ([]model.FileHash, error) {
+	var hashes []model.FileHash
+	if err := s.db.Where("hash = ? AND size = ?", hash, size).Order("id").Find(&hashes).Error; err != nil {
+		return nil, fmt.Errorf("查询文件摘要失败: %w", err)
+	}
+	return hashes, nil
+}
+
+// movePath 文件或文件夹移动、重命名后，同步其自身及子路径的摘要记录
+func (s *FileHashServiceImpl) movePath(oldPath, newPath string) error {
+	hashes, err := s.subtreeHashes(oldPath)
+	if err != nil || len(hashes) == 0 {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, h := range hashes {
+			moved := newPath + strings.TrimPrefix(h.Path, oldPath)
+			if err := tx.Model(&model.FileHash{}).Where("id = ?", h.ID).Update("path", moved).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removePath 文件或文件夹删除后，移除其自身及子路径的摘要记录
+func (s *FileHashServiceImpl) removePath(filePath string) error {
+	query := s.db.Unscoped()
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	} else {
+		query = query.Where("1 = 1")
+	}
+	return query.Delete(&model.FileHash{}).Error
+}
+
+// subtreeHashes 查询路径自身及其子路径的摘要记录
+func (s *FileHashServiceImpl) subtreeHashes(filePath string) ([]model.FileHash, error) {
+	var hashes []model.FileHash
+	query := s.db.Model(&model.FileHash{})
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	}
+	if err := query.Find(&hashes).Error; err != nil {
+		return nil, fmt.Errorf("查询文件摘要失败: %w", err)
+	}
+	return hashes, nil
+}
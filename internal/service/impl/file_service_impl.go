@@ -2,15 +2,24 @@ package impl
 
 import (
 	"FileNest/common/glog"
+	"FileNest/internal/auth"
 	"FileNest/internal/cache"
 	"FileNest/internal/consts"
 	"FileNest/internal/model"
+	"FileNest/internal/storage"
+	"FileNest/internal/utils/sandbox"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"os"
+	"io/fs"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,56 +31,108 @@ import (
   @date: 2024/9/28
 **/
 
-type FileServiceImpl struct{}
+type FileServiceImpl struct {
+	// storage 文件存储
+	storage storage.Driver
+	// temp 分块上传的临时存储
+	temp storage.Driver
+	// acl 路径权限，为 nil 时不校验 ACL
+	acl *ACLServiceImpl
+	// quota 用户存储用量，为 nil 时只校验目录配额
+	quota *QuotaServiceImpl
+	// favorites 用户收藏，为 nil 时不支持收藏
+	favorites *FavoriteServiceImpl
+	// hashes 文件摘要索引，为 nil 时不支持秒传
+	hashes *FileHashServiceImpl
+}
 
-func init() {
-	// 确保上传目录存在
-	if err := os.MkdirAll(consts.UploadDir, os.ModePerm); err != nil {
-		glog.Errorf("创建上传目录失败: %s", err)
-		panic(err)
+// NewFileServiceImpl 创建文件服务
+func NewFileServiceImpl(store storage.Driver, temp storage.Driver, acl *ACLServiceImpl, quota *QuotaServiceImpl, favorites *FavoriteServiceImpl, hashes *FileHashServiceImpl) *FileServiceImpl {
+	return &FileServiceImpl{
+		storage:   store,
+		temp:      temp,
+		acl:       acl,
+		quota:     quota,
+		favorites: favorites,
+		hashes:    hashes,
 	}
 }
 
-func (h *FileServiceImpl) DownloadFile(filePath string) (string, error) {
+func (h *FileServiceImpl) DownloadFile(ctx context.Context, filePath string) (storage.File, fs.FileInfo, error) {
+	filePath, err := sandbox.Clean(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := h.authorize(ctx, model.PermRead, filePath); err != nil {
+		return nil, nil, err
+	}
+
 	// 检查文件是否存在
-	filePath = filepath.Join(consts.UploadDir, filePath)
-	absPath, err := filepath.Abs(filePath)
+	info, err := h.storage.Stat(filePath)
 	if err != nil {
-		return "", fmt.Errorf("stat error: %s", err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("file does not exist")
+		}
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, fmt.Errorf("path is a directory")
+	}
+	if isMergeTemp(info.Name()) {
+		return nil, nil, fmt.Errorf("file does not exist")
 	}
 
-	if _, err = os.Stat(absPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("file does not exist")
+	file, err := h.storage.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open error: %s", err)
 	}
-	return absPath, nil
+	return file, info, nil
 }
 
-func (h *FileServiceImpl) CreateDir(path string) error {
-	path = filepath.Join(consts.UploadDir, path)
-	fileInfo, err := os.Stat(path)
-	if err != nil && !os.IsNotExist(err) {
+func (h *FileServiceImpl) CreateDir(ctx context.Context, path string) error {
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermWrite, path); err != nil {
+		return err
+	}
+	if err := checkReservedName(path); err != nil {
+		return err
+	}
+
+	fileInfo, err := h.storage.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("stat error: %s", err)
 	}
 	if err == nil && !fileInfo.IsDir() {
 		return fmt.Errorf("path exists but is not a directory")
 	}
 	if err != nil {
-		return os.MkdirAll(path, os.ModePerm)
+		return h.storage.Mkdir(path)
 	}
 	return nil
 }
 
 // GetFileList 获取文件列表（带缓存）
-func (s *FileServiceImpl) GetFileList(path string) ([]model.FileInfo, error) {
+func (s *FileServiceImpl) GetFileList(ctx context.Context, path string) ([]model.FileInfo, error) {
 	glog.Infof("开始获取文件列表，路径: %s", path)
 
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, model.PermRead, path); err != nil {
+		return nil, err
+	}
+
 	// 尝试从缓存获取
 	cacheKey := cache.FileListKey(path)
 	if cached, err := cache.Get(cacheKey); err == nil {
 		var files []model.FileInfo
 		if err := json.Unmarshal([]byte(cached), &files); err == nil {
 			glog.Infof("从缓存获取文件列表成功，路径: %s", path)
-			return files, nil
+			return s.filterAccessible(ctx, "", files), nil
 		}
 	}
 
@@ -86,37 +147,33 @@ func (s *FileServiceImpl) GetFileList(path string) ([]model.FileInfo, error) {
 		cache.Set(cacheKey, cacheData, time.Duration(cache.FileListExpiration)*time.Second)
 	}
 
-	return files, nil
+	return s.filterAccessible(ctx, "", files), nil
 }
 
 // getFileListFromFS 从文件系统获取文件列表
 func (s *FileServiceImpl) getFileListFromFS(path string) ([]model.FileInfo, error) {
-	absPath := filepath.Join(consts.UploadDir, path)
-	glog.Infof("从文件系统获取文件列表，完整路径: %s", absPath)
+	glog.Infof("从文件系统获取文件列表，路径: %s", path)
 
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+	if _, err := s.storage.Stat(path); errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("路径不存在: %s", path)
 	}
 
-	entries, err := os.ReadDir(absPath)
+	entries, err := s.storage.List(path)
 	if err != nil {
 		return nil, fmt.Errorf("读取目录失败: %s", err)
 	}
 
 	var files []model.FileInfo
-	for _, entry := range entries {
-		info, err := entry.Info()
-		if err != nil {
-			glog.Errorf("获取文件信息失败: %s", err)
+	for _, info := range entries {
+		if !info.IsDir() && isMergeTemp(info.Name()) {
 			continue
 		}
-
 		fileInfo := model.FileInfo{
-			FileName: entry.Name(),
-			FilePath: filepath.Join(path, entry.Name()),
+			FileName: info.Name(),
+			FilePath: filepath.ToSlash(filepath.Join(path, info.Name())),
 			FileSize: info.Size(),
-			FileType: filepath.Ext(entry.Name()),
-			IsDir:    entry.IsDir(),
+			FileType: filepath.Ext(info.Name()),
+			IsDir:    info.IsDir(),
 			ModTime:  info.ModTime().Format(time.DateTime),
 		}
 		files = append(files, fileInfo)
@@ -125,17 +182,70 @@ func (s *FileServiceImpl) getFileListFromFS(path string) ([]model.FileInfo, erro
 	return files, nil
 }
 
+// StatFile 获取文件或目录信息
+func (s *FileServiceImpl) StatFile(ctx context.Context, path string) (fs.FileInfo, error) {
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, "", path); err != nil {
+		return nil, err
+	}
+	if isMergeTemp(filepath.Base(path)) {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+	return s.storage.Stat(path)
+}
+
+// ReadDir 读取目录下的直接子项
+func (s *FileServiceImpl) ReadDir(ctx context.Context, path string) ([]fs.FileInfo, error) {
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, model.PermRead, path); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.storage.List(path)
+	if err != nil {
+		return nil, err
+	}
+	check, err := s.accessChecker(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var result []fs.FileInfo
+	for _, entry := range entries {
+		if !entry.IsDir() && isMergeTemp(entry.Name()) {
+			continue
+		}
+		if check(filepath.ToSlash(filepath.Join(path, entry.Name()))) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
 // GetFileStats 获取文件统计信息（带缓存）
-func (s *FileServiceImpl) GetFileStats(path string) (*model.FileStats, error) {
+func (s *FileServiceImpl) GetFileStats(ctx context.Context, path string) (*model.FileStats, error) {
 	glog.Infof("开始获取文件统计信息，路径: %s", path)
 
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, model.PermRead, path); err != nil {
+		return nil, err
+	}
+
 	// 尝试从缓存获取
 	cacheKey := cache.FileStatsKey(path)
 	if cached, err := cache.Get(cacheKey); err == nil {
 		var stats model.FileStats
 		if err := json.Unmarshal([]byte(cached), &stats); err == nil {
 			glog.Infof("从缓存获取文件统计信息成功，路径: %s", path)
-			return &stats, nil
+			return s.withQuota(ctx, path, &stats)
 		}
 	}
 
@@ -150,27 +260,37 @@ func (s *FileServiceImpl) GetFileStats(path string) (*model.FileStats, error) {
 		cache.Set(cacheKey, cacheData, time.Duration(cache.FileStatsExpiration)*time.Second)
 	}
 
+	return s.withQuota(ctx, path, stats)
+}
+
+// withQuota 附带当前用户与所在目录的配额用量，配额用量不缓存
+func (s *FileServiceImpl) withQuota(ctx context.Context, path string, stats *model.FileStats) (*model.FileStats, error) {
+	quota, err := s.quotaInfo(ctx, path, stats)
+	if err != nil {
+		return nil, err
+	}
+	stats.Quota = quota
 	return stats, nil
 }
 
 // getFileStatsFromFS 从文件系统获取文件统计信息
 func (s *FileServiceImpl) getFileStatsFromFS(path string) (*model.FileStats, error) {
-	absPath := filepath.Join(consts.UploadDir, path)
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+	if _, err := s.storage.Stat(path); errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("路径不存在: %s", path)
 	}
 
+	root := path
 	stats := &model.FileStats{}
-	err := filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
+	err := s.storage.Walk(root, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if info.IsDir() {
-			if path != absPath {
+			if path != root {
 				stats.TotalFolders++
 			}
-		} else {
+		} else if !isMergeTemp(info.Name()) {
 			stats.TotalFiles++
 			stats.TotalSize += info.Size()
 		}
@@ -185,10 +305,13 @@ func (s *FileServiceImpl) getFileStatsFromFS(path string) (*model.FileStats, err
 }
 
 // SearchFiles 搜索文件（带缓存）
-func (s *FileServiceImpl) SearchFiles(keyword string) ([]model.FileInfo, error) {
+func (s *FileServiceImpl) SearchFiles(ctx context.Context, keyword string) ([]model.FileInfo, error) {
 	glog.Infof("开始搜索文件，关键词: %s", keyword)
 
-	ctx := context.Background()
+	if err := s.authorize(ctx, model.PermRead); err != nil {
+		return nil, err
+	}
+
 	redisClient := cache.GetRedisClient()
 
 	// 记录搜索历史
@@ -206,7 +329,7 @@ func (s *FileServiceImpl) SearchFiles(keyword string) ([]model.FileInfo, error)
 		var files []model.FileInfo
 		if err := json.Unmarshal([]byte(cached), &files); err == nil {
 			glog.Infof("从缓存获取搜索结果成功，关键词: %s", keyword)
-			return files, nil
+			return s.filterAccessible(ctx, model.PermRead, files), nil
 		}
 	}
 
@@ -221,27 +344,42 @@ func (s *FileServiceImpl) SearchFiles(keyword string) ([]model.FileInfo, error)
 		cache.Set(cacheKey, cacheData, time.Duration(cache.SearchExpiration)*time.Second)
 	}
 
-	return files, nil
+	return s.filterAccessible(ctx, model.PermRead, files), nil
+}
+
+// filterAccessible 过滤掉当前身份没有 permission 权限的文件
+func (s *FileServiceImpl) filterAccessible(ctx context.Context, permission string, files []model.FileInfo) []model.FileInfo {
+	check, err := s.accessChecker(ctx, permission)
+	if err != nil {
+		glog.Errorf("加载访问权限失败: %s", err)
+		return nil
+	}
+
+	var result []model.FileInfo
+	for _, file := range files {
+		if check(file.FilePath) {
+			result = append(result, file)
+		}
+	}
+	return result
 }
 
 // searchFilesInFS 在文件系统中搜索文件
 func (s *FileServiceImpl) searchFilesInFS(keyword string) ([]model.FileInfo, error) {
 	var files []model.FileInfo
-	err := filepath.Walk(consts.UploadDir, func(path string, info os.FileInfo, err error) error {
+	err := s.storage.Walk("", func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if path == "" || (!info.IsDir() && isMergeTemp(info.Name())) {
+			return nil
+		}
 
 		// 检查文件名是否匹配关键词
 		if strings.Contains(strings.ToLower(info.Name()), strings.ToLower(keyword)) {
-			relPath, err := filepath.Rel(consts.UploadDir, path)
-			if err != nil {
-				return err
-			}
-
 			fileInfo := model.FileInfo{
 				FileName: info.Name(),
-				FilePath: relPath,
+				FilePath: path,
 				FileSize: info.Size(),
 				FileType: filepath.Ext(info.Name()),
 				IsDir:    info.IsDir(),
@@ -260,14 +398,53 @@ func (s *FileServiceImpl) searchFilesInFS(keyword string) ([]model.FileInfo, err
 }
 
 // DeleteFile 删除文件（清除相关缓存）
-func (s *FileServiceImpl) DeleteFile(path string, force bool) error {
+func (s *FileServiceImpl) DeleteFile(ctx context.Context, path string, force bool) error {
 	glog.Infof("开始删除文件，路径: %s, 强制删除: %v", path, force)
 
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("不允许删除根目录")
+	}
+	if err := s.authorize(ctx, model.PermDelete, path); err != nil {
+		return err
+	}
+	// 强制删除会删除整个目录，其中每一项都需要删除权限
+	if force {
+		if err := s.authorizeTree(ctx, model.PermDelete, path); err != nil {
+			return err
+		}
+	}
+
 	// 删除文件
-	err := s.deleteFileFromFS(path, force)
+	defer s.trackFolderUsage(path)()
+	err = s.deleteFileFromFS(path, force)
 	if err != nil {
 		return err
 	}
+	if s.acl != nil {
+		if err := s.acl.removePath(path); err != nil {
+			glog.Errorf("清理 ACL 失败: %s, 路径: %s", err, path)
+		}
+	}
+	if s.quota != nil {
+		if err := s.quota.removePath(path); err != nil {
+			glog.Errorf("释放存储用量失败: %s, 路径: %s", err, path)
+		}
+	}
+	if s.favorites != nil {
+		if err := s.favorites.removePath(path); err != nil {
+			glog.Errorf("移除收藏失败: %s, 路径: %s", err, path)
+		}
+		cache.DelByPattern(cache.FavoriteListPattern)
+	}
+	if s.hashes != nil {
+		if err := s.hashes.removePath(path); err != nil {
+			glog.Errorf("移除文件摘要失败: %s, 路径: %s", err, path)
+		}
+	}
 
 	// 清除相关缓存
 	s.clearFileRelatedCache(path)
@@ -276,19 +453,16 @@ func (s *FileServiceImpl) DeleteFile(path string, force bool) error {
 
 // deleteFileFromFS 从文件系统删除文件
 func (s *FileServiceImpl) deleteFileFromFS(path string, force bool) error {
-	path = filepath.Clean(path)
-	fullPath := filepath.Join(consts.UploadDir, path)
-
-	info, err := os.Stat(fullPath)
+	info, err := s.storage.Stat(path)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
 		return fmt.Errorf("检查路径状态失败: %s", err)
 	}
 
 	if info.IsDir() {
-		entries, err := os.ReadDir(fullPath)
+		entries, err := s.storage.List(path)
 		if err != nil {
 			return fmt.Errorf("读取目录失败: %s", err)
 		}
@@ -296,12 +470,12 @@ func (s *FileServiceImpl) deleteFileFromFS(path string, force bool) error {
 			return fmt.Errorf("目录不为空，如需删除请勾选\"强制删除\"选项")
 		}
 		if force {
-			err = os.RemoveAll(fullPath)
+			err = s.storage.RemoveAll(path)
 		} else {
-			err = os.Remove(fullPath)
+			err = s.storage.Remove(path)
 		}
 	} else {
-		err = os.Remove(fullPath)
+		err = s.storage.Remove(path)
 	}
 
 	if err != nil {
@@ -328,55 +502,109 @@ func (s *FileServiceImpl) clearFileRelatedCache(path string) {
 	}
 }
 
-// UploadFile 上传文件（更新缓存）
-func (s *FileServiceImpl) UploadFile(path, fileName string, totalChunks int, override bool) error {
+// prepareUpload 校验上传前置条件与配额，size 为文件大小（未知时为 0），返回配额允许写入的大小
+func (s *FileServiceImpl) prepareUpload(ctx context.Context, path, fileName string, override bool, size int64) (*quotaAllowance, error) {
 	glog.Infof("开始上传文件，路径: %s, 文件名: %s", path, fileName)
 
-	// 设置上传进度
-	progressKey := cache.UploadProgressKey(path, fileName)
-	cache.HSet(progressKey,
-		"status", "uploading",
-		"progress", "0",
-		"total_chunks", totalChunks,
-	)
-	cache.Expire(progressKey, time.Hour)
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if fileName, err = sandbox.CleanName(fileName); err != nil {
+		return nil, err
+	}
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	if err := s.authorize(ctx, model.PermWrite, filePath); err != nil {
+		return nil, err
+	}
+	if err := checkUploadPolicy(filePath, size, 0); err != nil {
+		return nil, err
+	}
+	allowance, err := s.checkQuota(ctx, filePath, s.uploadQuotaRequest(filePath, size))
+	if err != nil {
+		return nil, err
+	}
 
 	// 执行上传
-	err := s.uploadFileToFS(path, fileName, override)
-	if err != nil {
-		// 更新失败状态
-		cache.HSet(progressKey, "status", "error", "error", err.Error())
-		return err
+	if err := s.uploadFileToFS(path, fileName, override); err != nil {
+		return nil, err
 	}
 
 	// 清除相关缓存
 	s.clearFileRelatedCache(filepath.Join(path, fileName))
-	return nil
+	return allowance, nil
 }
 
-// uploadFileToFS 上传文件到文件系统
-func (s *FileServiceImpl) uploadFileToFS(path, fileName string, override bool) error {
-	path = filepath.Clean(path)
-	if path == "." {
-		path = ""
+// CheckUpload 校验上传前置条件，供需要先接收完整内容再保存的上传方式提前拒绝
+func (s *FileServiceImpl) CheckUpload(ctx context.Context, path, fileName string, size int64, override bool) error {
+	_, err := s.checkUpload(ctx, path, fileName, size, override)
+	return err
+}
+
+// CheckUploadLimit 校验上传前置条件，返回配额与上传策略允许的最大文件大小
+func (s *FileServiceImpl) CheckUploadLimit(ctx context.Context, path, fileName string, override bool) (*model.UploadLimit, error) {
+	allowance, err := s.checkUpload(ctx, path, fileName, 0, override)
+	if err != nil {
+		return nil, err
 	}
+	limit := &model.UploadLimit{MaxBytes: allowance.remaining, Err: allowance.err}
 
-	outFilePath := filepath.Join(consts.UploadDir, path, fileName)
-	targetDir := filepath.Dir(outFilePath)
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	if filePath, err = sandbox.Clean(filePath); err != nil {
+		return nil, err
+	}
+	if policy := uploadPolicy(filePath); policy.MaxFileSize > 0 && (limit.MaxBytes < 0 || policy.MaxFileSize < limit.MaxBytes) {
+		limit.MaxBytes = policy.MaxFileSize
+		limit.Err = &model.UploadPolicyError{Reason: fmt.Sprintf("文件大小超过上限 %d 字节", policy.MaxFileSize)}
+	}
+	return limit, nil
+}
 
-	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-		return fmt.Errorf("创建目标目录失败: %s", err)
+// checkUpload 校验上传前置条件，返回配额允许写入的大小，用于限制大小未知的内容
+func (s *FileServiceImpl) checkUpload(ctx context.Context, path, fileName string, size int64, override bool) (*quotaAllowance, error) {
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return nil, err
+	}
+	if fileName, err = sandbox.CleanName(fileName); err != nil {
+		return nil, err
+	}
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	if err := s.authorize(ctx, model.PermWrite, filePath); err != nil {
+		return nil, err
 	}
+	if err := checkUploadPolicy(filePath, size, 0); err != nil {
+		return nil, err
+	}
+
+	if info, err := s.storage.Stat(filePath); err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("同名文件夹已存在: %s", fileName)
+		}
+		if !override {
+			return nil, fmt.Errorf("文件已存在: %s", fileName)
+		}
+	}
+	return s.checkQuota(ctx, filePath, s.uploadQuotaRequest(filePath, size))
+}
 
-	if err := s.checkDirectoryWritePermission(targetDir); err != nil {
-		return fmt.Errorf("目标目录无写入权限: %s", err)
+// uploadFileToFS 上传文件到文件系统
+func (s *FileServiceImpl) uploadFileToFS(path, fileName string, override bool) error {
+	outFilePath := filepath.Join(path, fileName)
+
+	if err := s.storage.Mkdir(path); err != nil {
+		return fmt.Errorf("创建目标目录失败: %s", err)
 	}
 
-	if _, err := os.Stat(outFilePath); err == nil {
+	if info, err := s.storage.Stat(outFilePath); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("同名文件夹已存在: %s", fileName)
+		}
 		if !override {
 			return fmt.Errorf("文件已存在: %s", fileName)
 		}
-		if err := os.Remove(outFilePath); err != nil {
+		defer s.trackFolderUsage(outFilePath)()
+		if err := s.storage.Remove(outFilePath); err != nil {
 			return fmt.Errorf("删除已存在文件失败: %s", err)
 		}
 	}
@@ -384,26 +612,401 @@ func (s *FileServiceImpl) uploadFileToFS(path, fileName string, override bool) e
 	return nil
 }
 
-// checkDirectoryWritePermission 检查目录是否有写入权限
-func (s *FileServiceImpl) checkDirectoryWritePermission(dir string) error {
-	tempFile := filepath.Join(dir, ".write_test")
-	f, err := os.Create(tempFile)
+// SaveFile 保存上传的文件内容
+func (s *FileServiceImpl) SaveFile(ctx context.Context, path, fileName string, reader io.Reader, override bool) (string, error) {
+	// 检查文件上传前置条件
+	allowance, err := s.prepareUpload(ctx, path, fileName, override, readerSize(reader))
+	if err != nil {
+		return "", err
+	}
+
+	path, _ = sandbox.Clean(path)
+	fileName, _ = sandbox.CleanName(fileName)
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	head, reader, err := peekHead(reader)
+	if err == nil {
+		err = checkUploadType(filePath, head)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.New()
+	reader = limitUploadSize(filePath, allowance.limitReader(reader, 0), 0)
+	defer s.trackFolderUsage(filePath)()
+	if err := s.writeFile(filePath, io.TeeReader(reader, digest)); err != nil {
+		var quotaErr *model.QuotaExceededError
+		var policyErr *model.UploadPolicyError
+		if errors.As(err, &quotaErr) || errors.As(err, &policyErr) {
+			// 不保留超出配额或上传策略限制的部分内容
+			if err := s.storage.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				glog.Warnf("删除超出配额的文件失败: %s, 路径: %s", err, filePath)
+			}
+			s.clearFileRelatedCache(filePath)
+			return "", err
+		}
+		return "", fmt.Errorf("保存文件失败: %s", err)
+	}
+	contentHash := hex.EncodeToString(digest.Sum(nil))
+	s.recordHash(filePath, contentHash)
+	s.recordUsage(ctx, filePath)
+
+	// 清除相关缓存
+	s.clearFileRelatedCache(filePath)
+
+	glog.Infof("文件上传成功: %s", filePath)
+	return filePath, nil
+}
+
+// listChunkDir 列出临时存储目录 dir 中的分块
+func (s *FileServiceImpl) listChunkDir(dir string) []model.ChunkInfo {
+	entries, err := s.temp.List(dir)
+	if err != nil {
+		return []model.ChunkInfo{}
+	}
+	chunks := make([]model.ChunkInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		index, ok := parseChunkName(entry.Name())
+		if !ok {
+			continue
+		}
+		chunks = append(chunks, model.ChunkInfo{Index: index, Size: entry.Size()})
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Index < chunks[j].Index
+	})
+	return chunks
+}
+
+// mergeChunkDir 将临时存储 tempDir 中的分块按顺序合并为 path/fileName，size 为分块总大小，
+// 合并进度发布到 eventKey 对应上传的订阅者
+//
+// 分块先写入目标目录中的隐藏临时文件，落盘并通过摘要校验后再重命名覆盖目标文件，
+// 任一步骤失败都不会影响已有文件。分块只在合并成功后删除。
+func (s *FileServiceImpl) mergeChunkDir(ctx context.Context, tempDir, path, fileName string, totalChunks int, override bool, size int64, checksum *model.Checksum, eventKey string) (string, *model.Checksum, error) {
+	checksum, err := normalizeChecksum(checksum)
+	if err != nil {
+		return "", nil, err
+	}
+
+	filePath := filepath.ToSlash(filepath.Join(path, fileName))
+	if err := checkUploadPolicy(filePath, size, totalChunks); err != nil {
+		return "", nil, err
+	}
+
+	// 确认所有分块都已接收，避免合并出不完整的文件
+	received := make(map[int]bool, totalChunks)
+	for _, chunk := range s.listChunkDir(tempDir) {
+		received[chunk.Index] = true
+	}
+	missing := 0
+	for i := 0; i < totalChunks; i++ {
+		if !received[i] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return "", nil, fmt.Errorf("还有 %d 个分块未上传", missing)
+	}
+
+	// 检查文件上传前置条件，按分块总大小校验上传策略与配额；已有文件在合并成功后才会被替换
+	if err := s.CheckUpload(ctx, path, fileName, size, override); err != nil {
+		return "", nil, err
+	}
+	head, err := s.chunkHead(tempDir, totalChunks)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkUploadType(filePath, head); err != nil {
+		return "", nil, err
+	}
+	if err := s.storage.Mkdir(path); err != nil {
+		return "", nil, fmt.Errorf("创建目标目录失败: %s", err)
+	}
+
+	algorithm := consts.ChecksumSHA256
+	if checksum != nil {
+		algorithm = checksum.Algorithm
+	}
+	digest, _ := newDigest(algorithm)
+	// 摘要索引始终使用 SHA-256
+	contentDigest := digest
+	if algorithm != consts.ChecksumSHA256 {
+		contentDigest = sha256.New()
+	}
+
+	tempPath, err := s.writeMergeTemp(tempDir, path, totalChunks, digest, contentDigest, eventKey)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := verifyChecksum("文件 "+filePath, checksum, digest); err != nil {
+		s.removeMergeTemp(tempPath)
+		return "", nil, err
+	}
+
+	// 合并期间目标位置可能已被其他请求占用
+	defer s.trackFolderUsage(filePath)()
+	if info, err := s.storage.Stat(filePath); err == nil && (info.IsDir() || !override) {
+		s.removeMergeTemp(tempPath)
+		if info.IsDir() {
+			return "", nil, fmt.Errorf("同名文件夹已存在: %s", fileName)
+		}
+		return "", nil, fmt.Errorf("文件已存在: %s", fileName)
+	}
+	if err := s.storage.Rename(tempPath, filePath); err != nil {
+		s.removeMergeTemp(tempPath)
+		return "", nil, fmt.Errorf("替换目标文件失败: %s", err)
+	}
+	s.forgetMergeTemp(tempPath)
+
+	// 合并成功后再清理分块
+	if err := s.temp.RemoveAll(tempDir); err != nil {
+		glog.Warnf("清理临时目录失败: %s, 路径: %s", err, tempDir)
+	}
+	s.recordHash(filePath, hex.EncodeToString(contentDigest.Sum(nil)))
+	s.recordUsage(ctx, filePath)
+
+	// 清除相关缓存
+	s.clearFileRelatedCache(filePath)
+
+	glog.Infof("文件合并成功: %s", filePath)
+	return filePath, &model.Checksum{Algorithm: algorithm, Value: hex.EncodeToString(digest.Sum(nil))}, nil
+}
+
+// writeMergeTemp 将分块按顺序写入目录 path 中的隐藏临时文件并落盘，同时计算摘要，返回临时文件路径
+func (s *FileServiceImpl) writeMergeTemp(tempDir, path string, totalChunks int, digest, contentDigest hash.Hash, eventKey string) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", fmt.Errorf("生成临时文件名失败: %s", err)
+	}
+	tempPath := filepath.ToSlash(filepath.Join(path, consts.MergeTempPrefix+id))
+	// 先记录位置再创建，合并中断时过期清理据此找到临时文件
+	if err := s.recordMergeTemp(tempPath); err != nil {
+		return "", fmt.Errorf("记录临时文件失败: %s", err)
+	}
+	outFile, err := s.createParts(tempPath)
+	if err != nil {
+		s.forgetMergeTemp(tempPath)
+		return "", fmt.Errorf("创建临时文件失败: %s", err)
+	}
+
+	var digests io.Writer = digest
+	if contentDigest != digest {
+		digests = io.MultiWriter(digest, contentDigest)
+	}
+	progress := 0
+	for i := 0; i < totalChunks; i++ {
+		if err = s.appendChunk(outFile, digests, filepath.Join(tempDir, chunkName(i))); err != nil {
+			break
+		}
+		// 进度变化时才发布，避免分块很多时频繁发布
+		if current := chunkProgress(i+1, totalChunks); current != progress {
+			progress = current
+			publishUploadEvent(eventKey, &model.UploadEvent{
+				Type:           consts.UploadEventMergeProgress,
+				Status:         consts.UploadStatusUploading,
+				Progress:       progress,
+				ReceivedChunks: totalChunks,
+				TotalChunks:    totalChunks,
+			})
+		}
+	}
+	if err != nil {
+		outFile.Abort()
+		s.removeMergeTemp(tempPath)
+		return "", err
+	}
+	if err := outFile.Close(); err != nil {
+		s.removeMergeTemp(tempPath)
+		return "", fmt.Errorf("写入临时文件失败: %s", err)
+	}
+	return tempPath, nil
+}
+
+// createParts 创建按分块写入的文件，驱动支持分片写入（如对象存储的分片上传）时每个分块作为一个分片
+func (s *FileServiceImpl) createParts(path string) (storage.PartWriter, error) {
+	if creator, ok := s.storage.(storage.PartCreator); ok {
+		return creator.CreateParts(path)
+	}
+	writer, err := s.storage.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &streamPartWriter{writer: writer}, nil
+}
+
+// streamPartWriter 将分片依次写入普通的写入句柄，Close 前先落盘
+type streamPartWriter struct {
+	writer io.WriteCloser
+}
+
+func (w *streamPartWriter) WritePart(reader io.Reader, size int64) error {
+	n, err := io.Copy(w.writer, reader)
+	if err == nil && n != size {
+		err = fmt.Errorf("分片大小为 %d 字节，实际读取 %d 字节", size, n)
+	}
+	return err
+}
+
+func (w *streamPartWriter) Close() error {
+	if syncer, ok := w.writer.(storage.Syncer); ok {
+		if err := syncer.Sync(); err != nil {
+			w.writer.Close()
+			return err
+		}
+	}
+	return w.writer.Close()
+}
+
+func (w *streamPartWriter) Abort() error {
+	return w.writer.Close()
+}
+
+// chunkHead 读取合并后文件开头用于识别类型的内容
+func (s *FileServiceImpl) chunkHead(tempDir string, totalChunks int) ([]byte, error) {
+	head := make([]byte, 0, sniffLen)
+	for i := 0; i < totalChunks && len(head) < sniffLen; i++ {
+		chunkFile, err := s.temp.Open(filepath.Join(tempDir, chunkName(i)))
+		if err != nil {
+			return nil, fmt.Errorf("打开分块文件失败: %s", err)
+		}
+		buf, err := io.ReadAll(io.LimitReader(chunkFile, int64(sniffLen-len(head))))
+		chunkFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取分块文件失败: %s", err)
+		}
+		head = append(head, buf...)
+	}
+	return head, nil
+}
+
+// isMergeTemp 判断 name 是否为合并分块时写入的临时文件，这类文件不出现在列表、搜索与统计中
+func isMergeTemp(name string) bool {
+	return strings.HasPrefix(name, consts.MergeTempPrefix)
+}
+
+// checkReservedName 拒绝以合并临时文件前缀命名的文件或文件夹，避免被隐藏或被过期清理删除
+func checkReservedName(filePath string) error {
+	if isMergeTemp(filepath.Base(filePath)) {
+		return fmt.Errorf("名称不能以 %s 开头", consts.MergeTempPrefix)
+	}
+	return nil
+}
+
+// removeMergeTemp 删除合并失败留下的临时文件及其记录
+func (s *FileServiceImpl) removeMergeTemp(tempPath string) {
+	if err := s.storage.Remove(tempPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		glog.Warnf("删除临时文件失败: %s, 路径: %s", err, tempPath)
+		return
+	}
+	s.forgetMergeTemp(tempPath)
+}
+
+// mergeTempRecord 临时存储中记录合并临时文件 tempPath 位置的文件
+func mergeTempRecord(tempPath string) string {
+	return filepath.Join(consts.MergeTempDir, strings.TrimPrefix(filepath.Base(tempPath), consts.MergeTempPrefix))
+}
+
+// recordMergeTemp 在临时存储中记录合并临时文件的位置，过期清理只检查有记录的临时文件，无需遍历整个存储
+func (s *FileServiceImpl) recordMergeTemp(tempPath string) error {
+	writer, err := s.temp.Create(mergeTempRecord(tempPath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(writer, tempPath); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// forgetMergeTemp 临时文件已改名为目标文件或已删除后移除其记录
+func (s *FileServiceImpl) forgetMergeTemp(tempPath string) {
+	record := mergeTempRecord(tempPath)
+	if err := s.temp.Remove(record); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		glog.Warnf("删除合并临时文件记录失败: %s, 路径: %s", err, record)
+	}
+}
+
+// appendChunk 将分块内容作为一个分片写入目标文件，同时写入 digest
+func (s *FileServiceImpl) appendChunk(dst storage.PartWriter, digest io.Writer, chunkPath string) error {
+	chunkFile, err := s.temp.Open(chunkPath)
+	if err != nil {
+		return fmt.Errorf("打开分块文件失败: %s", err)
+	}
+	defer chunkFile.Close()
+	info, err := s.temp.Stat(chunkPath)
+	if err != nil {
+		return fmt.Errorf("获取分块信息失败: %s", err)
+	}
+
+	if err := dst.WritePart(io.TeeReader(chunkFile, digest), info.Size()); err != nil {
+		return fmt.Errorf("复制分块内容失败: %s", err)
+	}
+	return nil
+}
+
+// writeFile 将内容写入存储
+func (s *FileServiceImpl) writeFile(path string, reader io.Reader) error {
+	writer, err := s.storage.Create(path)
 	if err != nil {
 		return err
 	}
-	f.Close()
-	return os.Remove(tempFile)
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// chunkName 分块文件名
+func chunkName(index int) string {
+	return fmt.Sprintf("chunk_%d", index)
+}
+
+// parseChunkName 从分块文件名解析分块索引
+func parseChunkName(name string) (int, bool) {
+	index, err := strconv.Atoi(strings.TrimPrefix(name, "chunk_"))
+	if err != nil || index < 0 || !strings.HasPrefix(name, "chunk_") {
+		return 0, false
+	}
+	return index, true
+}
+
+// chunkProgress 根据已接收的分块数计算上传进度
+func chunkProgress(received, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	if received >= total {
+		return 100
+	}
+	return received * 100 / total
 }
 
 // AddFavorite 添加收藏
-func (s *FileServiceImpl) AddFavorite(filePath string) error {
+func (s *FileServiceImpl) AddFavorite(ctx context.Context, filePath string) error {
 	glog.Infof("添加收藏，文件路径: %s", filePath)
 
+	user, err := s.favoriteUser(ctx)
+	if err != nil {
+		return err
+	}
+	filePath, err = sandbox.Clean(filePath)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, model.PermRead, filePath); err != nil {
+		return err
+	}
+
 	// 检查文件是否存在
-	absPath := filepath.Join(consts.UploadDir, filePath)
-	info, err := os.Stat(absPath)
+	info, err := s.storage.Stat(filePath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("文件不存在: %s", filePath)
 		}
 		return fmt.Errorf("获取文件信息失败: %s", err)
@@ -411,125 +1014,214 @@ func (s *FileServiceImpl) AddFavorite(filePath string) error {
 
 	// 创建收藏记录
 	favorite := model.Favorite{
+		UserID:     user.ID,
 		Name:       info.Name(),
 		Path:       filePath,
 		IsDir:      info.IsDir(),
 		CreateTime: time.Now(),
 	}
+	if err := s.favorites.add(&favorite); err != nil {
+		return err
+	}
+	cache.Del(cache.FavoriteListKey(user.ID))
 
-	// TODO: 保存到数据库
 	glog.Infof("收藏成功: %+v", favorite)
 	return nil
 }
 
 // RemoveFavorite 取消收藏
-func (s *FileServiceImpl) RemoveFavorite(filePath string) error {
+func (s *FileServiceImpl) RemoveFavorite(ctx context.Context, filePath string) error {
 	glog.Infof("取消收藏，文件路径: %s", filePath)
-	// TODO: 从数据库中删除
+
+	user, err := s.favoriteUser(ctx)
+	if err != nil {
+		return err
+	}
+	filePath, err = sandbox.Clean(filePath)
+	if err != nil {
+		return err
+	}
+	if err := s.favorites.remove(user.ID, filePath); err != nil {
+		return err
+	}
+	cache.Del(cache.FavoriteListKey(user.ID))
 	return nil
 }
 
-// GetFavorites 获取收藏列表
-func (s *FileServiceImpl) GetFavorites() ([]model.Favorite, error) {
+// GetFavorites 获取收藏列表（带缓存）
+func (s *FileServiceImpl) GetFavorites(ctx context.Context) ([]model.Favorite, error) {
 	glog.Info("获取收藏列表")
-	// TODO: 从数据库中查询
-	return []model.Favorite{}, nil
+
+	user, err := s.favoriteUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 尝试从缓存获取
+	cacheKey := cache.FavoriteListKey(user.ID)
+	var favorites []model.Favorite
+	if cached, err := cache.Get(cacheKey); err == nil && json.Unmarshal([]byte(cached), &favorites) == nil {
+		glog.Infof("从缓存获取收藏列表成功，用户: %s", user.Username)
+	} else {
+		if favorites, err = s.favorites.list(user.ID); err != nil {
+			return nil, err
+		}
+		if cacheData, err := json.Marshal(favorites); err == nil {
+			cache.Set(cacheKey, cacheData, time.Duration(cache.FavoriteExpiration)*time.Second)
+		}
+	}
+
+	// 隐藏已失去访问权限的收藏
+	check, err := s.accessChecker(ctx, model.PermRead)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.Favorite{}
+	for _, favorite := range favorites {
+		if check(favorite.Path) {
+			result = append(result, favorite)
+		}
+	}
+	return result, nil
+}
+
+// favoriteUser 收藏所属的当前用户
+func (s *FileServiceImpl) favoriteUser(ctx context.Context) (*model.User, error) {
+	if s.favorites == nil {
+		return nil, fmt.Errorf("收藏功能未启用")
+	}
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return nil, fmt.Errorf("收藏需要登录")
+	}
+	return principal.User, nil
+}
+
+// moveFavorites 文件移动或重命名后同步收藏
+func (s *FileServiceImpl) moveFavorites(oldPath, newPath string) {
+	if s.favorites == nil {
+		return
+	}
+	if err := s.favorites.movePath(oldPath, newPath); err != nil {
+		glog.Errorf("同步收藏失败: %s, 路径: %s -> %s", err, oldPath, newPath)
+	}
+	cache.DelByPattern(cache.FavoriteListPattern)
 }
 
 // CreateFolder 创建文件夹
-func (h *FileServiceImpl) CreateFolder(path string) error {
+func (h *FileServiceImpl) CreateFolder(ctx context.Context, path string) error {
 	glog.Infof("开始创建文件夹，路径: %s", path)
 
 	// 规范化路径
-	path = filepath.Clean(path)
-	if path == "." {
-		path = ""
+	path, err := sandbox.Clean(path)
+	if err != nil {
+		return err
 	}
-
-	// 构建完整的文件夹路径
-	folderPath := filepath.Join(consts.UploadDir, path)
-	glog.Infof("目标文件夹路径: %s", folderPath)
+	if err := h.authorize(ctx, model.PermWrite, path); err != nil {
+		return err
+	}
+	if err := checkReservedName(path); err != nil {
+		return err
+	}
+	glog.Infof("目标文件夹路径: %s", path)
 
 	// 检查路径是否已存在
-	if info, err := os.Stat(folderPath); err == nil {
+	if info, err := h.storage.Stat(path); err == nil {
 		if info.IsDir() {
-			glog.Errorf("文件夹已存在: %s", folderPath)
+			glog.Errorf("文件夹已存在: %s", path)
 			return fmt.Errorf("文件夹已存在: %s", path)
 		}
-		glog.Errorf("路径已存在但不是文件夹: %s", folderPath)
+		glog.Errorf("路径已存在但不是文件夹: %s", path)
 		return fmt.Errorf("路径已存在但不是文件夹: %s", path)
-	} else if !os.IsNotExist(err) {
+	} else if !errors.Is(err, fs.ErrNotExist) {
 		glog.Errorf("检查路径状态失败: %s", err)
 		return fmt.Errorf("检查路径状态失败: %s", err)
 	}
 
 	// 创建文件夹
-	if err := os.MkdirAll(folderPath, os.ModePerm); err != nil {
-		glog.Errorf("创建文件夹失败: %s, 路径: %s", err, folderPath)
+	if err := h.storage.Mkdir(path); err != nil {
+		glog.Errorf("创建文件夹失败: %s, 路径: %s", err, path)
 		return fmt.Errorf("创建文件夹失败: %s", err)
 	}
 
 	// 清除相关缓存
 	h.clearFileRelatedCache(path)
 
-	glog.Infof("文件夹创建成功: %s", folderPath)
+	glog.Infof("文件夹创建成功: %s", path)
 	return nil
 }
 
 // RemoveFile 删除文件（实际调用 DeleteFile 方法）
-func (h *FileServiceImpl) RemoveFile(path string, force bool) error {
-	return h.DeleteFile(path, force)
+func (h *FileServiceImpl) RemoveFile(ctx context.Context, path string, force bool) error {
+	return h.DeleteFile(ctx, path, force)
 }
 
 // CopyFile 复制文件或文件夹
-func (h *FileServiceImpl) CopyFile(srcPath string, destPath string) error {
+func (h *FileServiceImpl) CopyFile(ctx context.Context, srcPath string, destPath string) error {
 	glog.Infof("开始复制，源路径: %s, 目标路径: %s", srcPath, destPath)
 
-	srcFullPath := filepath.Join(consts.UploadDir, srcPath)
-	destFullPath := filepath.Join(consts.UploadDir, destPath)
+	srcPath, destPath, err := cleanSrcDest(srcPath, destPath)
+	if err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermRead, srcPath); err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermWrite, destPath); err != nil {
+		return err
+	}
 
 	// 检查源路径是否存在
-	srcInfo, err := os.Stat(srcFullPath)
+	srcInfo, err := h.storage.Stat(srcPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("源文件不存在: %s", srcPath)
 		}
 		return fmt.Errorf("获取源文件信息失败: %s", err)
 	}
 
 	// 检查目标文件是否已存在
-	if destInfo, err := os.Stat(destFullPath); err == nil {
-		// 如果目标是文件夹，则在目标文件夹中创建同名文件/文件夹
-		if destInfo.IsDir() {
-			destFullPath = filepath.Join(destFullPath, filepath.Base(srcPath))
-			destPath = filepath.Join(destPath, filepath.Base(srcPath))
-			// 再次检查是否存在
-			if _, err := os.Stat(destFullPath); err == nil {
-				return fmt.Errorf("目标路径已存在: %s", destPath)
-			}
-		} else {
-			return fmt.Errorf("目标路径已存在: %s", destPath)
-		}
+	if destPath, err = h.resolveDestPath(srcPath, destPath); err != nil {
+		return err
+	}
+	if srcInfo.IsDir() && isSubPath(srcPath, destPath) {
+		return fmt.Errorf("不能将文件夹复制到其自身或子目录中")
+	}
+	if err := h.authorizeTree(ctx, model.PermRead, srcPath); err != nil {
+		return err
+	}
+	if err := h.checkPlacementPolicy(srcPath, destPath, srcInfo); err != nil {
+		return err
+	}
+	req, err := h.treeQuotaRequest(srcPath, srcInfo)
+	if err != nil {
+		return err
+	}
+	if _, err := h.checkQuota(ctx, destPath, req); err != nil {
+		return err
 	}
 
 	// 确保目标目录存在
-	destDir := filepath.Dir(destFullPath)
-	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+	if err := h.storage.Mkdir(filepath.Dir(destPath)); err != nil {
 		return fmt.Errorf("创建目标目录失败: %s", err)
 	}
 
+	defer h.trackFolderUsage(destPath)()
 	if srcInfo.IsDir() {
 		// 复制目录
-		if err := h.copyDir(srcFullPath, destFullPath); err != nil {
+		if err := h.copyDir(srcPath, destPath); err != nil {
 			return fmt.Errorf("复制目录失败: %s", err)
 		}
 	} else {
 		// 复制文件
-		if err := h.copyFileContent(srcFullPath, destFullPath); err != nil {
+		if err := h.copyFileContent(srcPath, destPath); err != nil {
 			return fmt.Errorf("复制文件失败: %s", err)
 		}
 	}
 
+	h.copyHashes(srcPath, destPath)
+	h.recordUsage(ctx, destPath)
+
 	// 清除源路径和目标路径相关的缓存
 	h.clearFileRelatedCache(srcPath)
 	h.clearFileRelatedCache(destPath)
@@ -540,46 +1232,62 @@ func (h *FileServiceImpl) CopyFile(srcPath string, destPath string) error {
 }
 
 // MoveFile 移动文件或文件夹
-func (h *FileServiceImpl) MoveFile(srcPath string, destPath string) error {
+func (h *FileServiceImpl) MoveFile(ctx context.Context, srcPath string, destPath string) error {
 	glog.Infof("开始移动，源路径: %s, 目标路径: %s", srcPath, destPath)
 
-	srcFullPath := filepath.Join(consts.UploadDir, srcPath)
-	destFullPath := filepath.Join(consts.UploadDir, destPath)
+	srcPath, destPath, err := cleanSrcDest(srcPath, destPath)
+	if err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermDelete, srcPath); err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermWrite, destPath); err != nil {
+		return err
+	}
 
 	// 检查源路径是否存在
-	if _, err := os.Stat(srcFullPath); err != nil {
-		if os.IsNotExist(err) {
+	srcInfo, err := h.storage.Stat(srcPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("源文件不存在: %s", srcPath)
 		}
 		return fmt.Errorf("获取源文件信息失败: %s", err)
 	}
 
 	// 检查目标文件是否已存在
-	if destInfo, err := os.Stat(destFullPath); err == nil {
-		// 如果目标是文件夹，则在目标文件夹中创建同名文件/文件夹
-		if destInfo.IsDir() {
-			destFullPath = filepath.Join(destFullPath, filepath.Base(srcPath))
-			destPath = filepath.Join(destPath, filepath.Base(srcPath))
-			// 再次检查是否存在
-			if _, err := os.Stat(destFullPath); err == nil {
-				return fmt.Errorf("目标路径已存在: %s", destPath)
-			}
-		} else {
-			return fmt.Errorf("目标路径已存在: %s", destPath)
-		}
+	if destPath, err = h.resolveDestPath(srcPath, destPath); err != nil {
+		return err
+	}
+	if srcInfo.IsDir() && isSubPath(srcPath, destPath) {
+		return fmt.Errorf("不能将文件夹移动到其自身或子目录中")
+	}
+	if err := h.authorizeTree(ctx, model.PermDelete, srcPath); err != nil {
+		return err
+	}
+	if err := h.checkPlacementPolicy(srcPath, destPath, srcInfo); err != nil {
+		return err
+	}
+	if err := h.checkMoveQuota(srcPath, destPath, srcInfo); err != nil {
+		return err
 	}
 
 	// 确保目标目录存在
-	destDir := filepath.Dir(destFullPath)
-	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+	if err := h.storage.Mkdir(filepath.Dir(destPath)); err != nil {
 		return fmt.Errorf("创建目标目录失败: %s", err)
 	}
 
 	// 执行移动
-	if err := os.Rename(srcFullPath, destFullPath); err != nil {
+	defer h.trackFolderUsage(srcPath)()
+	defer h.trackFolderUsage(destPath)()
+	if err := h.storage.Rename(srcPath, destPath); err != nil {
 		glog.Errorf("移动失败: %s", err)
 		return fmt.Errorf("移动失败: %s", err)
 	}
+	h.moveACL(srcPath, destPath)
+	h.moveUsage(srcPath, destPath)
+	h.moveFavorites(srcPath, destPath)
+	h.moveHashes(srcPath, destPath)
 
 	// 清除源路径和目标路径相关的缓存
 	h.clearFileRelatedCache(srcPath)
@@ -592,28 +1300,63 @@ func (h *FileServiceImpl) MoveFile(srcPath string, destPath string) error {
 }
 
 // RenameFile 重命名文件或文件夹
-func (h *FileServiceImpl) RenameFile(oldPath string, newName string) error {
+func (h *FileServiceImpl) RenameFile(ctx context.Context, oldPath string, newName string) error {
 	glog.Infof("开始重命名，原路径: %s, 新名称: %s", oldPath, newName)
 
-	// 构建完整的原路径
-	oldFullPath := filepath.Join(consts.UploadDir, oldPath)
+	oldPath, err := sandbox.Clean(oldPath)
+	if err != nil {
+		return err
+	}
+	if oldPath == "" {
+		return fmt.Errorf("不允许重命名根目录")
+	}
+	if newName, err = sandbox.CleanName(newName); err != nil {
+		return err
+	}
+	if err := checkReservedName(newName); err != nil {
+		return err
+	}
 
 	// 获取父目录
 	parentDir := filepath.Dir(oldPath)
 	// 构建新路径
-	newPath := filepath.Join(parentDir, newName)
-	newFullPath := filepath.Join(consts.UploadDir, newPath)
+	newPath := filepath.ToSlash(filepath.Join(parentDir, newName))
+	if err := h.authorize(ctx, model.PermDelete, oldPath); err != nil {
+		return err
+	}
+	if err := h.authorize(ctx, model.PermWrite, newPath); err != nil {
+		return err
+	}
+	if err := h.authorizeTree(ctx, model.PermDelete, oldPath); err != nil {
+		return err
+	}
+	oldInfo, err := h.storage.Stat(oldPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("源文件不存在: %s", oldPath)
+		}
+		return fmt.Errorf("获取源文件信息失败: %s", err)
+	}
+	if err := h.checkPlacementPolicy(oldPath, newPath, oldInfo); err != nil {
+		return err
+	}
 
 	// 检查新路径是否已存在
-	if _, err := os.Stat(newFullPath); err == nil {
+	if _, err := h.storage.Stat(newPath); err == nil {
 		return fmt.Errorf("目标路径已存在: %s", newPath)
 	}
 
 	// 执行重命名
-	if err := os.Rename(oldFullPath, newFullPath); err != nil {
+	defer h.trackFolderUsage(oldPath)()
+	defer h.trackFolderUsage(newPath)()
+	if err := h.storage.Rename(oldPath, newPath); err != nil {
 		glog.Errorf("重命名失败: %s", err)
 		return fmt.Errorf("重命名失败: %s", err)
 	}
+	h.moveACL(oldPath, newPath)
+	h.moveUsage(oldPath, newPath)
+	h.moveFavorites(oldPath, newPath)
+	h.moveHashes(oldPath, newPath)
 
 	// 清除旧路径和新路径相关的缓存
 	h.clearFileRelatedCache(oldPath)
@@ -624,15 +1367,56 @@ func (h *FileServiceImpl) RenameFile(oldPath string, newName string) error {
 	return nil
 }
 
+// cleanSrcDest 校验复制/移动操作的源路径和目标路径
+func cleanSrcDest(srcPath, destPath string) (string, string, error) {
+	srcPath, err := sandbox.Clean(srcPath)
+	if err != nil {
+		return "", "", err
+	}
+	if srcPath == "" {
+		return "", "", fmt.Errorf("不允许操作根目录")
+	}
+	destPath, err = sandbox.Clean(destPath)
+	if err != nil {
+		return "", "", err
+	}
+	return srcPath, destPath, nil
+}
+
+// resolveDestPath 计算复制/移动的最终目标路径
+//
+// 目标是已存在的文件夹时，在其中创建同名文件/文件夹；目标已存在且不是文件夹时报错。
+func (h *FileServiceImpl) resolveDestPath(srcPath, destPath string) (string, error) {
+	destInfo, err := h.storage.Stat(destPath)
+	if err != nil {
+		return destPath, nil
+	}
+	if !destInfo.IsDir() {
+		return "", fmt.Errorf("目标路径已存在: %s", destPath)
+	}
+
+	destPath = filepath.ToSlash(filepath.Join(destPath, filepath.Base(srcPath)))
+	// 再次检查是否存在
+	if _, err := h.storage.Stat(destPath); err == nil {
+		return "", fmt.Errorf("目标路径已存在: %s", destPath)
+	}
+	return destPath, nil
+}
+
+// isSubPath 判断 path 是否为 parent 本身或其子路径
+func isSubPath(parent, path string) bool {
+	return path == parent || strings.HasPrefix(path, parent+"/")
+}
+
 // copyDir 复制目录
 func (h *FileServiceImpl) copyDir(src string, dest string) error {
 	// 创建目标目录
-	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+	if err := h.storage.Mkdir(dest); err != nil {
 		return err
 	}
 
 	// 读取源目录
-	entries, err := os.ReadDir(src)
+	entries, err := h.storage.List(src)
 	if err != nil {
 		return err
 	}
@@ -659,35 +1443,24 @@ func (h *FileServiceImpl) copyDir(src string, dest string) error {
 
 // copyFileContent 复制文件内容
 func (h *FileServiceImpl) copyFileContent(src string, dest string) error {
-	// 打开源文件
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
+	// 存储支持时直接在服务端复制
+	if copier, ok := h.storage.(storage.Copier); ok {
+		return copier.Copy(src, dest)
 	}
-	defer srcFile.Close()
 
-	// 创建目标文件
-	destFile, err := os.Create(dest)
+	// 打开源文件
+	srcFile, err := h.storage.Open(src)
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
+	defer srcFile.Close()
 
 	// 复制内容
-	if _, err := io.Copy(destFile, srcFile); err != nil {
-		return err
-	}
-
-	// 保持文件权限
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-	return os.Chmod(dest, srcInfo.Mode())
+	return h.writeFile(dest, srcFile)
 }
 
 // ClearFileCache 清除文件相关的缓存
-func (s *FileServiceImpl) ClearFileCache(path string) error {
+func (s *FileServiceImpl) ClearFileCache(ctx context.Context, path string) error {
 	s.clearFileRelatedCache(path)
 	return nil
 }
@@ -0,0 +1,167 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// UploadFolder 在 dir 下按相对路径批量保存文件并重建目录结构，conflict 为同名文件的处理方式
+//
+// 相对路径不合法、重复或整批超出配额时不写入任何内容；其余情况逐个校验权限、上传策略与同名文件后保存，
+// 单个文件失败不影响其他文件，结果按 entries 的顺序返回。
+func (s *FileServiceImpl) UploadFolder(ctx context.Context, dir string, entries []*model.FolderUploadEntry, conflict string) ([]*model.FolderUploadResult, error) {
+	dir, err := sandbox.Clean(dir)
+	if err != nil {
+		return nil, err
+	}
+	if conflict == "" {
+		conflict = consts.ConflictError
+	}
+	switch conflict {
+	case consts.ConflictError, consts.ConflictOverwrite, consts.ConflictSkip, consts.ConflictRename:
+	default:
+		return nil, fmt.Errorf("不支持的同名文件处理方式: %s", conflict)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("没有要上传的文件")
+	}
+	if limit := config.Upload.MaxBatchFiles; limit > 0 && len(entries) > limit {
+		return nil, fmt.Errorf("一次最多上传 %d 个文件", limit)
+	}
+
+	// 先校验全部相对路径，避免只创建出一部分目录
+	targets := make([]string, len(entries))
+	reserved := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		rel, err := sandbox.Clean(entry.RelativePath)
+		if err != nil {
+			return nil, err
+		}
+		if rel == "" {
+			return nil, fmt.Errorf("文件的相对路径不能为空")
+		}
+		target := path.Join(dir, rel)
+		if reserved[target] {
+			return nil, fmt.Errorf("相对路径重复: %s", rel)
+		}
+		reserved[target] = true
+		targets[i] = target
+	}
+	for _, target := range targets {
+		for parent := path.Dir(target); parent != "." && parent != dir; parent = path.Dir(parent) {
+			if reserved[parent] {
+				return nil, fmt.Errorf("路径既是文件又是文件夹: %s", strings.TrimPrefix(parent, dir+"/"))
+			}
+		}
+	}
+
+	// 按同名文件的处理方式确定每个文件的保存位置，不能保存的文件直接记录原因
+	results := make([]*model.FolderUploadResult, len(entries))
+	var total quotaRequest
+	for i, entry := range entries {
+		result := &model.FolderUploadResult{RelativePath: entry.RelativePath}
+		results[i] = result
+		target := targets[i]
+		if info, err := s.storage.Stat(target); err == nil && !info.IsDir() {
+			switch conflict {
+			case consts.ConflictSkip:
+				result.Status, result.Path = consts.UploadStatusSkipped, target
+				continue
+			case consts.ConflictRename:
+				target = s.availableName(target, reserved)
+				reserved[target] = true
+				targets[i] = target
+			}
+		}
+
+		parent, fileName := splitFilePath(target)
+		if err := s.CheckUpload(ctx, parent, fileName, entry.Size, conflict == consts.ConflictOverwrite); err != nil {
+			result.Status, result.Error = consts.UploadStatusError, err.Error()
+			continue
+		}
+		req := s.uploadQuotaRequest(target, entry.Size)
+		total.bytes += req.bytes
+		total.files += req.files
+		total.freed += req.freed
+	}
+	if _, err := s.checkQuota(ctx, dir, total); err != nil {
+		return nil, err
+	}
+
+	var saved, skipped, failed int
+	for i, entry := range entries {
+		result := results[i]
+		switch result.Status {
+		case consts.UploadStatusSkipped:
+			skipped++
+			continue
+		case consts.UploadStatusError:
+			failed++
+			continue
+		}
+
+		filePath, err := s.saveFolderEntry(ctx, targets[i], entry, conflict == consts.ConflictOverwrite)
+		if err != nil {
+			glog.Errorf("批量上传文件失败: %s, 文件: %s", err, entry.RelativePath)
+			result.Status, result.Error = consts.UploadStatusError, err.Error()
+			failed++
+			continue
+		}
+		result.Status, result.Path = consts.UploadStatusDone, filePath
+		saved++
+	}
+
+	glog.Infof("批量上传完成，目录: %s, 成功: %d, 跳过: %d, 失败: %d", dir, saved, skipped, failed)
+	return results, nil
+}
+
+// saveFolderEntry 保存批量上传中的一个文件到 target
+func (s *FileServiceImpl) saveFolderEntry(ctx context.Context, target string, entry *model.FolderUploadEntry, override bool) (string, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return "", fmt.Errorf("读取上传文件失败: %s", err)
+	}
+	defer reader.Close()
+
+	parent, fileName := splitFilePath(target)
+	return s.SaveFile(ctx, parent, fileName, reader, override)
+}
+
+// splitFilePath 拆分为所在目录与文件名，根目录返回空字符串
+func splitFilePath(filePath string) (string, string) {
+	parent, fileName := path.Split(filePath)
+	parent = path.Clean(parent)
+	if parent == "." {
+		parent = ""
+	}
+	return parent, fileName
+}
+
+// availableName 为 filePath 选择一个未被占用的名称，如 "a (1).txt"
+func (s *FileServiceImpl) availableName(filePath string, reserved map[string]bool) string {
+	parent, fileName := splitFilePath(filePath)
+	for n := 1; ; n++ {
+		candidate := path.Join(parent, numberedName(fileName, n))
+		if reserved[candidate] {
+			continue
+		}
+		if _, err := s.storage.Stat(candidate); errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+	}
+}
+
+// numberedName 在扩展名前加上序号，如 "a.txt" 的第 1 个为 "a (1).txt"
+func numberedName(fileName string, n int) string {
+	ext := path.Ext(fileName)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(fileName, ext), n, ext)
+}
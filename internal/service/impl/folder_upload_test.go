@@ -0,0 +1,138 @@
+package impl
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// folderEntries 按 "相对路径", "内容" 成对的参数创建批量上传的文件
+func folderEntries(pairs ...string) []*model.FolderUploadEntry {
+	var entries []*model.FolderUploadEntry
+	for i := 0; i+1 < len(pairs); i += 2 {
+		content := pairs[i+1]
+		entries = append(entries, &model.FolderUploadEntry{
+			RelativePath: pairs[i],
+			Size:         int64(len(content)),
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+		})
+	}
+	return entries
+}
+
+// resultSummary 将结果概括为 "相对路径:状态:保存路径"，便于比较
+func resultSummary(results []*model.FolderUploadResult) []string {
+	var summary []string
+	for _, result := range results {
+		summary = append(summary, result.RelativePath+":"+result.Status+":"+result.Path)
+	}
+	return summary
+}
+
+func TestUploadFolderConflictModes(t *testing.T) {
+	cases := []struct {
+		conflict string
+		results  []string
+		contents map[string]string
+	}{
+		{
+			conflict: consts.ConflictError,
+			results:  []string{"a.txt:error:", "sub/b.txt:error:", "sub/c.txt:done:dest/sub/c.txt"},
+			contents: map[string]string{"dest/a.txt": "old a", "dest/sub/b.txt": "old b", "dest/sub/c.txt": "new c"},
+		},
+		{
+			conflict: consts.ConflictOverwrite,
+			results:  []string{"a.txt:done:dest/a.txt", "sub/b.txt:done:dest/sub/b.txt", "sub/c.txt:done:dest/sub/c.txt"},
+			contents: map[string]string{"dest/a.txt": "new a", "dest/sub/b.txt": "new b", "dest/sub/c.txt": "new c"},
+		},
+		{
+			conflict: consts.ConflictSkip,
+			results:  []string{"a.txt:skipped:dest/a.txt", "sub/b.txt:skipped:dest/sub/b.txt", "sub/c.txt:done:dest/sub/c.txt"},
+			contents: map[string]string{"dest/a.txt": "old a", "dest/sub/b.txt": "old b", "dest/sub/c.txt": "new c"},
+		},
+		{
+			conflict: consts.ConflictRename,
+			results:  []string{"a.txt:done:dest/a (2).txt", "sub/b.txt:done:dest/sub/b (1).txt", "sub/c.txt:done:dest/sub/c.txt"},
+			contents: map[string]string{"dest/a.txt": "old a", "dest/a (2).txt": "new a", "dest/sub/b (1).txt": "new b", "dest/sub/c.txt": "new c"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.conflict, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.writeFile(t, "dest/a.txt", "old a")
+			env.writeFile(t, "dest/a (1).txt", "taken")
+			env.writeFile(t, "dest/sub/b.txt", "old b")
+
+			entries := folderEntries("a.txt", "new a", "sub/b.txt", "new b", "sub/c.txt", "new c")
+			results, err := env.service.UploadFolder(context.Background(), "dest", entries, c.conflict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultSummary(results); strings.Join(got, ",") != strings.Join(c.results, ",") {
+				t.Errorf("结果 = %v, 期望 %v", got, c.results)
+			}
+			for p, want := range c.contents {
+				if got := env.readFile(t, p); got != want {
+					t.Errorf("%s 的内容 = %q, 期望 %q", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadFolderRejectsInvalidBatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		entries  []*model.FolderUploadEntry
+		conflict string
+	}{
+		{"相对路径越界", folderEntries("a.txt", "a", "../b.txt", "b"), ""},
+		{"相对路径重复", folderEntries("a.txt", "a", "./a.txt", "b"), ""},
+		{"既是文件又是文件夹", folderEntries("a", "a", "a/b.txt", "b"), ""},
+		{"相对路径为空", folderEntries("a.txt", "a", "", "b"), ""},
+		{"未知的处理方式", folderEntries("a.txt", "a"), "merge"},
+		{"没有文件", nil, ""},
+	}
+	for _, c := range cases {
+		if _, err := env.service.UploadFolder(ctx, "dest", c.entries, c.conflict); err == nil {
+			t.Errorf("%s: 批量上传应失败", c.name)
+		}
+	}
+
+	// 整批超出配额时同样不写入任何文件
+	setQuota(t, config.QuotaConfig{Folders: map[string]config.QuotaLimit{"dest": {MaxBytes: 5}}})
+	_, err := env.service.UploadFolder(ctx, "dest", folderEntries("a.txt", "aaa", "b.txt", "bbb"), "")
+	assertQuotaExceeded(t, err)
+	if env.exists("dest") {
+		t.Error("被拒绝的批量上传不应创建任何内容")
+	}
+}
+
+func TestUploadFolderChecksEachEntry(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := setupLockedTree(t, env)
+	setFolderPolicy(t, "shared/docs", config.UploadPolicy{BlockedExtensions: []string{".exe"}})
+
+	// 权限与上传策略逐个校验，失败的文件不影响其他文件
+	entries := folderEntries("docs/a.txt", "a", "docs/b.exe", "b", "locked/c.txt", "c")
+	results, err := env.service.UploadFolder(ctx, "shared", entries, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := []string{results[0].Status, results[1].Status, results[2].Status}
+	if strings.Join(statuses, ",") != "done,error,error" {
+		t.Errorf("结果 = %v", resultSummary(results))
+	}
+	if !env.exists("shared/docs/a.txt") || env.exists("shared/docs/b.exe") || env.exists("shared/locked/c.txt") {
+		t.Error("文件树与逐个校验的结果不符")
+	}
+}
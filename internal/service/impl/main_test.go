@@ -0,0 +1,155 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"FileNest/internal/storage"
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// TestMain 在临时目录中运行测试，日志与临时文件不会写入源码目录
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "filenest-impl-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	glog.Install()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testEnv 测试使用的文件服务及其依赖，数据库为 SQLite，Redis 为 miniredis
+type testEnv struct {
+	db      *gorm.DB
+	redis   *miniredis.Miniredis
+	store   *storage.LocalDriver
+	temp    *storage.LocalDriver
+	users   *UserServiceImpl
+	tokens  *TokenServiceImpl
+	acl     *ACLServiceImpl
+	service *FileServiceImpl
+	gc      *UploadGCServiceImpl
+	fetch   *FetchServiceImpl
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	config.Redis.Host = mr.Host()
+	config.Redis.Port, _ = strconv.Atoi(mr.Port())
+	config.Redis.Password = ""
+	config.Redis.DB = 0
+	if err := cache.InitRedis(); err != nil {
+		t.Fatalf("连接 Redis 失败: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/filenest.db"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&model.User{},
+		&model.APIToken{},
+		&model.ACLEntry{},
+		&model.UserGroup{},
+		&model.UserGroupMember{},
+		&model.FileOwner{},
+		&model.Favorite{},
+		&model.FileHash{},
+	); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	store, err := storage.NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp, err := storage.NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl := NewACLServiceImpl(db)
+	files := NewFileServiceImpl(store, temp, acl, NewQuotaServiceImpl(db), NewFavoriteServiceImpl(db), NewFileHashServiceImpl(db))
+	return &testEnv{
+		db:      db,
+		redis:   mr,
+		store:   store,
+		temp:    temp,
+		users:   NewUserServiceImpl(db),
+		tokens:  NewTokenServiceImpl(db),
+		acl:     acl,
+		service: files,
+		gc:      NewUploadGCServiceImpl(store, temp),
+		fetch:   NewFetchServiceImpl(files),
+	}
+}
+
+// createUser 创建普通用户，返回携带该用户身份的 ctx
+func (e *testEnv) createUser(t *testing.T, username string) (*model.User, context.Context) {
+	t.Helper()
+	user, err := e.users.CreateUser(username, "password1", model.RoleUser)
+	if err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user, auth.WithPrincipal(context.Background(), &auth.Principal{User: user})
+}
+
+// writeFile 直接在存储中写入文件，不经过权限与配额校验
+func (e *testEnv) writeFile(t *testing.T, p, content string) {
+	t.Helper()
+	w, err := e.store.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile 直接从存储中读取文件内容
+func (e *testEnv) readFile(t *testing.T, p string) string {
+	t.Helper()
+	f, err := e.store.Open(p)
+	if err != nil {
+		t.Fatalf("打开 %s 失败: %v", p, err)
+	}
+	defer f.Close()
+	var b strings.Builder
+	if _, err := io.Copy(&b, f); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// exists 判断存储中是否存在 p
+func (e *testEnv) exists(p string) bool {
+	_, err := e.store.Stat(p)
+	return err == nil
+}
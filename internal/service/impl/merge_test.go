@@ -0,0 +1,168 @@
+package impl
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadSessionMerge(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+
+	content := "hello, merged world"
+	session, err := env.service.CreateUploadSession(ctx, "docs", "a.txt", int64(len(content)), 8, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分块可以乱序上传
+	for _, i := range []int{2, 0, 1} {
+		end := min((i+1)*8, len(content))
+		if err := env.service.SaveSessionChunk(ctx, session.ID, i, strings.NewReader(content[i*8:end]), nil); err != nil {
+			t.Fatalf("保存分块 %d 失败: %v", i, err)
+		}
+	}
+
+	filePath, checksum, err := env.service.MergeUploadSession(ctx, session.ID, nil)
+	if err != nil {
+		t.Fatalf("合并失败: %v", err)
+	}
+	if filePath != "docs/a.txt" || checksum == nil {
+		t.Fatalf("合并结果 = %q, %v", filePath, checksum)
+	}
+	if got := env.readFile(t, "docs/a.txt"); got != content {
+		t.Fatalf("合并后内容 = %q", got)
+	}
+	entries, err := env.store.List("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("合并后目录中只应有目标文件, 实际 %d 项", len(entries))
+	}
+	if records, _ := env.temp.List(consts.MergeTempDir); len(records) != 0 {
+		t.Errorf("合并完成后应删除临时文件记录, 实际 %d 项", len(records))
+	}
+}
+
+func TestMergeTempHiddenFromListings(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	temp := "docs/" + consts.MergeTempPrefix + "abc"
+	env.writeFile(t, "docs/a.txt", "a")
+	env.writeFile(t, temp, "partial content")
+
+	files, err := env.service.GetFileList(ctx, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].FileName != "a.txt" {
+		t.Errorf("列表中不应出现合并临时文件: %+v", files)
+	}
+
+	entries, err := env.service.ReadDir(ctx, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a.txt" {
+		t.Errorf("ReadDir 不应返回合并临时文件: %v", entries)
+	}
+
+	stats, err := env.service.GetFileStats(ctx, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 1 || stats.TotalSize != 1 {
+		t.Errorf("统计不应计入合并临时文件: %+v", stats)
+	}
+
+	results, err := env.service.SearchFiles(ctx, "merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("搜索结果不应包含合并临时文件: %+v", results)
+	}
+
+	if _, err := env.service.StatFile(ctx, temp); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("StatFile 合并临时文件应返回不存在, 实际 %v", err)
+	}
+	if _, _, err := env.service.DownloadFile(ctx, temp); err == nil {
+		t.Error("不应允许下载合并临时文件")
+	}
+}
+
+func TestMergeTempPrefixReserved(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	name := consts.MergeTempPrefix + "x"
+
+	_, err := env.service.SaveFile(ctx, "", name, strings.NewReader("x"), false)
+	var policyErr *model.UploadPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Errorf("上传保留名称应被上传策略拒绝, 实际 %v", err)
+	}
+	if err := env.service.CreateFolder(ctx, name); err == nil {
+		t.Error("不应允许创建保留名称的文件夹")
+	}
+	env.writeFile(t, "a.txt", "a")
+	if err := env.service.RenameFile(ctx, "a.txt", name); err == nil {
+		t.Error("不应允许重命名为保留名称")
+	}
+}
+
+func TestGCRemovesStaleMergeTemps(t *testing.T) {
+	env := newTestEnv(t)
+	stale := "docs/" + consts.MergeTempPrefix + "stale"
+	fresh := "docs/" + consts.MergeTempPrefix + "fresh"
+	unrecorded := "docs/" + consts.MergeTempPrefix + "unrecorded"
+	env.writeFile(t, stale, "stale")
+	env.writeFile(t, fresh, "fresh")
+	env.writeFile(t, unrecorded, "unrecorded")
+	env.writeFile(t, "docs/a.txt", "a")
+	// 清理只检查合并开始时记录的临时文件
+	env.writeTemp(t, mergeTempRecord(stale), stale)
+	env.writeTemp(t, mergeTempRecord(fresh), fresh)
+	// 临时文件已不存在的过期记录
+	gone := "docs/" + consts.MergeTempPrefix + "gone"
+	env.writeTemp(t, mergeTempRecord(gone), gone)
+
+	old := time.Now().Add(-config.Upload.ChunkTTL - time.Hour)
+	for _, p := range []string{stale, unrecorded} {
+		if err := os.Chtimes(filepath.Join(env.store.Root(), filepath.FromSlash(p)), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(filepath.Join(env.temp.Root(), mergeTempRecord(gone)), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := env.gc.CollectUploadGarbage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MergeTemps != 1 || report.Bytes != int64(len("stale")) {
+		t.Errorf("清理结果 = %+v, 期望清理 1 个合并临时文件", report)
+	}
+	if env.exists(stale) {
+		t.Error("过期的合并临时文件应被删除")
+	}
+	if !env.exists(fresh) || !env.exists("docs/a.txt") {
+		t.Error("未过期的临时文件与普通文件不应被删除")
+	}
+	if !env.exists(unrecorded) {
+		t.Error("没有记录的文件不应被扫描删除")
+	}
+	records, _ := env.temp.List(consts.MergeTempDir)
+	if len(records) != 1 || records[0].Name() != "fresh" {
+		t.Errorf("清理后应只保留未过期临时文件的记录: %v", records)
+	}
+}
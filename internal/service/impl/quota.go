@@ -0,0 +1,405 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// quotaRequest 一次写入对配额的影响
+type quotaRequest struct {
+	bytes int64 // 写入的字节数，大小未知时为 0，由 quotaAllowance.limitReader 在写入过程中限制
+	files int64 // 新增的文件数
+	freed int64 // 覆盖已有文件释放的字节数
+}
+
+// quotaAllowance 配额允许写入的内容大小
+type quotaAllowance struct {
+	remaining int64 // 最多还能写入的字节数，-1 表示不限制
+	err       error // 超出 remaining 时返回的错误
+}
+
+// checkQuota 在 destPath 写入前校验当前用户与所在目录的配额
+//
+// 没有身份的内部调用只校验目录配额，管理员不受用户配额限制。
+func (s *FileServiceImpl) checkQuota(ctx context.Context, destPath string, req quotaRequest) (*quotaAllowance, error) {
+	allowance := &quotaAllowance{remaining: -1}
+
+	if user := s.quotaUser(ctx); user != nil {
+		limit := userQuotaLimit(user)
+		if !quotaUnlimited(limit) {
+			usedBytes, usedFiles, err := s.quota.usage(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := allowance.check("用户 "+user.Username, limit, usedBytes, usedFiles, req); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, folder := range folderQuotas(destPath, "") {
+		stats, err := s.folderUsage(folder)
+		if err != nil {
+			return nil, err
+		}
+		limit := config.Quota.Folders[folder]
+		if err := allowance.check(folderLabel(folder), limit, stats.TotalSize, stats.TotalFiles, req); err != nil {
+			return nil, err
+		}
+	}
+	return allowance, nil
+}
+
+// checkMoveQuota 移动前校验目标所在、源不在其中的目录配额，用户用量不受移动影响
+func (s *FileServiceImpl) checkMoveQuota(srcPath, destPath string, srcInfo fs.FileInfo) error {
+	folders := folderQuotas(destPath, srcPath)
+	if len(folders) == 0 {
+		return nil
+	}
+	req, err := s.treeQuotaRequest(srcPath, srcInfo)
+	if err != nil {
+		return err
+	}
+
+	allowance := &quotaAllowance{remaining: -1}
+	for _, folder := range folders {
+		stats, err := s.folderUsage(folder)
+		if err != nil {
+			return err
+		}
+		limit := config.Quota.Folders[folder]
+		if err := allowance.check(folderLabel(folder), limit, stats.TotalSize, stats.TotalFiles, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadQuotaRequest 上传 size 字节到 filePath 对配额的影响，覆盖已有文件时扣除其占用
+func (s *FileServiceImpl) uploadQuotaRequest(filePath string, size int64) quotaRequest {
+	req := quotaRequest{bytes: size, files: 1}
+	if info, err := s.storage.Stat(filePath); err == nil && !info.IsDir() {
+		req.files = 0
+		req.freed = info.Size()
+	}
+	return req
+}
+
+// treeQuotaRequest 复制或移动 srcPath 对配额的影响
+func (s *FileServiceImpl) treeQuotaRequest(srcPath string, srcInfo fs.FileInfo) (quotaRequest, error) {
+	if !srcInfo.IsDir() {
+		return quotaRequest{bytes: srcInfo.Size(), files: 1}, nil
+	}
+	stats, err := s.getFileStatsFromFS(srcPath)
+	if err != nil {
+		return quotaRequest{}, err
+	}
+	return quotaRequest{bytes: stats.TotalSize, files: stats.TotalFiles}, nil
+}
+
+// recordUsage 记录当前用户写入的文件，root 为文件夹时记录其中的全部文件
+func (s *FileServiceImpl) recordUsage(ctx context.Context, root string) {
+	principal := auth.FromContext(ctx)
+	if s.quota == nil || principal == nil {
+		return
+	}
+
+	files := make(map[string]int64)
+	err := s.storage.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !isMergeTemp(info.Name()) {
+			files[filepath.ToSlash(path)] = info.Size()
+		}
+		return nil
+	})
+	if err == nil {
+		err = s.quota.record(principal.User.ID, files)
+	}
+	if err != nil {
+		glog.Errorf("记录存储用量失败: %s, 路径: %s", err, root)
+	}
+}
+
+// moveUsage 文件移动或重命名后同步文件归属
+func (s *FileServiceImpl) moveUsage(oldPath, newPath string) {
+	if s.quota == nil {
+		return
+	}
+	if err := s.quota.movePath(oldPath, newPath); err != nil {
+		glog.Errorf("同步文件归属失败: %s, 路径: %s -> %s", err, oldPath, newPath)
+	}
+}
+
+// quotaInfo 当前用户与 path 所在目录的配额用量，均未配置配额时返回 nil
+func (s *FileServiceImpl) quotaInfo(ctx context.Context, path string, stats *model.FileStats) (*model.QuotaInfo, error) {
+	info := &model.QuotaInfo{}
+
+	if user := s.quotaUser(ctx); user != nil {
+		if limit := userQuotaLimit(user); !quotaUnlimited(limit) {
+			usedBytes, usedFiles, err := s.quota.usage(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			info.User = &model.QuotaUsage{
+				UsedBytes: usedBytes,
+				MaxBytes:  limit.MaxBytes,
+				UsedFiles: usedFiles,
+				MaxFiles:  limit.MaxFiles,
+			}
+		}
+	}
+
+	// 取最近的一个目录配额
+	if folders := folderQuotas(path, ""); len(folders) > 0 {
+		folder := folders[0]
+		for _, f := range folders[1:] {
+			if len(f) > len(folder) {
+				folder = f
+			}
+		}
+		usage := stats
+		if folder != path {
+			var err error
+			if usage, err = s.folderUsage(folder); err != nil {
+				return nil, err
+			}
+		}
+		limit := config.Quota.Folders[folder]
+		info.Folder = &model.QuotaUsage{
+			Path:      folder,
+			UsedBytes: usage.TotalSize,
+			MaxBytes:  limit.MaxBytes,
+			UsedFiles: usage.TotalFiles,
+			MaxFiles:  limit.MaxFiles,
+		}
+	}
+
+	if info.User == nil && info.Folder == nil {
+		return nil, nil
+	}
+	return info, nil
+}
+
+// quotaUser 需要统计用户配额的当前用户
+func (s *FileServiceImpl) quotaUser(ctx context.Context) *model.User {
+	principal := auth.FromContext(ctx)
+	if s.quota == nil || principal == nil || principal.User.IsAdmin() {
+		return nil
+	}
+	return principal.User
+}
+
+// folderUsage 目录的用量，目录不存在时用量为 0
+//
+// 用量缓存在 Redis 中，未缓存时遍历目录统计一次，之后由 trackFolderUsage 在写入、删除与移动时增量更新，
+// 校验配额不再每次遍历整个目录。
+func (s *FileServiceImpl) folderUsage(folder string) (*model.FileStats, error) {
+	key := cache.FolderUsageKey(folder)
+	if values, err := cache.HGetAll(key); err == nil && len(values) > 0 {
+		bytes, bytesErr := strconv.ParseInt(values["bytes"], 10, 64)
+		files, filesErr := strconv.ParseInt(values["files"], 10, 64)
+		if bytesErr == nil && filesErr == nil {
+			return &model.FileStats{TotalSize: bytes, TotalFiles: files}, nil
+		}
+	}
+
+	stats, err := s.pathUsage(folder)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.HSet(key, "bytes", stats.TotalSize, "files", stats.TotalFiles); err == nil {
+		cache.Expire(key, time.Duration(cache.FolderUsageExpiration)*time.Second)
+	}
+	return stats, nil
+}
+
+// trackFolderUsage 记录 path 修改前的用量，返回的函数在修改完成后将用量变化计入包含 path 的目录配额
+//
+// 只更新已缓存的目录用量，未缓存的目录在下次校验时重新统计。用法为 defer s.trackFolderUsage(path)()。
+func (s *FileServiceImpl) trackFolderUsage(path string) func() {
+	var folders []string
+	for _, folder := range folderQuotas(path, "") {
+		if exists, err := cache.Exists(cache.FolderUsageKey(folder)); err == nil && exists {
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) == 0 {
+		return func() {}
+	}
+
+	before, err := s.pathUsage(path)
+	if err != nil {
+		return func() { s.resetFolderUsage(folders) }
+	}
+	return func() {
+		after, err := s.pathUsage(path)
+		if err != nil {
+			s.resetFolderUsage(folders)
+			return
+		}
+		bytes, files := after.TotalSize-before.TotalSize, after.TotalFiles-before.TotalFiles
+		if bytes == 0 && files == 0 {
+			return
+		}
+		for _, folder := range folders {
+			if _, err := cache.HIncrByIfExists(cache.FolderUsageKey(folder), "bytes", bytes, "files", files); err != nil {
+				s.resetFolderUsage([]string{folder})
+			}
+		}
+	}
+}
+
+// resetFolderUsage 无法确定用量变化时删除目录用量缓存，下次校验时重新统计
+func (s *FileServiceImpl) resetFolderUsage(folders []string) {
+	for _, folder := range folders {
+		if err := cache.Del(cache.FolderUsageKey(folder)); err != nil {
+			glog.Errorf("重置目录用量失败: %s, 目录: %s", err, folder)
+		}
+	}
+}
+
+// pathUsage 统计 path 的用量，path 为文件时即其大小，不存在时用量为 0
+func (s *FileServiceImpl) pathUsage(path string) (*model.FileStats, error) {
+	info, err := s.storage.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &model.FileStats{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if isMergeTemp(info.Name()) {
+			return &model.FileStats{}, nil
+		}
+		return &model.FileStats{TotalSize: info.Size(), TotalFiles: 1}, nil
+	}
+	return s.getFileStatsFromFS(path)
+}
+
+// check 校验写入后是否超出 limit，并收紧允许写入的字节数
+func (a *quotaAllowance) check(scope string, limit config.QuotaLimit, usedBytes, usedFiles int64, req quotaRequest) error {
+	if limit.MaxFiles > 0 && req.files > 0 && usedFiles+req.files > limit.MaxFiles {
+		return &model.QuotaExceededError{Scope: scope, Limit: fmt.Sprintf("文件数上限 %d", limit.MaxFiles)}
+	}
+	if limit.MaxBytes <= 0 {
+		return nil
+	}
+
+	exceeded := &model.QuotaExceededError{Scope: scope, Limit: fmt.Sprintf("容量上限 %d 字节", limit.MaxBytes)}
+	remaining := limit.MaxBytes - usedBytes + req.freed
+	if remaining < 0 {
+		remaining = 0
+	}
+	if req.bytes > remaining {
+		return exceeded
+	}
+	if a.remaining < 0 || remaining < a.remaining {
+		a.remaining = remaining
+		a.err = exceeded
+	}
+	return nil
+}
+
+// limitReader 限制从 reader 读取的内容不超过配额，offset 为之前已写入的字节数
+func (a *quotaAllowance) limitReader(reader io.Reader, offset int64) io.Reader {
+	if a.remaining < 0 {
+		return reader
+	}
+	return &quotaReader{reader: reader, remaining: a.remaining - offset, err: a.err}
+}
+
+// quotaReader 读取超过 remaining 字节时返回超出配额的错误
+type quotaReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err
+	}
+	return n, err
+}
+
+// userQuotaLimit 用户的配额，优先使用按用户名配置的值
+func userQuotaLimit(user *model.User) config.QuotaLimit {
+	if limit, ok := config.Quota.Users[user.Username]; ok {
+		return limit
+	}
+	return config.Quota.User
+}
+
+// quotaUnlimited 配额是否不限制
+func quotaUnlimited(limit config.QuotaLimit) bool {
+	return limit.MaxBytes <= 0 && limit.MaxFiles <= 0
+}
+
+// folderQuotas 包含 destPath 的目录配额，跳过同时包含 excludePath 的目录
+func folderQuotas(destPath, excludePath string) []string {
+	var folders []string
+	for folder, limit := range config.Quota.Folders {
+		if quotaUnlimited(limit) {
+			continue
+		}
+		cleaned, err := sandbox.Clean(folder)
+		if err != nil || cleaned != folder {
+			glog.Warnf("忽略无效的目录配额: %q", folder)
+			continue
+		}
+		if !inFolder(folder, destPath) || (excludePath != "" && inFolder(folder, excludePath)) {
+			continue
+		}
+		folders = append(folders, folder)
+	}
+	return folders
+}
+
+// inFolder 判断 path 是否位于 folder 中，folder 为空表示存储根目录
+func inFolder(folder, path string) bool {
+	return folder == "" || isSubPath(folder, path)
+}
+
+// folderLabel 目录配额在错误信息中的名称
+func folderLabel(folder string) string {
+	if folder == "" {
+		return "存储"
+	}
+	return "目录 " + folder
+}
+
+// readerSize 获取可 Seek 的内容剩余大小，未知时返回 0
+func readerSize(reader io.Reader) int64 {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return 0
+	}
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0
+	}
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0
+	}
+	return end - current
+}
@@ -0,0 +1,92 @@
+package impl
+
+import (
+	"FileNest/internal/model"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuotaServiceImpl 记录文件归属，统计用户的存储用量
+type QuotaServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewQuotaServiceImpl 创建配额服务
+func NewQuotaServiceImpl(db *gorm.DB) *QuotaServiceImpl {
+	return &QuotaServiceImpl{db: db}
+}
+
+// usage 统计用户写入的文件总大小与文件数
+func (s *QuotaServiceImpl) usage(userID uint) (int64, int64, error) {
+	var result struct {
+		Bytes int64
+		Files int64
+	}
+	err := s.db.Model(&model.FileOwner{}).
+		Select("COALESCE(SUM(size), 0) AS bytes, COUNT(*) AS files").
+		Where("user_id = ?", userID).
+		Scan(&result).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("统计存储用量失败: %w", err)
+	}
+	return result.Bytes, result.Files, nil
+}
+
+// record 记录用户写入的文件，files 为文件路径到大小的映射；覆盖已有文件时归属转移给该用户
+func (s *QuotaServiceImpl) record(userID uint, files map[string]int64) error {
+	if len(files) == 0 {
+		return nil
+	}
+	owners := make([]model.FileOwner, 0, len(files))
+	for filePath, size := range files {
+		owners = append(owners, model.FileOwner{Path: filePath, UserID: userID, Size: size})
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "size", "updated_at"}),
+	}).CreateInBatches(owners, 100).Error
+}
+
+// movePath 文件或文件夹移动、重命名后，同步其自身及子路径的归属记录
+func (s *QuotaServiceImpl) movePath(oldPath, newPath string) error {
+	owners, err := s.subtreeOwners(oldPath)
+	if err != nil || len(owners) == 0 {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, owner := range owners {
+			moved := newPath + strings.TrimPrefix(owner.Path, oldPath)
+			if err := tx.Model(&model.FileOwner{}).Where("id = ?", owner.ID).Update("path", moved).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removePath 文件或文件夹删除后，释放其自身及子路径占用的配额
+func (s *QuotaServiceImpl) removePath(filePath string) error {
+	query := s.db.Unscoped()
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	} else {
+		query = query.Where("1 = 1")
+	}
+	return query.Delete(&model.FileOwner{}).Error
+}
+
+// subtreeOwners 查询路径自身及其子路径的归属记录
+func (s *QuotaServiceImpl) subtreeOwners(filePath string) ([]model.FileOwner, error) {
+	var owners []model.FileOwner
+	query := s.db.Model(&model.FileOwner{})
+	if filePath != "" {
+		query = query.Where("path = ? OR path LIKE ? ESCAPE '!'", filePath, escapeLike(filePath)+"/%")
+	}
+	if err := query.Find(&owners).Error; err != nil {
+		return nil, fmt.Errorf("查询文件归属失败: %w", err)
+	}
+	return owners, nil
+}
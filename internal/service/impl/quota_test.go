@@ -0,0 +1,180 @@
+package impl
+
+import (
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// setQuota 替换配额配置，测试结束后恢复
+func setQuota(t *testing.T, quota config.QuotaConfig) {
+	t.Helper()
+	saved := *config.Quota
+	*config.Quota = quota
+	t.Cleanup(func() { *config.Quota = saved })
+}
+
+// folderCounter 读取缓存中的目录用量，未缓存时 ok 为 false
+func folderCounter(t *testing.T, folder string) (bytes, files string, ok bool) {
+	t.Helper()
+	values, err := cache.HGetAll(cache.FolderUsageKey(folder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values["bytes"], values["files"], len(values) > 0
+}
+
+func assertQuotaExceeded(t *testing.T, err error) {
+	t.Helper()
+	var quotaErr *model.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("期望超出配额的错误, 实际为 %v", err)
+	}
+}
+
+func TestFolderQuota(t *testing.T) {
+	env := newTestEnv(t)
+	setQuota(t, config.QuotaConfig{Folders: map[string]config.QuotaLimit{"docs": {MaxBytes: 10, MaxFiles: 2}}})
+	ctx := context.Background()
+
+	if _, err := env.service.SaveFile(ctx, "docs", "a.txt", strings.NewReader("123456"), false); err != nil {
+		t.Fatal(err)
+	}
+	_, err := env.service.SaveFile(ctx, "docs", "b.txt", strings.NewReader("12345"), false)
+	assertQuotaExceeded(t, err)
+	if env.exists("docs/b.txt") {
+		t.Error("超出配额的文件不应保留")
+	}
+	if _, err := env.service.SaveFile(ctx, "other", "b.txt", strings.NewReader("12345"), false); err != nil {
+		t.Errorf("配额目录之外的写入不受限制: %v", err)
+	}
+
+	// 覆盖已有文件时扣除其占用
+	if _, err := env.service.SaveFile(ctx, "docs", "a.txt", strings.NewReader("1234567890"), true); err != nil {
+		t.Errorf("覆盖后未超出配额: %v", err)
+	}
+
+	if err := env.service.DeleteFile(ctx, "docs/a.txt", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.service.SaveFile(ctx, "docs", "b.txt", strings.NewReader("12345"), false); err != nil {
+		t.Errorf("删除后应释放配额: %v", err)
+	}
+}
+
+func TestFolderUsageUpdatedIncrementally(t *testing.T) {
+	env := newTestEnv(t)
+	setQuota(t, config.QuotaConfig{Folders: map[string]config.QuotaLimit{"docs": {MaxBytes: 100}}})
+	ctx := context.Background()
+
+	if _, err := env.service.SaveFile(ctx, "docs", "a.txt", strings.NewReader("123456"), false); err != nil {
+		t.Fatal(err)
+	}
+	if bytes, files, ok := folderCounter(t, "docs"); !ok || bytes != "6" || files != "1" {
+		t.Fatalf("写入后目录用量 = %s 字节 %s 个文件 (缓存: %v)", bytes, files, ok)
+	}
+
+	// 绕过服务写入的内容不会被统计，说明校验使用的是缓存的用量而不是重新遍历目录
+	env.writeFile(t, "docs/untracked.txt", strings.Repeat("x", 90))
+	if _, err := env.service.SaveFile(ctx, "docs", "b.txt", strings.NewReader("1234"), false); err != nil {
+		t.Fatalf("应按缓存的用量校验: %v", err)
+	}
+	if bytes, files, _ := folderCounter(t, "docs"); bytes != "10" || files != "2" {
+		t.Fatalf("第二次写入后目录用量 = %s 字节 %s 个文件", bytes, files)
+	}
+
+	if err := env.service.CopyFile(ctx, "docs/a.txt", "docs/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes, files, _ := folderCounter(t, "docs"); bytes != "16" || files != "3" {
+		t.Fatalf("复制后目录用量 = %s 字节 %s 个文件", bytes, files)
+	}
+
+	if err := env.service.MoveFile(ctx, "docs/c.txt", "other/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.RenameFile(ctx, "docs/a.txt", "renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes, files, _ := folderCounter(t, "docs"); bytes != "10" || files != "2" {
+		t.Fatalf("移出与重命名后目录用量 = %s 字节 %s 个文件", bytes, files)
+	}
+
+	if err := env.service.DeleteFile(ctx, "docs/renamed.txt", false); err != nil {
+		t.Fatal(err)
+	}
+	if bytes, files, _ := folderCounter(t, "docs"); bytes != "4" || files != "1" {
+		t.Fatalf("删除后目录用量 = %s 字节 %s 个文件", bytes, files)
+	}
+
+	// 缓存过期后重新统计，包括绕过服务写入的内容
+	cache.Del(cache.FolderUsageKey("docs"))
+	_, err := env.service.SaveFile(ctx, "docs", "d.txt", strings.NewReader("123456789"), false)
+	assertQuotaExceeded(t, err)
+	if bytes, files, _ := folderCounter(t, "docs"); bytes != "94" || files != "2" {
+		t.Fatalf("重新统计后目录用量 = %s 字节 %s 个文件", bytes, files)
+	}
+}
+
+func TestUserQuota(t *testing.T) {
+	env := newTestEnv(t)
+	setQuota(t, config.QuotaConfig{User: config.QuotaLimit{MaxBytes: 10}})
+	_, ctx := env.createUser(t, "alice")
+	_, otherCtx := env.createUser(t, "bob")
+
+	if _, err := env.service.SaveFile(ctx, "", "a.txt", strings.NewReader("123456"), false); err != nil {
+		t.Fatal(err)
+	}
+	_, err := env.service.SaveFile(ctx, "", "b.txt", strings.NewReader("12345"), false)
+	assertQuotaExceeded(t, err)
+	if _, err := env.service.SaveFile(otherCtx, "", "b.txt", strings.NewReader("12345"), false); err != nil {
+		t.Errorf("配额按用户统计: %v", err)
+	}
+
+	// 大小未知的内容在写入过程中限制
+	_, err = env.service.SaveFile(ctx, "", "c.txt", io.MultiReader(strings.NewReader("12345")), false)
+	assertQuotaExceeded(t, err)
+	if env.exists("c.txt") {
+		t.Error("超出配额的部分内容不应保留")
+	}
+}
+
+func TestCheckUploadLimit(t *testing.T) {
+	env := newTestEnv(t)
+	setQuota(t, config.QuotaConfig{Folders: map[string]config.QuotaLimit{"docs": {MaxBytes: 10}}})
+	setFolderPolicy(t, "docs/small", config.UploadPolicy{MaxFileSize: 3})
+	env.writeFile(t, "docs/a.txt", "123456")
+	ctx := context.Background()
+
+	// 覆盖已有文件时其占用计入允许的大小
+	limit, err := env.service.CheckUploadLimit(ctx, "docs", "a.txt", true)
+	if err != nil || limit.MaxBytes != 10 {
+		t.Fatalf("覆盖 a.txt 的上限: %+v, %v", limit, err)
+	}
+	if limit, err = env.service.CheckUploadLimit(ctx, "docs", "b.txt", false); err != nil || limit.MaxBytes != 4 {
+		t.Fatalf("新文件的上限: %+v, %v", limit, err)
+	}
+	assertQuotaExceeded(t, limit.Check(5))
+	if err := limit.Check(4); err != nil {
+		t.Errorf("未超出上限: %v", err)
+	}
+
+	// 上传策略的大小上限更小时以其为准
+	limit, err = env.service.CheckUploadLimit(ctx, "docs/small", "c.txt", false)
+	if err != nil || limit.MaxBytes != 3 {
+		t.Fatalf("策略目录的上限: %+v, %v", limit, err)
+	}
+	assertPolicyRejected(t, limit.Check(4), "超出策略大小上限")
+
+	if limit, err = env.service.CheckUploadLimit(ctx, "other", "d.txt", false); err != nil || limit.MaxBytes != -1 {
+		t.Fatalf("不限制时: %+v, %v", limit, err)
+	}
+	if _, err := env.service.CheckUploadLimit(ctx, "docs", "a.txt", false); err == nil {
+		t.Error("不覆盖时同名文件应被拒绝")
+	}
+}
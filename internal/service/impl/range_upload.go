@@ -0,0 +1,146 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+)
+
+// SaveFileRange 保存按 Content-Range 上传的一段内容
+//
+// 已接收的内容按用户与目标路径存放在临时目录中，每段内容保存为一个分块，连接中断时已收到的部分也会保留，
+// 客户端可以从返回的 Received 处继续上传。offset 为 0 时丢弃之前未完成的内容重新开始。
+// 接收完整后按合并分块的方式原子地保存为文件。总大小未知（size 为 -1）时每一段同样按剩余的用户与目录配额
+// 及上传策略的大小上限限制，超出时丢弃该段；全部上传后以 reader 为 nil、size 为总大小的请求完成保存。
+func (s *FileServiceImpl) SaveFileRange(ctx context.Context, filePath string, offset, size int64, reader io.Reader, override bool) (*model.RangeUpload, error) {
+	filePath, err := sandbox.Clean(filePath)
+	if err != nil {
+		return nil, err
+	}
+	dir, fileName := path.Split(filePath)
+	if fileName == "" {
+		return nil, fmt.Errorf("文件名不能为空")
+	}
+	dir = path.Clean(dir)
+	if dir == "." {
+		dir = ""
+	}
+	if size < -1 || offset < 0 || (size >= 0 && offset > size) {
+		return nil, fmt.Errorf("上传范围不合法: %d/%d", offset, size)
+	}
+	// 文件大小未知时按已声明的偏移量校验，之后的内容在写入过程中按剩余配额限制
+	allowance, err := s.checkUpload(ctx, dir, fileName, max(size, offset), override)
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir := rangeDir(ctx, filePath)
+	chunks := s.listChunkDir(tempDir)
+	state := &model.RangeUpload{Path: filePath, Size: size}
+	for _, chunk := range chunks {
+		state.Received += chunk.Size
+	}
+	if reader == nil {
+		// 总大小未知时上传的内容，由之后声明了总大小的查询请求完成保存
+		if size > 0 && state.Received == size {
+			return s.mergeRange(ctx, state, tempDir, dir, fileName, override)
+		}
+		return state, nil
+	}
+
+	if offset == 0 && len(chunks) > 0 {
+		// 从头开始时丢弃之前未完成的内容
+		if err := s.temp.RemoveAll(tempDir); err != nil {
+			return nil, fmt.Errorf("清理未完成的上传失败: %s", err)
+		}
+		chunks, state.Received = nil, 0
+	}
+	if offset != state.Received {
+		return nil, &model.RangeMismatchError{Offset: offset, Received: state.Received}
+	}
+	index := len(chunks)
+	if err := checkUploadPolicy(filePath, max(size, offset), index+1); err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		// 第一段包含文件开头，在保存前识别文件类型
+		var head []byte
+		if head, reader, err = peekHead(reader); err != nil {
+			return nil, fmt.Errorf("读取上传内容失败: %s", err)
+		}
+		if err := checkUploadType(filePath, head); err != nil {
+			return nil, err
+		}
+	}
+	reader = limitUploadSize(filePath, allowance.limitReader(reader, offset), offset)
+	if size >= 0 {
+		// 多读一个字节以发现超出文件大小的内容
+		reader = io.LimitReader(reader, size-offset+1)
+	}
+
+	chunkPath := filepath.Join(tempDir, chunkName(index))
+	writer, err := s.temp.Create(chunkPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建分块文件失败: %s", err)
+	}
+	written, err := io.Copy(writer, reader)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size >= 0 && offset+written > size {
+		err = fmt.Errorf("上传内容超出文件大小 %d 字节", size)
+		written = 0
+	}
+	var quotaErr *model.QuotaExceededError
+	var policyErr *model.UploadPolicyError
+	if errors.As(err, &quotaErr) || errors.As(err, &policyErr) {
+		// 不保留超出配额或上传策略限制的这一段内容
+		written = 0
+	}
+	if written == 0 {
+		s.temp.Remove(chunkPath)
+	}
+	state.Received += written
+	if quotaErr != nil || policyErr != nil {
+		return state, err
+	}
+	if err != nil {
+		// 已收到的部分保留，客户端从 Received 处继续上传
+		return state, fmt.Errorf("保存上传内容失败: %s", err)
+	}
+	if size < 0 || state.Received < size {
+		return state, nil
+	}
+	return s.mergeRange(ctx, state, tempDir, dir, fileName, override)
+}
+
+// mergeRange 已接收完整的内容合并为文件
+func (s *FileServiceImpl) mergeRange(ctx context.Context, state *model.RangeUpload, tempDir, dir, fileName string, override bool) (*model.RangeUpload, error) {
+	savedPath, checksum, err := s.mergeChunkDir(ctx, tempDir, dir, fileName, len(s.listChunkDir(tempDir)), override, state.Size, nil, "")
+	if err != nil {
+		return state, err
+	}
+	state.Path, state.Checksum, state.Completed = savedPath, checksum, true
+	glog.Infof("分段上传完成: %s, 大小: %d", savedPath, state.Size)
+	return state, nil
+}
+
+// rangeDir 分段上传的内容在临时存储中的目录，同一用户上传到同一路径时使用同一目录
+func rangeDir(ctx context.Context, filePath string) string {
+	var userID uint
+	if principal := auth.FromContext(ctx); principal != nil {
+		userID = principal.User.ID
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", userID, filePath)))
+	return filepath.Join(consts.RangeUploadDir, hex.EncodeToString(sum[:16]))
+}
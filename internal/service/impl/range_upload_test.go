@@ -0,0 +1,108 @@
+package impl
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSaveFileRange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	state, err := env.service.SaveFileRange(ctx, "docs/a.txt", 0, 11, strings.NewReader("hello "), false)
+	if err != nil || state.Received != 6 || state.Completed {
+		t.Fatalf("第一段: %+v, %v", state, err)
+	}
+
+	var mismatch *model.RangeMismatchError
+	if _, err := env.service.SaveFileRange(ctx, "docs/a.txt", 3, 11, strings.NewReader("xx"), false); !errors.As(err, &mismatch) || mismatch.Received != 6 {
+		t.Fatalf("偏移量不连续时应返回已接收的大小, 实际为 %v", err)
+	}
+
+	state, err = env.service.SaveFileRange(ctx, "docs/a.txt", 6, 11, strings.NewReader("world"), false)
+	if err != nil || !state.Completed {
+		t.Fatalf("第二段: %+v, %v", state, err)
+	}
+	if got := env.readFile(t, "docs/a.txt"); got != "hello world" {
+		t.Errorf("保存的内容为 %q", got)
+	}
+}
+
+func TestSaveFileRangeUnknownSizeCappedByQuota(t *testing.T) {
+	env := newTestEnv(t)
+	setQuota(t, config.QuotaConfig{User: config.QuotaLimit{MaxBytes: 10}})
+	_, ctx := env.createUser(t, "alice")
+
+	state, err := env.service.SaveFileRange(ctx, "a.txt", 0, -1, strings.NewReader("123456"), false)
+	if err != nil || state.Received != 6 {
+		t.Fatalf("第一段: %+v, %v", state, err)
+	}
+
+	state, err = env.service.SaveFileRange(ctx, "a.txt", 6, -1, strings.NewReader("12345"), false)
+	assertQuotaExceeded(t, err)
+	if state == nil || state.Received != 6 {
+		t.Fatalf("超出配额的一段不应保留: %+v", state)
+	}
+
+	state, err = env.service.SaveFileRange(ctx, "a.txt", 6, 10, strings.NewReader("7890"), false)
+	if err != nil || !state.Completed {
+		t.Fatalf("未超出配额的内容应能完成上传: %+v, %v", state, err)
+	}
+	if got := env.readFile(t, "a.txt"); got != "1234567890" {
+		t.Errorf("保存的内容为 %q", got)
+	}
+}
+
+func TestSaveFileRangeUnknownSizeCappedByPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	saved := config.Upload.Policy
+	config.Upload.Policy = config.UploadPolicy{MaxFileSize: 8}
+	t.Cleanup(func() { config.Upload.Policy = saved })
+	ctx := context.Background()
+
+	if _, err := env.service.SaveFileRange(ctx, "a.txt", 0, -1, strings.NewReader("123456"), false); err != nil {
+		t.Fatal(err)
+	}
+	state, err := env.service.SaveFileRange(ctx, "a.txt", 6, -1, strings.NewReader("789"), false)
+	var policyErr *model.UploadPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("期望不符合上传策略的错误, 实际为 %v", err)
+	}
+	if state == nil || state.Received != 6 {
+		t.Fatalf("超出大小上限的一段不应保留: %+v", state)
+	}
+}
+
+func TestSaveFileRangeFinalizeUnknownSize(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.service.SaveFileRange(ctx, "docs/a.txt", 0, -1, strings.NewReader("hello "), false); err != nil {
+		t.Fatal(err)
+	}
+	state, err := env.service.SaveFileRange(ctx, "docs/a.txt", 6, -1, strings.NewReader("world"), false)
+	if err != nil || state.Received != 11 || state.Completed {
+		t.Fatalf("总大小未知时不应保存: %+v, %v", state, err)
+	}
+
+	// 声明的总大小与已接收的不一致时只返回进度
+	state, err = env.service.SaveFileRange(ctx, "docs/a.txt", 0, 12, nil, false)
+	if err != nil || state.Received != 11 || state.Completed || env.exists("docs/a.txt") {
+		t.Fatalf("总大小不一致时: %+v, %v", state, err)
+	}
+
+	state, err = env.service.SaveFileRange(ctx, "docs/a.txt", 0, 11, nil, false)
+	if err != nil || !state.Completed || state.Checksum == nil {
+		t.Fatalf("声明总大小后应保存: %+v, %v", state, err)
+	}
+	if got := env.readFile(t, "docs/a.txt"); got != "hello world" {
+		t.Errorf("保存的内容为 %q", got)
+	}
+	if _, err := env.temp.Stat(rangeDir(ctx, "docs/a.txt")); err == nil {
+		t.Error("保存后临时内容未删除")
+	}
+}
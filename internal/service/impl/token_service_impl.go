@@ -0,0 +1,138 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lastUsedInterval 最近使用时间的更新间隔，避免每次请求都写数据库
+const lastUsedInterval = time.Minute
+
+var errInvalidAPIToken = errors.New("API 令牌无效或已过期")
+
+type TokenServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewTokenServiceImpl 创建 API 令牌服务
+func NewTokenServiceImpl(db *gorm.DB) *TokenServiceImpl {
+	return &TokenServiceImpl{db: db}
+}
+
+// CreateToken 创建 API 令牌，明文令牌只在创建时返回一次
+func (s *TokenServiceImpl) CreateToken(userID uint, name string, scopes []string, pathPrefix string, expiresIn time.Duration) (string, *model.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return "", nil, errors.New("令牌名称不能为空且长度不超过 64")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("至少需要一个权限范围")
+	}
+	for _, scope := range scopes {
+		if scope != model.ScopeRead && scope != model.ScopeUpload && scope != model.ScopeWrite {
+			return "", nil, fmt.Errorf("未知的权限范围: %s", scope)
+		}
+	}
+	pathPrefix, err := sandbox.Clean(pathPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	if expiresIn < 0 {
+		return "", nil, errors.New("有效期不能为负数")
+	}
+
+	plain := model.APITokenPrefix + randomToken(32)
+	token := &model.APIToken{
+		UserID:      userID,
+		Name:        name,
+		TokenHash:   hashAPIToken(plain),
+		TokenPrefix: plain[:len(model.APITokenPrefix)+8],
+		Scopes:      strings.Join(scopes, ","),
+		PathPrefix:  pathPrefix,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.db.Create(token).Error; err != nil {
+		return "", nil, fmt.Errorf("创建 API 令牌失败: %w", err)
+	}
+
+	glog.Infof("创建 API 令牌成功, 用户 ID: %d, 名称: %s, 权限: %s, 目录: /%s", userID, name, token.Scopes, pathPrefix)
+	return plain, token, nil
+}
+
+// ListTokens 获取用户的 API 令牌列表
+func (s *TokenServiceImpl) ListTokens(userID uint) ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("查询 API 令牌失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken 吊销 API 令牌
+func (s *TokenServiceImpl) RevokeToken(userID, tokenID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&model.APIToken{})
+	if result.Error != nil {
+		return fmt.Errorf("吊销 API 令牌失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("API 令牌不存在: %d", tokenID)
+	}
+	glog.Infof("吊销 API 令牌成功, 用户 ID: %d, 令牌 ID: %d", userID, tokenID)
+	return nil
+}
+
+// Authenticate 校验 API 令牌，返回令牌所属用户
+func (s *TokenServiceImpl) Authenticate(plain string) (*model.User, *model.APIToken, error) {
+	if !strings.HasPrefix(plain, model.APITokenPrefix) {
+		return nil, nil, errInvalidAPIToken
+	}
+
+	var token model.APIToken
+	if err := s.db.Where("token_hash = ?", hashAPIToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("查询 API 令牌失败: %w", err)
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, errInvalidAPIToken
+	}
+
+	var user model.User
+	if err := s.db.First(&user, token.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if user.Disabled {
+		return nil, nil, fmt.Errorf("用户已被禁用: %s", user.Username)
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedInterval {
+		if err := s.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			glog.Warnf("更新 API 令牌使用时间失败: %s", err)
+		}
+		token.LastUsedAt = &now
+	}
+	return &user, &token, nil
+}
+
+// hashAPIToken 数据库中只保存令牌的哈希
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,162 @@
+package impl
+
+import (
+	"FileNest/internal/auth"
+	"FileNest/internal/model"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenContext 为 user 创建 API 令牌，返回以该令牌访问的 ctx
+func (e *testEnv) tokenContext(t *testing.T, user *model.User, scopes []string, pathPrefix string) context.Context {
+	t.Helper()
+	plain, _, err := e.tokens.CreateToken(user.ID, "ci", scopes, pathPrefix, 0)
+	if err != nil {
+		t.Fatalf("创建 API 令牌失败: %v", err)
+	}
+	tokenUser, token, err := e.tokens.Authenticate(plain)
+	if err != nil {
+		t.Fatalf("API 令牌认证失败: %v", err)
+	}
+	return auth.WithPrincipal(context.Background(), &auth.Principal{User: tokenUser, Token: token})
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	env := newTestEnv(t)
+	alice, _ := env.createUser(t, "alice")
+
+	plain, token, err := env.tokens.CreateToken(alice.ID, "ci", []string{model.ScopeRead}, "builds/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.PathPrefix != "builds" || !strings.HasPrefix(plain, token.TokenPrefix) || token.TokenHash == plain {
+		t.Fatalf("令牌信息: %+v", token)
+	}
+
+	user, authed, err := env.tokens.Authenticate(plain)
+	if err != nil || user.ID != alice.ID || authed.ID != token.ID {
+		t.Fatalf("认证结果: %+v, %+v, %v", user, authed, err)
+	}
+	tokens, err := env.tokens.ListTokens(alice.ID)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("认证后应记录最近使用时间: %+v, %v", tokens, err)
+	}
+
+	if _, _, err := env.tokens.Authenticate(plain + "x"); err == nil {
+		t.Error("错误的令牌不应通过认证")
+	}
+	if err := env.tokens.RevokeToken(alice.ID+1, token.ID); err == nil {
+		t.Error("不应能吊销其他用户的令牌")
+	}
+	if err := env.tokens.RevokeToken(alice.ID, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.tokens.Authenticate(plain); err == nil {
+		t.Error("吊销后的令牌不应通过认证")
+	}
+
+	expired, _, err := env.tokens.CreateToken(alice.ID, "old", []string{model.ScopeRead}, "", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := env.tokens.Authenticate(expired); err == nil {
+		t.Error("过期的令牌不应通过认证")
+	}
+}
+
+func TestCreateTokenRejectsInvalidRequest(t *testing.T) {
+	env := newTestEnv(t)
+	alice, _ := env.createUser(t, "alice")
+
+	cases := []struct {
+		name       string
+		scopes     []string
+		pathPrefix string
+	}{
+		{"", []string{model.ScopeRead}, ""},
+		{"ci", nil, ""},
+		{"ci", []string{"admin"}, ""},
+		{"ci", []string{model.ScopeRead}, "../etc"},
+	}
+	for _, c := range cases {
+		if _, _, err := env.tokens.CreateToken(alice.ID, c.name, c.scopes, c.pathPrefix, 0); err == nil {
+			t.Errorf("创建令牌 %q %v %q 应失败", c.name, c.scopes, c.pathPrefix)
+		}
+	}
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	env := newTestEnv(t)
+	alice, _ := env.createUser(t, "alice")
+	env.writeFile(t, "docs/a.txt", "a")
+
+	// 只读令牌可以列表与下载，不能上传或删除
+	readCtx := env.tokenContext(t, alice, []string{model.ScopeRead}, "")
+	if _, err := env.service.GetFileList(readCtx, "docs"); err != nil {
+		t.Errorf("只读令牌列表失败: %v", err)
+	}
+	file, _, err := env.service.DownloadFile(readCtx, "docs/a.txt")
+	if err != nil {
+		t.Errorf("只读令牌下载失败: %v", err)
+	} else {
+		file.Close()
+	}
+	_, err = env.service.SaveFile(readCtx, "docs", "b.txt", strings.NewReader("b"), false)
+	assertForbidden(t, err, "docs/b.txt")
+	assertForbidden(t, env.service.DeleteFile(readCtx, "docs/a.txt", false), "docs/a.txt")
+
+	// 上传令牌可以上传，不能列表、删除或移动
+	uploadCtx := env.tokenContext(t, alice, []string{model.ScopeUpload}, "")
+	if _, err := env.service.SaveFile(uploadCtx, "docs", "b.txt", strings.NewReader("b"), false); err != nil {
+		t.Errorf("上传令牌上传失败: %v", err)
+	}
+	_, err = env.service.GetFileList(uploadCtx, "docs")
+	assertForbidden(t, err, "docs")
+	assertForbidden(t, env.service.DeleteFile(uploadCtx, "docs/a.txt", false), "docs/a.txt")
+	if err := env.service.MoveFile(uploadCtx, "docs/a.txt", "other"); err == nil {
+		t.Error("上传令牌不应能移动文件")
+	}
+
+	// 写入令牌包含上传权限，并允许删除
+	writeCtx := env.tokenContext(t, alice, []string{model.ScopeWrite}, "")
+	if _, err := env.service.SaveFile(writeCtx, "docs", "c.txt", strings.NewReader("c"), false); err != nil {
+		t.Errorf("写入令牌上传失败: %v", err)
+	}
+	if err := env.service.DeleteFile(writeCtx, "docs/c.txt", false); err != nil {
+		t.Errorf("写入令牌删除失败: %v", err)
+	}
+	if !env.exists("docs/a.txt") || !env.exists("docs/b.txt") || env.exists("docs/c.txt") {
+		t.Error("文件树与令牌权限不符")
+	}
+}
+
+func TestAPITokenPathPrefix(t *testing.T) {
+	env := newTestEnv(t)
+	alice, _ := env.createUser(t, "alice")
+	env.writeFile(t, "builds/app.tar", "app")
+	env.writeFile(t, "builds-old/app.tar", "old")
+	env.writeFile(t, "docs/a.txt", "a")
+	ctx := env.tokenContext(t, alice, []string{model.ScopeRead, model.ScopeUpload}, "builds")
+
+	if _, err := env.service.SaveFile(ctx, "builds/v2", "app.tar", strings.NewReader("v2"), false); err != nil {
+		t.Errorf("在限制目录内上传失败: %v", err)
+	}
+	_, err := env.service.SaveFile(ctx, "docs", "b.txt", strings.NewReader("b"), false)
+	assertForbidden(t, err, "docs/b.txt")
+	_, err = env.service.GetFileList(ctx, "builds-old")
+	assertForbidden(t, err, "builds-old")
+	if _, _, err := env.service.DownloadFile(ctx, "docs/a.txt"); err == nil {
+		t.Error("不应能下载限制目录外的文件")
+	}
+
+	// 限制目录之外（包括根目录）都不能访问
+	_, err = env.service.GetFileList(ctx, "")
+	assertForbidden(t, err, "")
+	files, err := env.service.GetFileList(ctx, "builds")
+	if err != nil || len(files) != 2 {
+		t.Errorf("限制目录列表 = %+v, %v", files, err)
+	}
+}
@@ -0,0 +1,115 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/cache"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// SubscribeUploadEvents 订阅上传会话 sessionID 的进度事件
+//
+// 返回的通道先推送一次当前状态，之后推送 Redis 中发布的事件，任一实例处理的上传都能收到；
+// 推送完成或失败事件、或 ctx 结束后通道关闭。
+func (s *FileServiceImpl) SubscribeUploadEvents(ctx context.Context, sessionID string) (<-chan *model.UploadEvent, error) {
+	if _, err := s.loadSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	key := cache.UploadSessionKey(sessionID)
+	// 当前状态取自会话中随分块更新的进度
+	snapshot := func() (*model.UploadEvent, error) {
+		session, err := s.loadSession(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		event := &model.UploadEvent{
+			Status:         session.Status,
+			Progress:       session.Progress,
+			ReceivedChunks: session.ReceivedChunks,
+			TotalChunks:    session.TotalChunks,
+			ReceivedSize:   session.ReceivedSize,
+			Path:           session.FilePath,
+			Error:          session.Error,
+		}
+		if session.Status == consts.UploadStatusDone {
+			event.Checksum = session.Checksum
+		}
+		return event, nil
+	}
+
+	// 先订阅再读取当前状态，避免漏掉两者之间发布的事件
+	pubsub, err := cache.Subscribe(ctx, cache.UploadEventChannel(key))
+	if err != nil {
+		return nil, err
+	}
+	first, err := snapshot()
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	switch first.Status {
+	case consts.UploadStatusDone:
+		first.Type = consts.UploadEventCompleted
+	case consts.UploadStatusError:
+		first.Type = consts.UploadEventError
+	default:
+		first.Type = consts.UploadEventStatus
+	}
+	first.Time = time.Now()
+
+	events := make(chan *model.UploadEvent, 16)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		send := func(event *model.UploadEvent) bool {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return false
+			}
+			return event.Type != consts.UploadEventCompleted && event.Type != consts.UploadEventError
+		}
+		if !send(first) {
+			return
+		}
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event model.UploadEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					glog.Warnf("解析上传事件失败: %s, 频道: %s", err, message.Channel)
+					continue
+				}
+				if !send(&event) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// publishUploadEvent 向订阅 key 对应上传的客户端发布事件，key 为空时不发布
+func publishUploadEvent(key string, event *model.UploadEvent) {
+	if key == "" {
+		return
+	}
+	event.Time = time.Now()
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = cache.Publish(cache.UploadEventChannel(key), data)
+	}
+	if err != nil {
+		glog.Warnf("发布上传事件失败: %s, 上传: %s", err, key)
+	}
+}
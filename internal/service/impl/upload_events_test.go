@@ -0,0 +1,127 @@
+package impl
+
+import (
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextEvent 读取下一个事件，通道关闭时返回 nil
+func nextEvent(t *testing.T, events <-chan *model.UploadEvent) *model.UploadEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("未在限定时间内收到事件")
+		return nil
+	}
+}
+
+func TestUploadEvents(t *testing.T) {
+	env := newTestEnv(t)
+	_, ctx := env.createUser(t, "alice")
+	_, otherCtx := env.createUser(t, "bob")
+	session, err := env.service.CreateUploadSession(ctx, "docs", "a.txt", 8, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.service.SubscribeUploadEvents(otherCtx, session.ID); err == nil {
+		t.Fatal("其他用户不应能订阅上传事件")
+	}
+
+	// 多个订阅者同时收到事件
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var subscribers []<-chan *model.UploadEvent
+	for i := 0; i < 2; i++ {
+		events, err := env.service.SubscribeUploadEvents(subCtx, session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first := nextEvent(t, events); first.Type != consts.UploadEventStatus || first.ReceivedChunks != 0 {
+			t.Fatalf("初始事件 = %+v", first)
+		}
+		subscribers = append(subscribers, events)
+	}
+
+	for i, chunk := range []string{"1234", "5678"} {
+		if err := env.service.SaveSessionChunk(ctx, session.ID, i, strings.NewReader(chunk), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := env.service.MergeUploadSession(ctx, session.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, events := range subscribers {
+		var types []string
+		var last *model.UploadEvent
+		// 推送完成事件后通道关闭
+		for event := nextEvent(t, events); event != nil; event = nextEvent(t, events) {
+			types = append(types, event.Type)
+			last = event
+			if event.Type == consts.UploadEventChunkReceived && (event.Chunk == nil || event.TotalChunks != 2) {
+				t.Errorf("分块事件 = %+v", event)
+			}
+		}
+		want := []string{
+			consts.UploadEventChunkReceived, consts.UploadEventChunkReceived,
+			consts.UploadEventMergeProgress, consts.UploadEventMergeProgress,
+			consts.UploadEventCompleted,
+		}
+		if strings.Join(types, ",") != strings.Join(want, ",") {
+			t.Errorf("事件顺序 = %v, 期望 %v", types, want)
+		}
+		if last.Path != "docs/a.txt" || last.Checksum == nil || last.Progress != 100 {
+			t.Errorf("完成事件 = %+v", last)
+		}
+	}
+
+	// 上传完成后订阅只推送完成状态
+	events, err := env.service.SubscribeUploadEvents(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := nextEvent(t, events); first.Type != consts.UploadEventCompleted || first.Path != "docs/a.txt" {
+		t.Errorf("完成后订阅的初始事件 = %+v", first)
+	}
+	if event := nextEvent(t, events); event != nil {
+		t.Errorf("完成后通道应关闭, 收到 %+v", event)
+	}
+}
+
+func TestUploadEventsMergeError(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	session, err := env.service.CreateUploadSession(ctx, "docs", "a.txt", 4, 4, false, sumOf(consts.ChecksumSHA256, "abcd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.SaveSessionChunk(ctx, session.ID, 0, strings.NewReader("1234"), nil); err != nil {
+		t.Fatal(err)
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := env.service.SubscribeUploadEvents(subCtx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := nextEvent(t, events); first.Type != consts.UploadEventStatus || first.ReceivedChunks != 1 || first.Progress != 100 {
+		t.Fatalf("初始事件 = %+v", first)
+	}
+
+	if _, _, err := env.service.MergeUploadSession(ctx, session.ID, nil); err == nil {
+		t.Fatal("摘要不一致时合并应失败")
+	}
+	var last *model.UploadEvent
+	for event := nextEvent(t, events); event != nil; event = nextEvent(t, events) {
+		last = event
+	}
+	if last == nil || last.Type != consts.UploadEventError || !strings.Contains(last.Error, "校验失败") {
+		t.Errorf("失败事件 = %+v", last)
+	}
+}
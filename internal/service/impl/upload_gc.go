@@ -0,0 +1,329 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UploadGCServiceImpl 清理上传过程中留在临时存储与文件存储中的过期内容
+type UploadGCServiceImpl struct {
+	// storage 文件存储，其中可能留有中断的合并临时文件
+	storage storage.Driver
+	// temp 分块、上传会话与 tus 上传的临时存储
+	temp storage.Driver
+
+	// mu 保证同一时间只有一次清理，并保护 last
+	mu sync.Mutex
+	// last 最近一次清理的结果
+	last *model.UploadGCReport
+}
+
+// NewUploadGCServiceImpl 创建上传清理服务，store 与 temp 应与文件服务使用的存储相同
+func NewUploadGCServiceImpl(store storage.Driver, temp storage.Driver) *UploadGCServiceImpl {
+	return &UploadGCServiceImpl{
+		storage: store,
+		temp:    temp,
+	}
+}
+
+// gcChunk 待清理的分块
+type gcChunk struct {
+	path string
+	size int64
+}
+
+// gcChunkDir 临时存储中的一个分块目录，modTime 为其中最新分块的修改时间
+type gcChunkDir struct {
+	chunks  []gcChunk
+	modTime time.Time
+}
+
+// CollectUploadGarbage 清理临时存储中过期的分块，返回清理结果
+//
+// 按目录中最新分块的修改时间判断，超过 config.Upload.ChunkTTL 未更新的分块目录（分段上传、下载任务，以及旧版本按路径存放的分块）删除；
+// 上传会话或 tus 上传的状态已过期或被取消时，其内容无论新旧都会删除。合并中断（如服务崩溃）留在存储中的临时文件超过
+// ChunkTTL 未更新时同样删除。同一时间只有一次清理在执行。
+func (s *UploadGCServiceImpl) CollectUploadGarbage(ctx context.Context) (*model.UploadGCReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &model.UploadGCReport{StartedAt: time.Now()}
+	cutoff := report.StartedAt.Add(-config.Upload.ChunkTTL)
+
+	dirs := make(map[string]*gcChunkDir)
+	err := s.temp.Walk("", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			// 遍历期间被上传或合并删除的目录
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		path = filepath.ToSlash(path)
+		if info.IsDir() {
+			if path == consts.UploadSessionDir || path == consts.TusUploadDir || path == consts.MergeTempDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if _, ok := parseChunkName(info.Name()); !ok {
+			return nil
+		}
+		dir := filepath.ToSlash(filepath.Dir(path))
+		usage, ok := dirs[dir]
+		if !ok {
+			usage = &gcChunkDir{}
+			dirs[dir] = usage
+		}
+		usage.chunks = append(usage.chunks, gcChunk{path: path, size: info.Size()})
+		if info.ModTime().After(usage.modTime) {
+			usage.modTime = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描临时目录失败: %s", err)
+	}
+
+	expired := make([]string, 0, len(dirs))
+	for dir, usage := range dirs {
+		if usage.modTime.Before(cutoff) {
+			expired = append(expired, dir)
+		}
+	}
+	sort.Strings(expired)
+	for _, dir := range expired {
+		if !s.removeChunks(report, dirs[dir].chunks) {
+			continue
+		}
+		report.ChunkDirs++
+		s.removeEmptyDirs(dir)
+		glog.Infof("清理过期分块: %s, 分块数: %d", dir, len(dirs[dir].chunks))
+	}
+
+	s.collectSessions(report, cutoff)
+	s.collectTusUploads(report)
+	s.collectMergeTemps(report, cutoff)
+
+	report.FinishedAt = time.Now()
+	s.last = report
+	glog.Infof("过期分块清理完成，分块目录: %d, 上传会话: %d, tus 上传: %d, 合并临时文件: %d, 分块: %d, 回收: %d 字节, 失败: %d, 耗时: %s",
+		report.ChunkDirs, report.Sessions, report.TusUploads, report.MergeTemps, report.Files, report.Bytes, report.Failed, report.FinishedAt.Sub(report.StartedAt))
+	return report, nil
+}
+
+// LastUploadGC 最近一次清理过期分块的结果，尚未清理时返回 nil
+func (s *UploadGCServiceImpl) LastUploadGC(ctx context.Context) *model.UploadGCReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// collectSessions 清理已过期、已取消或超过 cutoff 未更新的上传会话的分块
+func (s *UploadGCServiceImpl) collectSessions(report *model.UploadGCReport, cutoff time.Time) {
+	entries, err := s.temp.List(consts.UploadSessionDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			glog.Warnf("读取上传会话目录失败: %s", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		dir := sessionDir(id)
+		key := cache.UploadSessionKey(id)
+		exists, err := cache.Exists(key)
+		if err != nil {
+			// 无法确认会话是否仍在使用时不清理
+			glog.Warnf("获取上传会话失败: %s, 会话: %s", err, id)
+			continue
+		}
+
+		modTime := entry.ModTime()
+		var chunks []gcChunk
+		files, _ := s.temp.List(dir)
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			chunks = append(chunks, gcChunk{path: filepath.ToSlash(filepath.Join(dir, file.Name())), size: file.Size()})
+			if file.ModTime().After(modTime) {
+				modTime = file.ModTime()
+			}
+		}
+		if exists && !modTime.Before(cutoff) {
+			continue
+		}
+
+		s.removeChunks(report, chunks)
+		if err := s.temp.RemoveAll(dir); err != nil {
+			glog.Warnf("删除上传会话目录失败: %s, 路径: %s", err, dir)
+			continue
+		}
+		if exists {
+			cache.Del(key)
+		}
+		report.Sessions++
+		glog.Infof("清理过期上传会话: %s, 分块数: %d", id, len(chunks))
+	}
+}
+
+// collectTusUploads 清理状态已不在 Redis 中（过期或已终止）的 tus 上传内容
+//
+// tus 上传的状态在最后一次写入后保留 cache.TusUploadExpiration，状态过期后内容不会再被续传。
+func (s *UploadGCServiceImpl) collectTusUploads(report *model.UploadGCReport) {
+	entries, err := s.temp.List(consts.TusUploadDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			glog.Warnf("读取 tus 上传目录失败: %s", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		exists, err := cache.Exists(cache.TusUploadKey(id))
+		if err != nil {
+			// 无法确认上传是否仍在使用时不清理
+			glog.Warnf("获取 tus 上传状态失败: %s, 上传: %s", err, id)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		dir := filepath.Join(consts.TusUploadDir, id)
+		var parts []gcChunk
+		files, _ := s.temp.List(dir)
+		for _, file := range files {
+			if !file.IsDir() {
+				parts = append(parts, gcChunk{path: filepath.ToSlash(filepath.Join(dir, file.Name())), size: file.Size()})
+			}
+		}
+		s.removeChunks(report, parts)
+		if err := s.temp.RemoveAll(dir); err != nil {
+			glog.Warnf("删除 tus 上传目录失败: %s, 路径: %s", err, dir)
+			continue
+		}
+		report.TusUploads++
+		glog.Infof("清理过期 tus 上传: %s, 分段数: %d", id, len(parts))
+	}
+}
+
+// collectMergeTemps 删除存储中超过 cutoff 未更新的合并临时文件
+//
+// 合并开始时在临时存储的 consts.MergeTempDir 中记录临时文件的位置，这里只检查有记录的临时文件。
+// 合并过程中临时文件持续写入，修改时间早于 cutoff 说明合并已经中断。
+func (s *UploadGCServiceImpl) collectMergeTemps(report *model.UploadGCReport, cutoff time.Time) {
+	records, err := s.temp.List(consts.MergeTempDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			glog.Warnf("读取合并临时文件记录失败: %s", err)
+		}
+		return
+	}
+	for _, record := range records {
+		if record.IsDir() {
+			continue
+		}
+		recordPath := filepath.Join(consts.MergeTempDir, record.Name())
+		tempPath, err := s.readMergeTempRecord(recordPath)
+		if err != nil {
+			glog.Warnf("读取合并临时文件记录失败: %s, 路径: %s", err, recordPath)
+			continue
+		}
+
+		info, err := s.storage.Stat(tempPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// 记录后尚未创建临时文件的合并很快会创建，只清理过期的记录
+			if record.ModTime().Before(cutoff) {
+				s.temp.Remove(recordPath)
+			}
+			continue
+		case err != nil:
+			glog.Warnf("获取合并临时文件失败: %s, 路径: %s", err, tempPath)
+			continue
+		case info.IsDir() || !info.ModTime().Before(cutoff):
+			continue
+		}
+
+		if err := s.storage.Remove(tempPath); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				report.Failed++
+				glog.Warnf("删除合并临时文件失败: %s, 路径: %s", err, tempPath)
+				continue
+			}
+		} else {
+			report.MergeTemps++
+			report.Bytes += info.Size()
+			glog.Infof("清理中断的合并临时文件: %s, 大小: %d", tempPath, info.Size())
+		}
+		s.temp.Remove(recordPath)
+	}
+}
+
+// readMergeTempRecord 读取记录中的合并临时文件路径，路径必须指向合并临时文件
+func (s *UploadGCServiceImpl) readMergeTempRecord(recordPath string) (string, error) {
+	file, err := s.temp.Open(recordPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	b, err := io.ReadAll(io.LimitReader(file, 4096))
+	if err != nil {
+		return "", err
+	}
+	tempPath := string(b)
+	if !isMergeTemp(filepath.Base(tempPath)) {
+		return "", fmt.Errorf("不是合并临时文件: %q", tempPath)
+	}
+	return tempPath, nil
+}
+
+// removeChunks 删除分块并计入 report，至少删除了一个分块时返回 true
+func (s *UploadGCServiceImpl) removeChunks(report *model.UploadGCReport, chunks []gcChunk) bool {
+	removed := false
+	for _, chunk := range chunks {
+		if err := s.temp.Remove(chunk.path); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				report.Failed++
+				glog.Warnf("删除过期分块失败: %s, 路径: %s", err, chunk.path)
+			}
+			continue
+		}
+		removed = true
+		report.Files++
+		report.Bytes += chunk.size
+	}
+	return removed
+}
+
+// removeEmptyDirs 从 dir 开始向上删除临时存储中的空目录
+func (s *UploadGCServiceImpl) removeEmptyDirs(dir string) {
+	for dir != "." && dir != "" {
+		// 目录非空时删除失败，说明还有其他上传在使用
+		if err := s.temp.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.ToSlash(filepath.Dir(dir))
+	}
+}
@@ -0,0 +1,53 @@
+package impl
+
+import (
+	"FileNest/internal/cache"
+	"FileNest/internal/consts"
+	"context"
+	"io"
+	"path"
+	"testing"
+)
+
+// writeTemp 直接在临时存储中写入文件
+func (e *testEnv) writeTemp(t *testing.T, p, content string) {
+	t.Helper()
+	w, err := e.temp.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGCRemovesOrphanedTusUploads(t *testing.T) {
+	env := newTestEnv(t)
+	orphan := "0123456789abcdef0123456789abcdef"
+	live := "fedcba9876543210fedcba9876543210"
+	env.writeTemp(t, path.Join(consts.TusUploadDir, orphan, "00000000000000000000"), "orphan")
+	env.writeTemp(t, path.Join(consts.TusUploadDir, live, "00000000000000000000"), "live")
+	if err := cache.HSet(cache.TusUploadKey(live), "offset", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := env.gc.CollectUploadGarbage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TusUploads != 1 || report.Files != 1 || report.Bytes != int64(len("orphan")) {
+		t.Errorf("清理结果 = %+v, 期望清理 1 个 tus 上传", report)
+	}
+	if _, err := env.temp.Stat(path.Join(consts.TusUploadDir, orphan)); err == nil {
+		t.Error("状态已过期的 tus 上传内容应被删除")
+	}
+	if _, err := env.temp.Stat(path.Join(consts.TusUploadDir, live, "00000000000000000000")); err != nil {
+		t.Errorf("仍在进行的 tus 上传内容不应被删除: %v", err)
+	}
+	if last := env.gc.LastUploadGC(context.Background()); last != report {
+		t.Error("LastUploadGC 应返回最近一次清理的结果")
+	}
+}
@@ -0,0 +1,198 @@
+package impl
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sniffLen 识别文件类型时读取的字节数，与 http.DetectContentType 一致
+const sniffLen = 512
+
+// executableSignatures http.DetectContentType 无法识别的可执行文件
+var executableSignatures = []struct {
+	magic    []byte
+	mimeType string
+}{
+	{[]byte("MZ"), "application/x-msdownload"},
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("#!"), "text/x-shellscript"},
+}
+
+// uploadPolicy filePath 适用的上传策略，使用文件所在的最近一个配置了策略的目录
+func uploadPolicy(filePath string) config.UploadPolicy {
+	policy, matched := config.Upload.Policy, -1
+	for folder, folderPolicy := range config.Upload.Folders {
+		if inFolder(folder, filePath) && len(folder) > matched {
+			policy, matched = folderPolicy, len(folder)
+		}
+	}
+	return policy
+}
+
+// checkUploadPolicy 按文件名、文件大小与分块数校验上传策略，size 与 totalChunks 未知时为 0
+func checkUploadPolicy(filePath string, size int64, totalChunks int) error {
+	if err := checkReservedName(filePath); err != nil {
+		return &model.UploadPolicyError{Reason: err.Error()}
+	}
+	policy := uploadPolicy(filePath)
+	if policy.MaxFileSize > 0 && size > policy.MaxFileSize {
+		return &model.UploadPolicyError{Reason: fmt.Sprintf("文件大小超过上限 %d 字节", policy.MaxFileSize)}
+	}
+	if policy.MaxChunks > 0 && totalChunks > policy.MaxChunks {
+		return &model.UploadPolicyError{Reason: fmt.Sprintf("分块数超过上限 %d", policy.MaxChunks)}
+	}
+
+	name := strings.ToLower(filepath.Base(filePath))
+	if ext, ok := matchExtension(name, policy.BlockedExtensions); ok {
+		return &model.UploadPolicyError{Reason: fmt.Sprintf("不允许上传 %s 文件", ext)}
+	}
+	if len(policy.AllowedExtensions) > 0 {
+		if _, ok := matchExtension(name, policy.AllowedExtensions); !ok {
+			return &model.UploadPolicyError{Reason: fmt.Sprintf("只允许上传 %s 文件", strings.Join(policy.AllowedExtensions, "、"))}
+		}
+	}
+	return nil
+}
+
+// checkUploadType 按文件开头的内容 head 识别文件类型并校验上传策略
+func checkUploadType(filePath string, head []byte) error {
+	policy := uploadPolicy(filePath)
+	if len(policy.AllowedMIMETypes) == 0 && len(policy.BlockedMIMETypes) == 0 {
+		return nil
+	}
+	mimeType := detectMIMEType(head)
+	if matchMIMEType(mimeType, policy.BlockedMIMETypes) {
+		return &model.UploadPolicyError{Reason: fmt.Sprintf("不允许上传 %s 类型的文件", mimeType)}
+	}
+	if len(policy.AllowedMIMETypes) > 0 && !matchMIMEType(mimeType, policy.AllowedMIMETypes) {
+		return &model.UploadPolicyError{Reason: fmt.Sprintf("不允许上传 %s 类型的文件", mimeType)}
+	}
+	return nil
+}
+
+// checkPlacementPolicy 校验已有的 src 复制、移动或重命名为 dest 后是否符合 dest 的上传策略，
+// 文件夹逐个校验其中的文件；dest 所在目录的类型规则与 src 不同时读取文件开头识别类型
+func (s *FileServiceImpl) checkPlacementPolicy(src, dest string, srcInfo fs.FileInfo) error {
+	if !srcInfo.IsDir() {
+		return s.checkPlacedFile(src, dest, srcInfo.Size())
+	}
+	if err := checkReservedName(dest); err != nil {
+		return &model.UploadPolicyError{Reason: err.Error()}
+	}
+	return s.storage.Walk(src, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || isMergeTemp(info.Name()) {
+			return nil
+		}
+		path = filepath.ToSlash(path)
+		return s.checkPlacedFile(path, dest+strings.TrimPrefix(path, src), info.Size())
+	})
+}
+
+// checkPlacedFile 校验文件 src 放到 dest 时的文件名、大小与类型
+func (s *FileServiceImpl) checkPlacedFile(src, dest string, size int64) error {
+	if err := checkUploadPolicy(dest, size, 0); err != nil {
+		return err
+	}
+	policy := uploadPolicy(dest)
+	if len(policy.AllowedMIMETypes) == 0 && len(policy.BlockedMIMETypes) == 0 {
+		return nil
+	}
+	if srcPolicy := uploadPolicy(src); slices.Equal(srcPolicy.AllowedMIMETypes, policy.AllowedMIMETypes) &&
+		slices.Equal(srcPolicy.BlockedMIMETypes, policy.BlockedMIMETypes) {
+		// 类型规则相同，文件写入时已校验过
+		return nil
+	}
+
+	file, err := s.storage.Open(src)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %s", err)
+	}
+	defer file.Close()
+	head, _, err := peekHead(file)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %s", err)
+	}
+	return checkUploadType(dest, head)
+}
+
+// limitUploadSize 读取的内容超过上传策略允许的文件大小时返回错误，offset 为之前已写入的字节数
+func limitUploadSize(filePath string, reader io.Reader, offset int64) io.Reader {
+	policy := uploadPolicy(filePath)
+	if policy.MaxFileSize <= 0 {
+		return reader
+	}
+	return &quotaReader{
+		reader:    reader,
+		remaining: policy.MaxFileSize - offset,
+		err:       &model.UploadPolicyError{Reason: fmt.Sprintf("文件大小超过上限 %d 字节", policy.MaxFileSize)},
+	}
+}
+
+// peekHead 读取 reader 开头用于识别类型的内容，返回的 reader 仍从头开始读取
+func peekHead(reader io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+	return head, io.MultiReader(bytes.NewReader(head), reader), nil
+}
+
+// detectMIMEType 识别内容的类型，不含参数
+func detectMIMEType(head []byte) string {
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(head, signature.magic) {
+			return signature.mimeType
+		}
+	}
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mimeType
+}
+
+// matchExtension 查找 name 匹配的扩展名，name 已转为小写
+func matchExtension(name string, extensions []string) (string, bool) {
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if strings.HasSuffix(name, ext) {
+			return ext, true
+		}
+	}
+	return "", false
+}
+
+// matchMIMEType 判断类型是否匹配列表中的任意一项，"image/*" 匹配所有图片
+func matchMIMEType(mimeType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mimeType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package impl
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// setFolderPolicy 为 folder 配置上传策略，测试结束后恢复
+func setFolderPolicy(t *testing.T, folder string, policy config.UploadPolicy) {
+	t.Helper()
+	saved := config.Upload.Folders
+	folders := make(map[string]config.UploadPolicy, len(saved)+1)
+	for k, v := range saved {
+		folders[k] = v
+	}
+	folders[folder] = policy
+	config.Upload.Folders = folders
+	t.Cleanup(func() { config.Upload.Folders = saved })
+}
+
+// assertPolicyRejected 断言 err 为上传策略错误
+func assertPolicyRejected(t *testing.T, err error, action string) {
+	t.Helper()
+	var policyErr *model.UploadPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Errorf("%s 应被上传策略拒绝, 实际 %v", action, err)
+	}
+}
+
+func TestRenameChecksUploadPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	setFolderPolicy(t, "safe", config.UploadPolicy{BlockedExtensions: []string{".exe"}})
+	env.writeFile(t, "safe/a.txt", "a")
+	ctx := context.Background()
+
+	assertPolicyRejected(t, env.service.RenameFile(ctx, "safe/a.txt", "a.exe"), "重命名为 .exe")
+	if !env.exists("safe/a.txt") || env.exists("safe/a.exe") {
+		t.Fatal("被拒绝的重命名不应改变文件")
+	}
+	if err := env.service.RenameFile(ctx, "safe/a.txt", "b.txt"); err != nil {
+		t.Fatalf("重命名为允许的扩展名失败: %v", err)
+	}
+}
+
+func TestCopyAndMoveCheckDestinationPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	setFolderPolicy(t, "images", config.UploadPolicy{AllowedMIMETypes: []string{"image/*"}})
+	setFolderPolicy(t, "small", config.UploadPolicy{MaxFileSize: 8})
+	env.writeFile(t, "docs/note.png", "plain text")
+	env.writeFile(t, "docs/pic.png", "\x89PNG\r\n\x1a\n0000")
+	env.writeFile(t, "docs/sub/big.txt", "more than eight bytes")
+	if err := env.store.Mkdir("images"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.store.Mkdir("small"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 目标目录按内容识别类型，扩展名无法绕过
+	assertPolicyRejected(t, env.service.CopyFile(ctx, "docs/note.png", "images"), "复制文本到 images")
+	assertPolicyRejected(t, env.service.MoveFile(ctx, "docs/note.png", "images"), "移动文本到 images")
+	if env.exists("images/note.png") || !env.exists("docs/note.png") {
+		t.Fatal("被拒绝的复制或移动不应改变文件树")
+	}
+	if err := env.service.CopyFile(ctx, "docs/pic.png", "images"); err != nil {
+		t.Fatalf("复制图片失败: %v", err)
+	}
+
+	// 文件夹中任一文件不符合目标目录的策略时整个操作被拒绝
+	assertPolicyRejected(t, env.service.CopyFile(ctx, "docs/sub", "small"), "复制含大文件的文件夹")
+	assertPolicyRejected(t, env.service.MoveFile(ctx, "docs", "small"), "移动含大文件的文件夹")
+	if env.exists("small/sub") || env.exists("small/docs") || !env.exists("docs/sub/big.txt") {
+		t.Fatal("被拒绝的文件夹复制或移动不应改变文件树")
+	}
+}
+
+func TestUploadPolicyRejectsUploads(t *testing.T) {
+	env := newTestEnv(t)
+	setFolderPolicy(t, "docs", config.UploadPolicy{
+		MaxFileSize:       16,
+		MaxChunks:         2,
+		AllowedExtensions: []string{".txt", ".tar.gz"},
+		BlockedExtensions: []string{".exe"},
+	})
+	ctx := context.Background()
+
+	// 扩展名与大小按文件所在的最近一个配置了策略的目录校验
+	for _, name := range []string{"a.exe", "a.bin", "sub/a.exe"} {
+		dir, file := "docs", name
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			dir, file = "docs/"+name[:i], name[i+1:]
+		}
+		_, err := env.service.SaveFile(ctx, dir, file, strings.NewReader("x"), false)
+		assertPolicyRejected(t, err, "上传 docs/"+name)
+	}
+	_, err := env.service.SaveFile(ctx, "docs", "big.txt", strings.NewReader(strings.Repeat("x", 17)), false)
+	assertPolicyRejected(t, err, "上传超出大小的文件")
+	if env.exists("docs/big.txt") || env.exists("docs/a.exe") || env.exists("docs/sub") {
+		t.Fatal("被拒绝的上传不应保存文件")
+	}
+	for _, name := range []string{"a.txt", "a.TAR.GZ"} {
+		if _, err := env.service.SaveFile(ctx, "docs", name, strings.NewReader("ok"), false); err != nil {
+			t.Errorf("上传 %s 失败: %v", name, err)
+		}
+	}
+	// 其他目录使用默认策略
+	if _, err := env.service.SaveFile(ctx, "other", "a.exe", strings.NewReader("x"), false); err != nil {
+		t.Errorf("默认策略下上传失败: %v", err)
+	}
+
+	// 上传会话在创建时校验大小与分块数
+	_, err = env.service.CreateUploadSession(ctx, "docs", "s.txt", 32, 16, false, nil)
+	assertPolicyRejected(t, err, "创建超出大小的上传会话")
+	_, err = env.service.CreateUploadSession(ctx, "docs", "s.txt", 12, 4, false, nil)
+	assertPolicyRejected(t, err, "创建超出分块数的上传会话")
+
+	// 秒传同样校验目标位置的策略
+	saveIndexed(t, env, "other", "b.exe", "binary")
+	_, err = env.service.InstantUpload(ctx, "docs", "b.exe", sha256Hex("binary"), 6, false)
+	assertPolicyRejected(t, err, "秒传到禁止的扩展名")
+}
+
+func TestUploadPolicyChecksContentType(t *testing.T) {
+	env := newTestEnv(t)
+	setFolderPolicy(t, "images", config.UploadPolicy{AllowedMIMETypes: []string{"image/*"}})
+	ctx := context.Background()
+	png := "\x89PNG\r\n\x1a\n0000"
+
+	// 类型按内容识别，修改扩展名无法绕过
+	_, err := env.service.SaveFile(ctx, "images", "fake.png", strings.NewReader("plain text"), false)
+	assertPolicyRejected(t, err, "上传伪装成图片的文本")
+	if _, err := env.service.SaveFile(ctx, "images", "real.png", strings.NewReader(png), false); err != nil {
+		t.Fatalf("上传图片失败: %v", err)
+	}
+
+	// 上传会话在保存第一个分块时识别类型
+	session, err := env.service.CreateUploadSession(ctx, "images", "s.png", 8, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPolicyRejected(t, env.service.SaveSessionChunk(ctx, session.ID, 0, strings.NewReader("text"), nil), "保存文本分块")
+	if status, _ := env.service.GetUploadSession(ctx, session.ID); status.ReceivedChunks != 0 {
+		t.Errorf("被拒绝的分块不应保存: %+v", status)
+	}
+
+	// 秒传按来源文件的内容识别类型
+	saveIndexed(t, env, "docs", "note.txt", "plain text")
+	_, err = env.service.InstantUpload(ctx, "images", "note.png", sha256Hex("plain text"), 10, false)
+	assertPolicyRejected(t, err, "秒传文本到 images")
+	if env.exists("images/note.png") {
+		t.Error("被拒绝的秒传不应创建文件")
+	}
+}
@@ -0,0 +1,408 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/cache"
+	"FileNest/internal/consts"
+	"FileNest/internal/model"
+	"FileNest/internal/utils/sandbox"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// errUploadSessionNotFound 会话不存在、已过期或不属于当前用户
+var errUploadSessionNotFound = errors.New("上传会话不存在或已过期")
+
+// CreateUploadSession 创建分块上传会话
+//
+// 创建时即校验权限、同名文件与配额；分块存放在临时目录下以会话 ID 命名的目录中，
+// 会话在最后一次收到分块后保留 cache.UploadSessionExpiration。
+func (s *FileServiceImpl) CreateUploadSession(ctx context.Context, path, fileName string, size, chunkSize int64, override bool, checksum *model.Checksum) (*model.UploadSession, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("文件大小不合法: %d", size)
+	}
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("分块大小不合法: %d", chunkSize)
+	}
+	totalChunks := (size + chunkSize - 1) / chunkSize
+	if totalChunks > consts.MaxUploadChunks {
+		return nil, fmt.Errorf("分块数 %d 超过上限 %d，请增大分块大小", totalChunks, consts.MaxUploadChunks)
+	}
+	checksum, err := normalizeChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CheckUpload(ctx, path, fileName, size, override); err != nil {
+		return nil, err
+	}
+	path, _ = sandbox.Clean(path)
+	fileName, _ = sandbox.CleanName(fileName)
+	if err := checkUploadPolicy(filepath.ToSlash(filepath.Join(path, fileName)), size, int(totalChunks)); err != nil {
+		return nil, err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("创建上传会话失败: %s", err)
+	}
+	session := &model.UploadSession{
+		ID:          id,
+		Path:        path,
+		FileName:    fileName,
+		Override:    override,
+		Size:        size,
+		ChunkSize:   chunkSize,
+		TotalChunks: int(totalChunks),
+		Checksum:    checksum,
+		Status:      consts.UploadStatusUploading,
+		Chunks:      []model.ChunkInfo{},
+		CreatedAt:   time.Now(),
+	}
+	if principal := auth.FromContext(ctx); principal != nil {
+		session.UserID = principal.User.ID
+	}
+	if err := s.saveSession(session); err != nil {
+		return nil, fmt.Errorf("创建上传会话失败: %s", err)
+	}
+
+	glog.Infof("创建上传会话: %s, 文件: %s, 大小: %d, 分块数: %d", id, filepath.Join(path, fileName), size, totalChunks)
+	return session, nil
+}
+
+// GetUploadSession 获取上传会话及已接收的分块
+func (s *FileServiceImpl) GetUploadSession(ctx context.Context, id string) (*model.UploadSession, error) {
+	session, err := s.loadSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == consts.UploadStatusDone {
+		// 合并后分块已删除，进度以会话中记录的为准
+		return session, nil
+	}
+	session.Chunks = s.listSessionChunks(session)
+	session.ReceivedChunks, session.ReceivedSize = len(session.Chunks), 0
+	for _, chunk := range session.Chunks {
+		session.ReceivedSize += chunk.Size
+	}
+	session.Progress = chunkProgress(session.ReceivedChunks, session.TotalChunks)
+	return session, nil
+}
+
+// SaveSessionChunk 保存会话的一个分块，分块大小必须与会话约定的一致
+func (s *FileServiceImpl) SaveSessionChunk(ctx context.Context, id string, chunkIndex int, reader io.Reader, checksum *model.Checksum) error {
+	session, err := s.loadSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session.Status != consts.UploadStatusUploading {
+		return fmt.Errorf("上传会话已结束: %s", session.Status)
+	}
+	if err := s.authorize(ctx, model.PermWrite, sessionFilePath(session)); err != nil {
+		return err
+	}
+	if chunkIndex < 0 || chunkIndex >= session.TotalChunks {
+		return fmt.Errorf("分块索引超出范围: %d/%d", chunkIndex, session.TotalChunks)
+	}
+	if checksum, err = normalizeChecksum(checksum); err != nil {
+		return err
+	}
+
+	if chunkIndex == 0 {
+		// 第一个分块包含文件开头，在保存前识别文件类型
+		var head []byte
+		if head, reader, err = peekHead(reader); err != nil {
+			return fmt.Errorf("读取分块失败: %s", err)
+		}
+		if err := checkUploadType(sessionFilePath(session), head); err != nil {
+			return err
+		}
+	}
+
+	expected := sessionChunkSize(session, chunkIndex)
+	chunkPath := filepath.Join(sessionDir(id), chunkName(chunkIndex))
+	writer, err := s.temp.Create(chunkPath)
+	if err != nil {
+		return fmt.Errorf("创建分块文件失败: %s", err)
+	}
+	var digest hash.Hash
+	var dst io.Writer = writer
+	if checksum != nil {
+		digest, _ = newDigest(checksum.Algorithm)
+		dst = io.MultiWriter(writer, digest)
+	}
+	// 多读一个字节以发现超出约定大小的分块
+	written, err := io.Copy(dst, io.LimitReader(reader, expected+1))
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != expected {
+		err = fmt.Errorf("分块 %d 大小应为 %d 字节，实际收到 %d 字节", chunkIndex, expected, written)
+	}
+	if err == nil {
+		err = verifyChecksum(fmt.Sprintf("分块 %d", chunkIndex), checksum, digest)
+	}
+	if err != nil {
+		// 不保留不完整或损坏的分块，客户端重新上传；同一索引原有的分块已被覆盖，进度随之更新
+		s.temp.Remove(chunkPath)
+		s.updateSessionProgress(session)
+		return err
+	}
+
+	event := s.updateSessionProgress(session)
+	event.Chunk = &chunkIndex
+	publishUploadEvent(cache.UploadSessionKey(id), event)
+	return nil
+}
+
+// MergeUploadSession 合并会话的全部分块，返回文件路径与文件摘要
+//
+// checksum 为 nil 时使用创建会话时提供的期望摘要；会话已合并时直接返回之前的结果。
+func (s *FileServiceImpl) MergeUploadSession(ctx context.Context, id string, checksum *model.Checksum) (string, *model.Checksum, error) {
+	session, err := s.loadSession(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if session.Status == consts.UploadStatusDone {
+		return session.FilePath, session.Checksum, nil
+	}
+	if session.Status != consts.UploadStatusUploading {
+		return "", nil, fmt.Errorf("上传会话已结束: %s", session.Status)
+	}
+	if checksum == nil {
+		checksum = session.Checksum
+	}
+
+	// 确认所有分块都已完整接收
+	chunks := s.listSessionChunks(session)
+	received := make(map[int]int64, len(chunks))
+	for _, chunk := range chunks {
+		received[chunk.Index] = chunk.Size
+	}
+	missing := 0
+	for i := 0; i < session.TotalChunks; i++ {
+		if size, ok := received[i]; !ok || size != sessionChunkSize(session, i) {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return "", nil, fmt.Errorf("还有 %d 个分块未上传", missing)
+	}
+
+	key := cache.UploadSessionKey(id)
+	filePath, digest, err := s.mergeChunkDir(ctx, sessionDir(id), session.Path, session.FileName,
+		session.TotalChunks, session.Override, session.Size, checksum, key)
+	if err != nil {
+		// 分块仍然保留，会话保持上传中，客户端可以重新上传分块后再次合并
+		cache.HSet(key, "error", err.Error())
+		publishUploadEvent(key, &model.UploadEvent{
+			Type:   consts.UploadEventError,
+			Status: consts.UploadStatusUploading,
+			Error:  err.Error(),
+		})
+		return "", nil, err
+	}
+	cache.HSet(key,
+		"status", consts.UploadStatusDone,
+		"received_chunks", session.TotalChunks,
+		"received_size", session.Size,
+		"progress", "100",
+		"error", "",
+		"file_path", filePath,
+		"hash_algorithm", digest.Algorithm,
+		"hash", digest.Value,
+	)
+	cache.Expire(key, time.Duration(cache.UploadSessionExpiration)*time.Second)
+	publishUploadEvent(key, &model.UploadEvent{
+		Type:           consts.UploadEventCompleted,
+		Status:         consts.UploadStatusDone,
+		Progress:       100,
+		ReceivedChunks: session.TotalChunks,
+		TotalChunks:    session.TotalChunks,
+		ReceivedSize:   session.Size,
+		Path:           filePath,
+		Checksum:       digest,
+	})
+	return filePath, digest, nil
+}
+
+// AbortUploadSession 取消上传会话并删除已接收的分块
+func (s *FileServiceImpl) AbortUploadSession(ctx context.Context, id string) error {
+	if _, err := s.loadSession(ctx, id); err != nil {
+		return err
+	}
+	if err := s.temp.RemoveAll(sessionDir(id)); err != nil {
+		return fmt.Errorf("删除分块失败: %s", err)
+	}
+	if err := cache.Del(cache.UploadSessionKey(id)); err != nil {
+		return fmt.Errorf("删除上传会话失败: %s", err)
+	}
+	glog.Infof("取消上传会话: %s", id)
+	return nil
+}
+
+// loadSession 读取当前用户的上传会话，其他用户的会话视为不存在
+func (s *FileServiceImpl) loadSession(ctx context.Context, id string) (*model.UploadSession, error) {
+	if !validSessionID(id) {
+		return nil, errUploadSessionNotFound
+	}
+	values, err := cache.HGetAll(cache.UploadSessionKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("获取上传会话失败: %s", err)
+	}
+	if len(values) == 0 {
+		return nil, errUploadSessionNotFound
+	}
+
+	session := &model.UploadSession{
+		ID:       id,
+		Path:     values["path"],
+		FileName: values["file_name"],
+		Override: values["override"] == "1",
+		Status:   values["status"],
+		Error:    values["error"],
+		FilePath: values["file_path"],
+		Chunks:   []model.ChunkInfo{},
+	}
+	userID, _ := strconv.ParseUint(values["user_id"], 10, 64)
+	session.UserID = uint(userID)
+	session.Size, _ = strconv.ParseInt(values["size"], 10, 64)
+	session.ChunkSize, _ = strconv.ParseInt(values["chunk_size"], 10, 64)
+	session.TotalChunks, _ = strconv.Atoi(values["total_chunks"])
+	session.ReceivedChunks, _ = strconv.Atoi(values["received_chunks"])
+	session.ReceivedSize, _ = strconv.ParseInt(values["received_size"], 10, 64)
+	session.Progress, _ = strconv.Atoi(values["progress"])
+	if values["hash"] != "" {
+		session.Checksum = &model.Checksum{Algorithm: values["hash_algorithm"], Value: values["hash"]}
+	}
+	if createdAt, err := strconv.ParseInt(values["created_at"], 10, 64); err == nil {
+		session.CreatedAt = time.Unix(createdAt, 0)
+	}
+	if expiresAt, err := strconv.ParseInt(values["expires_at"], 10, 64); err == nil {
+		session.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+	if session.ChunkSize <= 0 {
+		return nil, fmt.Errorf("上传会话已损坏: %s", id)
+	}
+
+	var callerID uint
+	if principal := auth.FromContext(ctx); principal != nil {
+		callerID = principal.User.ID
+	}
+	if session.UserID != callerID {
+		return nil, errUploadSessionNotFound
+	}
+	return session, nil
+}
+
+// saveSession 保存新建的会话
+func (s *FileServiceImpl) saveSession(session *model.UploadSession) error {
+	session.ExpiresAt = time.Now().Add(time.Duration(cache.UploadSessionExpiration) * time.Second)
+	key := cache.UploadSessionKey(session.ID)
+	values := []interface{}{
+		"user_id", session.UserID,
+		"path", session.Path,
+		"file_name", session.FileName,
+		"override", boolFlag(session.Override),
+		"size", session.Size,
+		"chunk_size", session.ChunkSize,
+		"total_chunks", session.TotalChunks,
+		"received_chunks", 0,
+		"received_size", 0,
+		"progress", 0,
+		"status", session.Status,
+		"created_at", session.CreatedAt.Unix(),
+		"expires_at", session.ExpiresAt.Unix(),
+	}
+	if session.Checksum != nil {
+		values = append(values, "hash_algorithm", session.Checksum.Algorithm, "hash", session.Checksum.Value)
+	}
+	if err := cache.HSet(key, values...); err != nil {
+		return err
+	}
+	return cache.Expire(key, time.Duration(cache.UploadSessionExpiration)*time.Second)
+}
+
+// updateSessionProgress 收到分块后按临时存储中的分块更新会话进度并顺延过期时间，返回对应的分块事件
+func (s *FileServiceImpl) updateSessionProgress(session *model.UploadSession) *model.UploadEvent {
+	session.Chunks = s.listSessionChunks(session)
+	session.ReceivedChunks, session.ReceivedSize = len(session.Chunks), 0
+	for _, chunk := range session.Chunks {
+		session.ReceivedSize += chunk.Size
+	}
+	session.Progress = chunkProgress(session.ReceivedChunks, session.TotalChunks)
+	session.ExpiresAt = time.Now().Add(time.Duration(cache.UploadSessionExpiration) * time.Second)
+
+	key := cache.UploadSessionKey(session.ID)
+	cache.HSet(key,
+		"received_chunks", session.ReceivedChunks,
+		"received_size", session.ReceivedSize,
+		"progress", session.Progress,
+		"expires_at", session.ExpiresAt.Unix(),
+	)
+	cache.Expire(key, time.Duration(cache.UploadSessionExpiration)*time.Second)
+	return &model.UploadEvent{
+		Type:           consts.UploadEventChunkReceived,
+		Status:         consts.UploadStatusUploading,
+		Progress:       session.Progress,
+		ReceivedChunks: session.ReceivedChunks,
+		TotalChunks:    session.TotalChunks,
+		ReceivedSize:   session.ReceivedSize,
+	}
+}
+
+// listSessionChunks 列出会话已接收的分块
+func (s *FileServiceImpl) listSessionChunks(session *model.UploadSession) []model.ChunkInfo {
+	return s.listChunkDir(sessionDir(session.ID))
+}
+
+// sessionDir 会话分块在临时存储中的目录
+func sessionDir(id string) string {
+	return filepath.Join(consts.UploadSessionDir, id)
+}
+
+// sessionFilePath 会话的目标文件路径
+func sessionFilePath(session *model.UploadSession) string {
+	return filepath.ToSlash(filepath.Join(session.Path, session.FileName))
+}
+
+// sessionChunkSize 分块的约定大小，最后一个分块为剩余部分
+func sessionChunkSize(session *model.UploadSession, index int) int64 {
+	if index == session.TotalChunks-1 {
+		return session.Size - session.ChunkSize*int64(session.TotalChunks-1)
+	}
+	return session.ChunkSize
+}
+
+// newSessionID 生成随机的会话 ID
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validSessionID 会话 ID 只能是 newSessionID 生成的格式
+func validSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,144 @@
+package impl
+
+import (
+	"FileNest/internal/cache"
+	"FileNest/internal/consts"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUploadSessionsForSameFileAreIsolated(t *testing.T) {
+	env := newTestEnv(t)
+	_, aliceCtx := env.createUser(t, "alice")
+	_, bobCtx := env.createUser(t, "bob")
+
+	alice, err := env.service.CreateUploadSession(aliceCtx, "shared", "report.txt", 8, 4, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := env.service.CreateUploadSession(bobCtx, "shared", "report.txt", 8, 4, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.ID == bob.ID {
+		t.Fatal("同一文件的两个上传会话不应共用 ID")
+	}
+
+	// 两个用户交替上传同名文件的分块
+	chunks := []struct {
+		ctx   context.Context
+		id    string
+		index int
+		data  string
+	}{
+		{aliceCtx, alice.ID, 0, "AAAA"},
+		{bobCtx, bob.ID, 0, "bbbb"},
+		{bobCtx, bob.ID, 1, "BBBB"},
+		{aliceCtx, alice.ID, 1, "aaaa"},
+	}
+	for _, c := range chunks {
+		if err := env.service.SaveSessionChunk(c.ctx, c.id, c.index, strings.NewReader(c.data), nil); err != nil {
+			t.Fatalf("保存分块 %d 失败: %v", c.index, err)
+		}
+	}
+
+	if _, _, err := env.service.MergeUploadSession(aliceCtx, alice.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.readFile(t, "shared/report.txt"); got != "AAAAaaaa" {
+		t.Fatalf("alice 合并后内容 = %q", got)
+	}
+	if _, _, err := env.service.MergeUploadSession(bobCtx, bob.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.readFile(t, "shared/report.txt"); got != "bbbbBBBB" {
+		t.Fatalf("bob 合并后内容 = %q", got)
+	}
+}
+
+func TestUploadSessionOwnedByCreator(t *testing.T) {
+	env := newTestEnv(t)
+	_, aliceCtx := env.createUser(t, "alice")
+	_, bobCtx := env.createUser(t, "bob")
+
+	session, err := env.service.CreateUploadSession(aliceCtx, "", "a.txt", 4, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.service.GetUploadSession(bobCtx, session.ID); err == nil {
+		t.Error("其他用户不应能查询会话")
+	}
+	if err := env.service.SaveSessionChunk(bobCtx, session.ID, 0, strings.NewReader("evil"), nil); err == nil {
+		t.Error("其他用户不应能上传分块")
+	}
+	if err := env.service.AbortUploadSession(bobCtx, session.ID); err == nil {
+		t.Error("其他用户不应能取消会话")
+	}
+	if _, err := env.service.SubscribeUploadEvents(bobCtx, session.ID); err == nil {
+		t.Error("其他用户不应能订阅会话事件")
+	}
+
+	if err := env.service.SaveSessionChunk(aliceCtx, session.ID, 0, strings.NewReader("good"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.service.MergeUploadSession(aliceCtx, session.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.readFile(t, "a.txt"); got != "good" {
+		t.Fatalf("合并后内容 = %q", got)
+	}
+}
+
+func TestUploadSessionProgressKeptInSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	session, err := env.service.CreateUploadSession(ctx, "", "a.txt", 8, 4, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.SaveSessionChunk(ctx, session.ID, 1, strings.NewReader("5678"), nil); err != nil {
+		t.Fatal(err)
+	}
+	values, err := cache.HGetAll(cache.UploadSessionKey(session.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["received_chunks"] != "1" || values["received_size"] != "4" || values["progress"] != "50" {
+		t.Fatalf("会话中的进度 = %v", values)
+	}
+
+	// 订阅时先推送会话中记录的进度
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := env.service.SubscribeUploadEvents(subCtx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := <-events; first.Type != consts.UploadEventStatus || first.ReceivedChunks != 1 || first.Progress != 50 {
+		t.Fatalf("初始事件 = %+v", first)
+	}
+
+	status, err := env.service.GetUploadSession(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Chunks) != 1 || status.Chunks[0].Index != 1 || status.ReceivedSize != 4 || status.Progress != 50 {
+		t.Fatalf("会话状态 = %+v", status)
+	}
+
+	if err := env.service.SaveSessionChunk(ctx, session.ID, 0, strings.NewReader("1234"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.service.MergeUploadSession(ctx, session.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	status, err = env.service.GetUploadSession(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != consts.UploadStatusDone || status.ReceivedChunks != 2 || status.ReceivedSize != 8 || status.Progress != 100 {
+		t.Fatalf("合并后会话状态 = %+v", status)
+	}
+}
@@ -0,0 +1,364 @@
+package impl
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/model"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 令牌类型
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+const minPasswordLength = 8
+
+var (
+	errInvalidCredentials = errors.New("用户名或密码错误")
+	errInvalidToken       = errors.New("令牌无效或已过期")
+	usernamePattern       = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+)
+
+// tokenClaims JWT 载荷
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Type string `json:"typ"`
+	// Version 签发时用户的令牌版本，与当前版本不一致时令牌失效
+	Version uint `json:"ver"`
+}
+
+type UserServiceImpl struct {
+	db     *gorm.DB
+	secret []byte
+	// dummyHash 用户不存在时也执行一次密码比对，避免通过响应时间判断用户名是否存在
+	dummyHash []byte
+}
+
+// NewUserServiceImpl 创建用户服务
+func NewUserServiceImpl(db *gorm.DB) *UserServiceImpl {
+	secret := []byte(config.Auth.JWTSecret)
+	if len(secret) == 0 {
+		glog.Warn("未配置 JWT 密钥，已随机生成，服务重启后需要重新登录")
+		secret = []byte(randomToken(32))
+	}
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte(randomToken(16)), bcrypt.DefaultCost)
+
+	return &UserServiceImpl{
+		db:        db,
+		secret:    secret,
+		dummyHash: dummyHash,
+	}
+}
+
+// Login 校验用户名密码并签发令牌
+func (s *UserServiceImpl) Login(username, password string) (*model.TokenPair, *model.User, error) {
+	user, err := s.VerifyPassword(username, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	glog.Infof("用户登录成功: %s", user.Username)
+	return tokens, user, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+func (s *UserServiceImpl) Refresh(refreshToken string) (*model.TokenPair, error) {
+	claims, err := s.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// 刷新令牌只能使用一次
+	if _, err := cache.GetDel(cache.RefreshTokenKey(claims.Subject, claims.ID)); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errInvalidToken
+		}
+		return nil, fmt.Errorf("校验刷新令牌失败: %w", err)
+	}
+
+	user, err := s.activeUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user)
+}
+
+// Logout 吊销访问令牌与刷新令牌
+func (s *UserServiceImpl) Logout(accessToken, refreshToken string) error {
+	if claims, err := s.parseToken(accessToken, tokenTypeAccess); err == nil {
+		ttl := time.Until(claims.ExpiresAt.Time)
+		if err := cache.Set(cache.RevokedTokenKey(claims.ID), claims.Subject, ttl); err != nil {
+			return fmt.Errorf("吊销访问令牌失败: %w", err)
+		}
+	}
+	if refreshToken != "" {
+		if claims, err := s.parseToken(refreshToken, tokenTypeRefresh); err == nil {
+			if err := cache.Del(cache.RefreshTokenKey(claims.Subject, claims.ID)); err != nil {
+				return fmt.Errorf("吊销刷新令牌失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticate 校验访问令牌，返回当前用户
+func (s *UserServiceImpl) Authenticate(accessToken string) (*model.User, error) {
+	claims, err := s.parseToken(accessToken, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := cache.Exists(cache.RevokedTokenKey(claims.ID))
+	if err != nil {
+		return nil, fmt.Errorf("校验访问令牌失败: %w", err)
+	}
+	if revoked {
+		return nil, errInvalidToken
+	}
+	return s.activeUser(claims)
+}
+
+// VerifyPassword 校验用户名密码
+func (s *UserServiceImpl) VerifyPassword(username, password string) (*model.User, error) {
+	var user model.User
+	err := s.db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("查询用户失败: %w", err)
+		}
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, errInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		glog.Warnf("用户密码错误: %s", username)
+		return nil, errInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, fmt.Errorf("用户已被禁用: %s", username)
+	}
+	return &user, nil
+}
+
+// GetUser 根据用户名获取未被禁用的用户
+func (s *UserServiceImpl) GetUser(username string) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("用户不存在: %s", username)
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if user.Disabled {
+		return nil, fmt.Errorf("用户已被禁用: %s", username)
+	}
+	return &user, nil
+}
+
+// CreateUser 创建用户
+func (s *UserServiceImpl) CreateUser(username, password, role string) (*model.User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, errors.New("用户名只能包含字母、数字、下划线、点和横线，长度不超过 64")
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("密码长度不能少于 %d 位", minPasswordLength)
+	}
+	if role == "" {
+		role = model.RoleUser
+	}
+	if role != model.RoleAdmin && role != model.RoleUser {
+		return nil, fmt.Errorf("未知的角色: %s", role)
+	}
+
+	var count int64
+	if err := s.db.Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("用户名已存在: %s", username)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("生成密码哈希失败: %w", err)
+	}
+
+	user := &model.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+	glog.Infof("创建用户成功: %s, 角色: %s", username, role)
+	return user, nil
+}
+
+// ListUsers 获取用户列表
+func (s *UserServiceImpl) ListUsers() ([]model.User, error) {
+	var users []model.User
+	if err := s.db.Order("id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("查询用户列表失败: %w", err)
+	}
+	return users, nil
+}
+
+// ChangePassword 修改密码，并使该用户已签发的访问令牌与刷新令牌全部失效
+func (s *UserServiceImpl) ChangePassword(userID uint, oldPassword, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return fmt.Errorf("密码长度不能少于 %d 位", minPasswordLength)
+	}
+
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		return errors.New("原密码错误")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("生成密码哈希失败: %w", err)
+	}
+	err = s.db.Model(&user).Updates(map[string]interface{}{
+		"password_hash": string(hash),
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("修改密码失败: %w", err)
+	}
+
+	// 令牌版本递增后旧令牌已无法通过校验，这里同时删除刷新令牌，避免其继续占用缓存
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+	if err := cache.DelByPattern(cache.UserRefreshTokenPattern(subject)); err != nil {
+		glog.Warnf("删除刷新令牌失败: %s, 用户: %s", err, user.Username)
+	}
+	glog.Infof("用户修改密码成功，已吊销全部令牌: %s", user.Username)
+	return nil
+}
+
+// EnsureAdmin 没有任何用户时创建默认管理员
+func (s *UserServiceImpl) EnsureAdmin() error {
+	var count int64
+	if err := s.db.Model(&model.User{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	password := config.Auth.AdminPassword
+	if password == "" {
+		password = randomToken(8)
+		glog.Warnf("未配置管理员密码，已生成初始密码: %s，请登录后尽快修改", password)
+	}
+	_, err := s.CreateUser(config.Auth.AdminUsername, password, model.RoleAdmin)
+	return err
+}
+
+// issueTokens 签发访问令牌与刷新令牌
+func (s *UserServiceImpl) issueTokens(user *model.User) (*model.TokenPair, error) {
+	now := time.Now()
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+
+	accessToken, _, err := s.signToken(subject, user.TokenVersion, tokenTypeAccess, now, config.Auth.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshID, err := s.signToken(subject, user.TokenVersion, tokenTypeRefresh, now, config.Auth.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Set(cache.RefreshTokenKey(subject, refreshID), subject, config.Auth.RefreshTokenTTL); err != nil {
+		return nil, fmt.Errorf("保存刷新令牌失败: %w", err)
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(config.Auth.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+func (s *UserServiceImpl) signToken(subject string, version uint, tokenType string, now time.Time, ttl time.Duration) (string, string, error) {
+	id := randomToken(16)
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type:    tokenType,
+		Version: version,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", "", fmt.Errorf("签发令牌失败: %w", err)
+	}
+	return signed, id, nil
+}
+
+func (s *UserServiceImpl) parseToken(tokenString, tokenType string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Type != tokenType || claims.ID == "" {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// activeUser 根据令牌中的用户 ID 加载未被禁用的用户，令牌版本与用户当前版本不一致时视为无效
+func (s *UserServiceImpl) activeUser(claims *tokenClaims) (*model.User, error) {
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	var user model.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidToken
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if user.Disabled {
+		return nil, fmt.Errorf("用户已被禁用: %s", user.Username)
+	}
+	if claims.Version != user.TokenVersion {
+		return nil, errInvalidToken
+	}
+	return &user, nil
+}
+
+// randomToken 生成 n 字节的随机十六进制字符串
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,69 @@
+package impl
+
+import (
+	"testing"
+)
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	env := newTestEnv(t)
+	user, _ := env.createUser(t, "alice")
+
+	first, _, err := env.users.Login("alice", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := env.users.Login("alice", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.users.ChangePassword(user.ID, "password1", "password2"); err != nil {
+		t.Fatalf("修改密码失败: %v", err)
+	}
+
+	for _, tokens := range [][2]string{{first.AccessToken, first.RefreshToken}, {second.AccessToken, second.RefreshToken}} {
+		if _, err := env.users.Authenticate(tokens[0]); err == nil {
+			t.Error("修改密码后旧的访问令牌应失效")
+		}
+		if _, err := env.users.Refresh(tokens[1]); err == nil {
+			t.Error("修改密码后旧的刷新令牌应失效")
+		}
+	}
+	if keys := env.redis.Keys(); len(keys) != 0 {
+		t.Errorf("刷新令牌应从缓存中删除, 剩余 %v", keys)
+	}
+
+	if _, _, err := env.users.Login("alice", "password1"); err == nil {
+		t.Error("旧密码不应再能登录")
+	}
+	tokens, _, err := env.users.Login("alice", "password2")
+	if err != nil {
+		t.Fatalf("新密码登录失败: %v", err)
+	}
+	if _, err := env.users.Authenticate(tokens.AccessToken); err != nil {
+		t.Errorf("新签发的访问令牌应有效: %v", err)
+	}
+	if _, err := env.users.Refresh(tokens.RefreshToken); err != nil {
+		t.Errorf("新签发的刷新令牌应有效: %v", err)
+	}
+}
+
+func TestChangePasswordKeepsOtherUsersTokens(t *testing.T) {
+	env := newTestEnv(t)
+	alice, _ := env.createUser(t, "alice")
+	env.createUser(t, "bob")
+
+	bobTokens, _, err := env.users.Login("bob", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.users.ChangePassword(alice.ID, "password1", "password2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.users.Authenticate(bobTokens.AccessToken); err != nil {
+		t.Errorf("其他用户的访问令牌不应受影响: %v", err)
+	}
+	if _, err := env.users.Refresh(bobTokens.RefreshToken); err != nil {
+		t.Errorf("其他用户的刷新令牌不应受影响: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"FileNest/common/database"
+	"FileNest/internal/model"
+	"FileNest/internal/service/impl"
+	"time"
+)
+
+type TokenService interface {
+	// CreateToken 创建 API 令牌，明文令牌只在创建时返回一次
+	CreateToken(userID uint, name string, scopes []string, pathPrefix string, expiresIn time.Duration) (string, *model.APIToken, error)
+	// ListTokens 获取用户的 API 令牌列表
+	ListTokens(userID uint) ([]model.APIToken, error)
+	// RevokeToken 吊销 API 令牌
+	RevokeToken(userID, tokenID uint) error
+	// Authenticate 校验 API 令牌，返回令牌所属用户
+	Authenticate(token string) (*model.User, *model.APIToken, error)
+}
+
+func NewTokenService() TokenService {
+	return impl.NewTokenServiceImpl(database.GetDB())
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"FileNest/internal/model"
+	"FileNest/internal/service/impl"
+	"FileNest/internal/storage"
+	"context"
+)
+
+// UploadGCService 清理过期的分块、上传会话、tus 上传与中断的合并临时文件
+type UploadGCService interface {
+	// CollectUploadGarbage 清理过期的上传内容，返回清理结果
+	CollectUploadGarbage(ctx context.Context) (*model.UploadGCReport, error)
+	// LastUploadGC 最近一次清理的结果，尚未清理时返回 nil
+	LastUploadGC(ctx context.Context) *model.UploadGCReport
+}
+
+// NewUploadGCService 创建上传清理服务，store 与 temp 应与文件服务使用的存储相同
+func NewUploadGCService(store storage.Driver, temp storage.Driver) UploadGCService {
+	return impl.NewUploadGCServiceImpl(store, temp)
+}
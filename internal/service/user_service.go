@@ -0,0 +1,34 @@
+package service
+
+import (
+	"FileNest/common/database"
+	"FileNest/internal/model"
+	"FileNest/internal/service/impl"
+)
+
+type UserService interface {
+	// Login 校验用户名密码并签发令牌
+	Login(username, password string) (*model.TokenPair, *model.User, error)
+	// Refresh 使用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+	Refresh(refreshToken string) (*model.TokenPair, error)
+	// Logout 吊销访问令牌与刷新令牌
+	Logout(accessToken, refreshToken string) error
+	// Authenticate 校验访问令牌，返回当前用户
+	Authenticate(accessToken string) (*model.User, error)
+	// VerifyPassword 校验用户名密码，用于 WebDAV 等不支持令牌的客户端
+	VerifyPassword(username, password string) (*model.User, error)
+	// GetUser 根据用户名获取未被禁用的用户
+	GetUser(username string) (*model.User, error)
+	// CreateUser 创建用户
+	CreateUser(username, password, role string) (*model.User, error)
+	// ListUsers 获取用户列表
+	ListUsers() ([]model.User, error)
+	// ChangePassword 修改密码
+	ChangePassword(userID uint, oldPassword, newPassword string) error
+	// EnsureAdmin 没有任何用户时创建默认管理员
+	EnsureAdmin() error
+}
+
+func NewUserService() UserService {
+	return impl.NewUserServiceImpl(database.GetDB())
+}
@@ -0,0 +1,224 @@
+package sftpd
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/sftp"
+)
+
+// handlers 将 SFTP 请求转换为 FileService 调用
+type handlers struct {
+	fileService service.FileService
+	principal   *auth.Principal
+}
+
+func newHandlers(fileService service.FileService, principal *auth.Principal) sftp.Handlers {
+	h := &handlers{fileService: fileService, principal: principal}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// context 为请求附带登录用户的身份
+func (h *handlers) context(r *sftp.Request) context.Context {
+	return auth.WithPrincipal(r.Context(), h.principal)
+}
+
+// Fileread 打开文件用于下载
+func (h *handlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	name := clientPath(r.Filepath)
+	glog.Infof("SFTP 下载文件, 用户: %s, 路径: %s", h.principal.User.Username, name)
+
+	file, _, err := h.fileService.DownloadFile(h.context(r), name)
+	if err != nil {
+		return nil, h.statError(r, name, err)
+	}
+	return file, nil
+}
+
+// Filewrite 打开文件用于上传，内容先写入本地临时文件，关闭时保存
+func (h *handlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	name := clientPath(r.Filepath)
+	glog.Infof("SFTP 上传文件, 用户: %s, 路径: %s", h.principal.User.Username, name)
+
+	if name == "" {
+		return nil, os.ErrPermission
+	}
+	if info, err := h.fileService.StatFile(h.context(r), name); err == nil && info.IsDir() {
+		return nil, os.ErrExist
+	}
+	if _, err := h.fileService.StatFile(h.context(r), path.Dir(name)); err != nil {
+		return nil, err
+	}
+	// 提前校验权限、配额与上传策略，写入时按允许的大小截止，避免先把超出的内容落到临时文件
+	dirName, fileName := path.Split(name)
+	limit, err := h.fileService.CheckUploadLimit(h.context(r), dirName, fileName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "filenest-sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &uploadFile{file: tmp, ctx: h.context(r), fileService: h.fileService, name: name, limit: limit}, nil
+}
+
+// Filecmd 处理重命名、删除、创建目录等命令
+func (h *handlers) Filecmd(r *sftp.Request) error {
+	name := clientPath(r.Filepath)
+	glog.Infof("SFTP 命令, 用户: %s, 方法: %s, 路径: %s", h.principal.User.Username, r.Method, name)
+
+	switch r.Method {
+	case "Setstat":
+		// 存储驱动不支持修改权限与时间，忽略即可
+		return nil
+	case "Rename":
+		target := clientPath(r.Target)
+		if name == "" || target == "" {
+			return os.ErrPermission
+		}
+		if _, err := h.fileService.StatFile(h.context(r), target); err == nil {
+			return os.ErrExist
+		}
+		return h.fileService.MoveFile(h.context(r), name, target)
+	case "Rmdir":
+		info, err := h.fileService.StatFile(h.context(r), name)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		return h.fileService.DeleteFile(h.context(r), name, false)
+	case "Remove":
+		info, err := h.fileService.StatFile(h.context(r), name)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		return h.fileService.DeleteFile(h.context(r), name, false)
+	case "Mkdir":
+		if _, err := h.fileService.StatFile(h.context(r), name); err == nil {
+			return os.ErrExist
+		}
+		return h.fileService.CreateFolder(h.context(r), name)
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// Filelist 处理列目录与 stat
+func (h *handlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	name := clientPath(r.Filepath)
+
+	switch r.Method {
+	case "List":
+		entries, err := h.fileService.ReadDir(h.context(r), name)
+		if err != nil {
+			return nil, h.statError(r, name, err)
+		}
+		return listerAt(entries), nil
+	case "Stat":
+		info, err := h.fileService.StatFile(h.context(r), name)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	default:
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// statError 业务错误中丢失了"不存在"的语义，重新 stat 一次以返回正确的状态码
+func (h *handlers) statError(r *sftp.Request, name string, err error) error {
+	if _, statErr := h.fileService.StatFile(h.context(r), name); errors.Is(statErr, fs.ErrNotExist) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+// clientPath 将 SFTP 路径转换为 FileService 使用的相对路径
+func clientPath(name string) string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+type listerAt []fs.FileInfo
+
+func (l listerAt) ListAt(ls []fs.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// uploadFile 上传中的文件，Close 时通过 FileService 保存
+type uploadFile struct {
+	file        *os.File
+	ctx         context.Context
+	fileService service.FileService
+	name        string
+	limit       *model.UploadLimit
+	mu          sync.Mutex
+	err         error // 写入超出上限的错误，此时 Close 不再保存
+	closed      bool
+}
+
+// WriteAt 写入后的文件大小超出允许的上限时拒绝写入，SFTP 可能并发写入不同的位置
+func (f *uploadFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	if f.err == nil {
+		f.err = f.limit.Check(off + int64(len(p)))
+	}
+	err := f.err
+	f.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return f.file.WriteAt(p, off)
+}
+
+func (f *uploadFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	defer os.Remove(f.file.Name())
+	defer f.file.Close()
+
+	if f.err != nil {
+		return f.err
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dirName, fileName := path.Split(f.name)
+	_, err := f.fileService.SaveFile(f.ctx, dirName, fileName, f.file, true)
+	return err
+}
@@ -0,0 +1,249 @@
+package sftpd
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/config"
+	"FileNest/internal/service"
+	"FileNest/internal/utils/sandbox"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Server 内嵌的 SFTP 服务，使用 FileNest 账号登录，文件操作全部经过 FileService
+type Server struct {
+	cfg         *config.SFTPConfig
+	fileService service.FileService
+	userService service.UserService
+	sshConfig   *ssh.ServerConfig
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewServer 创建 SFTP 服务，加载主机密钥与公钥列表
+func NewServer(cfg *config.SFTPConfig, fileService service.FileService, userService service.UserService) (*Server, error) {
+	s := &Server{
+		cfg:         cfg,
+		fileService: fileService,
+		userService: userService,
+	}
+
+	hostKey, err := loadOrCreateHostKey(cfg.HostKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载主机密钥失败: %w", err)
+	}
+
+	sshConfig := &ssh.ServerConfig{
+		PasswordCallback: s.checkPassword,
+	}
+
+	if cfg.AuthorizedKeysDir != "" {
+		sshConfig.PublicKeyCallback = s.checkPublicKey
+	}
+	sshConfig.AddHostKey(hostKey)
+	s.sshConfig = sshConfig
+	return s, nil
+}
+
+// ListenAndServe 监听端口并处理连接，直到 Close 被调用
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	glog.Infof("starting sftp server on port %d", s.cfg.Port)
+	return s.Serve(listener)
+}
+
+// Serve 在指定的监听器上处理连接
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close 停止监听
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// checkPassword 使用 FileNest 账号密码登录
+func (s *Server) checkPassword(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	if _, err := s.userService.VerifyPassword(conn.User(), string(password)); err != nil {
+		return nil, fmt.Errorf("password rejected for %q: %w", conn.User(), err)
+	}
+	return &ssh.Permissions{}, nil
+}
+
+// checkPublicKey 使用 AuthorizedKeysDir 下与登录用户名同名文件中的公钥登录
+//
+// 每次登录都重新读取公钥文件，增删公钥无需重启服务。
+func (s *Server) checkPublicKey(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	username, err := sandbox.CleanName(conn.User())
+	if err != nil {
+		return nil, fmt.Errorf("invalid user %q", conn.User())
+	}
+	if _, err := s.userService.GetUser(username); err != nil {
+		return nil, fmt.Errorf("unknown user %q", username)
+	}
+
+	authorizedKeys, err := loadAuthorizedKeys(filepath.Join(s.cfg.AuthorizedKeysDir, username))
+	if err != nil {
+		glog.Warnf("SFTP 读取公钥列表失败: %s, 用户: %s", err, username)
+		return nil, fmt.Errorf("load authorized keys for %q: %w", username, err)
+	}
+	if !authorizedKeys[string(key.Marshal())] {
+		return nil, fmt.Errorf("unauthorized public key for %q", username)
+	}
+	return &ssh.Permissions{}, nil
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.sshConfig)
+	if err != nil {
+		glog.Warnf("SFTP 握手失败: %s, 地址: %s", err, conn.RemoteAddr())
+		return
+	}
+	defer sshConn.Close()
+
+	user, err := s.userService.GetUser(sshConn.User())
+	if err != nil {
+		glog.Warnf("SFTP 加载用户失败: %s", err)
+		return
+	}
+	principal := &auth.Principal{User: user}
+	glog.Infof("SFTP 用户登录: %s, 地址: %s", sshConn.User(), sshConn.RemoteAddr())
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			glog.Warnf("SFTP 建立会话失败: %s", err)
+			continue
+		}
+		go s.handleSession(principal, channel, requests)
+	}
+}
+
+// handleSession 只接受 sftp 子系统请求
+func (s *Server) handleSession(principal *auth.Principal, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+		req.Reply(ok, nil)
+		if !ok {
+			continue
+		}
+
+		go ssh.DiscardRequests(requests)
+		server := sftp.NewRequestServer(channel, newHandlers(s.fileService, principal))
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+			glog.Warnf("SFTP 会话异常结束: %s, 用户: %s", err, principal.User.Username)
+		}
+		server.Close()
+		glog.Infof("SFTP 会话结束, 用户: %s", principal.User.Username)
+		return
+	}
+}
+
+// loadOrCreateHostKey 读取主机私钥，不存在时生成 ed25519 密钥并保存
+func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return ssh.ParsePrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(key, "filenest")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, err
+	}
+	glog.Infof("已生成 SFTP 主机密钥: %s", path)
+	return ssh.NewSignerFromKey(key)
+}
+
+// loadAuthorizedKeys 读取公钥列表，文件不存在时返回空
+func loadAuthorizedKeys(path string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	if path == "" {
+		return keys, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, err
+	}
+
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, err
+		}
+		keys[string(key.Marshal())] = true
+		data = rest
+	}
+	return keys, nil
+}
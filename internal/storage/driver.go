@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+)
+
+// File 只读文件句柄，支持顺序读取、随机读取与定位
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// WalkFunc 遍历回调，path 为相对于存储根目录的路径（根目录为 ""）
+//
+// 与 filepath.WalkFunc 语义一致，返回 fs.SkipDir 可跳过当前目录。
+type WalkFunc func(path string, info fs.FileInfo, err error) error
+
+// Driver 存储驱动
+//
+// 所有路径均为相对于存储根目录、以 "/" 分隔的客户端路径，由驱动自行校验，
+// 不存在的路径返回可被 errors.Is(err, fs.ErrNotExist) 识别的错误。
+type Driver interface {
+	// Stat 获取文件或目录信息
+	Stat(path string) (fs.FileInfo, error)
+	// List 列出目录下的直接子项，按名称排序
+	List(path string) ([]fs.FileInfo, error)
+	// Open 以只读方式打开文件
+	Open(path string) (File, error)
+	// Create 创建或截断文件，父目录不存在时自动创建，写入在 Close 后生效
+	Create(path string) (io.WriteCloser, error)
+	// Mkdir 创建目录及其所有父目录
+	Mkdir(path string) error
+	// Rename 重命名或移动文件、目录，目标父目录不存在时自动创建
+	Rename(oldPath, newPath string) error
+	// Remove 删除文件或空目录
+	Remove(path string) error
+	// RemoveAll 递归删除文件或目录，路径不存在时不报错
+	RemoveAll(path string) error
+	// Walk 按字典序遍历 path 及其所有子项
+	Walk(path string, fn WalkFunc) error
+}
+
+// Copier 支持服务端复制的驱动，复制时无需经过本服务中转数据
+type Copier interface {
+	// Copy 复制单个文件，目标父目录不存在时自动创建
+	Copy(srcPath, destPath string) error
+}
+
+// Linker 支持硬链接的驱动，多个路径共享同一份数据
+//
+// 驱动的 Create 必须创建新文件而不是原地截断，否则写入会同时修改其他链接。
+type Linker interface {
+	// Link 为文件创建硬链接，目标父目录不存在时自动创建
+	Link(srcPath, destPath string) error
+}
+
+// Syncer 支持落盘的写入句柄，Create 返回的句柄实现该接口时可在 Close 前调用 Sync
+type Syncer interface {
+	Sync() error
+}
+
+// PartCreator 支持按分片写入文件的驱动，如对象存储的分片上传
+//
+// 合并分块时每个分块作为一个大小已知的分片写入，驱动无需缓冲长度未知的流。
+type PartCreator interface {
+	// CreateParts 创建按分片写入的文件，父目录不存在时自动创建，写入在 Close 后生效
+	CreateParts(path string) (PartWriter, error)
+}
+
+// PartWriter 按分片写入的文件，内容按 WritePart 的调用顺序拼接
+type PartWriter interface {
+	// WritePart 写入一个 size 字节的分片，reader 中的内容不足 size 时返回错误
+	WritePart(reader io.Reader, size int64) error
+	// Close 完成写入，之前写入的分片在 Close 返回后可见
+	Close() error
+	// Abort 放弃写入并清理已写入的分片，之后不能再调用 Close
+	Abort() error
+}
@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/utils/sandbox"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// drivers 返回需要满足 Driver 约定的全部驱动
+func drivers(t *testing.T) map[string]Driver {
+	t.Helper()
+	local, err := NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建本地驱动失败: %v", err)
+	}
+	return map[string]Driver{
+		"local":  local,
+		"memory": NewMemoryDriver(),
+		"s3":     newS3Driver(t),
+	}
+}
+
+// newS3Driver 创建连接到内存中的 S3 服务的驱动，测试结束后关闭服务
+func newS3Driver(t *testing.T) *S3Driver {
+	t.Helper()
+	fake := gofakes3.New(s3mem.New()).Server()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.ServeHTTP(&partialContentWriter{ResponseWriter: w}, r)
+	}))
+	t.Cleanup(server.Close)
+	d, err := NewS3Driver(config.S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "filenest",
+		SecretKey: "filenest",
+		Bucket:    "filenest",
+		Region:    "us-east-1",
+		Prefix:    "data",
+		PartSize:  minPartSize,
+	})
+	if err != nil {
+		t.Fatalf("创建 S3 驱动失败: %v", err)
+	}
+	return d
+}
+
+// partialContentWriter 将带 Content-Range 的 200 响应改为 206，
+// gofakes3 对 Range 请求返回 200，minio 客户端会误以为对象只有返回的这一段
+type partialContentWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *partialContentWriter) WriteHeader(code int) {
+	if code == http.StatusOK && w.Header().Get("Content-Range") != "" {
+		code = http.StatusPartialContent
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *partialContentWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func writeFile(t *testing.T, d Driver, p, content string) {
+	t.Helper()
+	w, err := d.Create(p)
+	if err != nil {
+		t.Fatalf("Create(%q) 失败: %v", p, err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatalf("写入 %q 失败: %v", p, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭 %q 失败: %v", p, err)
+	}
+}
+
+func readFile(t *testing.T, d Driver, p string) string {
+	t.Helper()
+	f, err := d.Open(p)
+	if err != nil {
+		t.Fatalf("Open(%q) 失败: %v", p, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("读取 %q 失败: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestDriverCreateAndOpen(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "a/b/c.txt", "hello")
+			if got := readFile(t, d, "a/b/c.txt"); got != "hello" {
+				t.Fatalf("内容 = %q, 期望 hello", got)
+			}
+
+			info, err := d.Stat("a/b")
+			if err != nil || !info.IsDir() {
+				t.Fatalf("父目录应被自动创建: %v", err)
+			}
+			info, err = d.Stat("a/b/c.txt")
+			if err != nil || info.IsDir() || info.Size() != 5 || info.Name() != "c.txt" {
+				t.Fatalf("Stat 结果不正确: %v %v", info, err)
+			}
+
+			// 截断已有文件
+			writeFile(t, d, "a/b/c.txt", "hi")
+			if got := readFile(t, d, "a/b/c.txt"); got != "hi" {
+				t.Fatalf("覆盖后内容 = %q, 期望 hi", got)
+			}
+		})
+	}
+}
+
+func TestDriverOpenSeekAndReadAt(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "f.txt", "0123456789")
+			f, err := d.Open("f.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			buf := make([]byte, 3)
+			if _, err := f.ReadAt(buf, 4); err != nil || string(buf) != "456" {
+				t.Fatalf("ReadAt = %q, %v", buf, err)
+			}
+			if _, err := f.Seek(7, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			rest, _ := io.ReadAll(f)
+			if string(rest) != "789" {
+				t.Fatalf("Seek 后读取 = %q", rest)
+			}
+		})
+	}
+}
+
+func TestDriverNotExist(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := d.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat 应返回 ErrNotExist, 实际 %v", err)
+			}
+			if _, err := d.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open 应返回 ErrNotExist, 实际 %v", err)
+			}
+			if _, err := d.List("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("List 应返回 ErrNotExist, 实际 %v", err)
+			}
+			if err := d.Remove("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Remove 应返回 ErrNotExist, 实际 %v", err)
+			}
+			if err := d.RemoveAll("missing"); err != nil {
+				t.Errorf("RemoveAll 不存在的路径不应报错: %v", err)
+			}
+		})
+	}
+}
+
+func TestDriverListSorted(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "dir/b.txt", "b")
+			writeFile(t, d, "dir/a.txt", "a")
+			writeFile(t, d, "dir/sub/c.txt", "c")
+
+			infos, err := d.List("dir")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			if want := []string{"a.txt", "b.txt", "sub"}; !reflect.DeepEqual(names, want) {
+				t.Fatalf("List = %v, 期望 %v", names, want)
+			}
+		})
+	}
+}
+
+func TestDriverRename(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "src/a.txt", "a")
+			writeFile(t, d, "src/sub/b.txt", "b")
+
+			if err := d.Rename("src", "x/y/dst"); err != nil {
+				t.Fatalf("Rename 失败: %v", err)
+			}
+			if _, err := d.Stat("src"); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("源目录应不存在: %v", err)
+			}
+			if got := readFile(t, d, "x/y/dst/sub/b.txt"); got != "b" {
+				t.Fatalf("子文件内容 = %q", got)
+			}
+
+			writeFile(t, d, "other.txt", "new")
+			if err := d.Rename("other.txt", "x/y/dst/a.txt"); err != nil {
+				t.Fatalf("覆盖已有文件失败: %v", err)
+			}
+			if got := readFile(t, d, "x/y/dst/a.txt"); got != "new" {
+				t.Fatalf("覆盖后内容 = %q", got)
+			}
+		})
+	}
+}
+
+func TestDriverRemove(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "dir/a.txt", "a")
+			if err := d.Remove("dir"); err == nil {
+				t.Fatal("Remove 非空目录应失败")
+			}
+			if err := d.Remove("dir/a.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Remove("dir"); err != nil {
+				t.Fatalf("Remove 空目录失败: %v", err)
+			}
+
+			writeFile(t, d, "tree/sub/a.txt", "a")
+			if err := d.RemoveAll("tree"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.Stat("tree/sub/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("RemoveAll 后文件仍存在: %v", err)
+			}
+		})
+	}
+}
+
+func TestDriverWalk(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, d, "w/b.txt", "b")
+			writeFile(t, d, "w/a/x.txt", "x")
+			writeFile(t, d, "w/skip/y.txt", "y")
+
+			var visited []string
+			err := d.Walk("w", func(p string, info fs.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() && info.Name() == "skip" {
+					return fs.SkipDir
+				}
+				visited = append(visited, p)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"w", "w/a", "w/a/x.txt", "w/b.txt"}
+			if !reflect.DeepEqual(visited, want) {
+				t.Fatalf("Walk = %v, 期望 %v", visited, want)
+			}
+		})
+	}
+}
+
+func TestDriverRejectsTraversal(t *testing.T) {
+	for name, d := range drivers(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, p := range []string{"../x", "a/../../x", "/etc/passwd"} {
+				if _, err := d.Stat(p); err == nil || errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Stat(%q) 应被拒绝, 实际 %v", p, err)
+				}
+				if _, err := d.Create(p); err == nil {
+					t.Errorf("Create(%q) 应被拒绝", p)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalDriverRejectsSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	d, err := NewLocalDriver(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Open("link/secret.txt"); !errors.Is(err, sandbox.ErrSymlinkEscape) {
+		t.Fatalf("通过符号链接读取根目录外的文件应被拒绝, 实际 %v", err)
+	}
+	if _, err := d.Create("link/new.txt"); err == nil {
+		t.Fatal("通过符号链接在根目录外创建文件应被拒绝")
+	}
+}
+
+func TestS3DriverCreateParts(t *testing.T) {
+	d := newS3Driver(t)
+	// 小于最小分片大小的分块累积后再上传，大分块与累积的内容一起作为一个部分
+	sizes := []int64{1 << 20, 3 << 20, 6 << 20, 2}
+	var want []byte
+	w, err := d.CreateParts("big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, size := range sizes {
+		part := []byte(strings.Repeat(string(rune('a'+i)), int(size)))
+		want = append(want, part...)
+		if err := w.WritePart(strings.NewReader(string(part)), size); err != nil {
+			t.Fatalf("写入第 %d 个分片失败: %v", i, err)
+		}
+	}
+	if _, err := d.Stat("big.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Close 前对象不应可见: %v", err)
+	}
+	if len(w.(*s3PartWriter).parts) != 1 {
+		t.Errorf("Close 前已上传 %d 个部分, 期望 1", len(w.(*s3PartWriter).parts))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, d, "big.bin"); got != string(want) {
+		t.Fatalf("内容长度 = %d, 期望 %d", len(got), len(want))
+	}
+
+	// 整个文件不足最小分片大小时直接上传
+	w, _ = d.CreateParts("small.txt")
+	for _, part := range []string{"hello ", "world"} {
+		if err := w.WritePart(strings.NewReader(part), int64(len(part))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, d, "small.txt"); got != "hello world" {
+		t.Fatalf("内容 = %q", got)
+	}
+
+	// 分片内容不足时报错，放弃后不留下对象
+	w, _ = d.CreateParts("short.txt")
+	if err := w.WritePart(strings.NewReader("abc"), 4); err == nil {
+		t.Fatal("分片内容不足时应报错")
+	}
+	if err := w.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Stat("short.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("放弃后对象不应存在: %v", err)
+	}
+}
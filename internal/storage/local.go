@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"FileNest/internal/utils/sandbox"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalDriver 本地磁盘存储驱动，所有路径都经过 sandbox 校验
+type LocalDriver struct {
+	root string
+}
+
+// NewLocalDriver 创建本地磁盘存储驱动，根目录不存在时自动创建
+func NewLocalDriver(root string) (*LocalDriver, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &LocalDriver{root: root}, nil
+}
+
+// Root 返回根目录
+func (d *LocalDriver) Root() string {
+	return d.root
+}
+
+func (d *LocalDriver) resolve(path string) (string, error) {
+	return sandbox.Resolve(d.root, path)
+}
+
+func (d *LocalDriver) Stat(path string) (fs.FileInfo, error) {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(fullPath)
+}
+
+func (d *LocalDriver) List(path string) ([]fs.FileInfo, error) {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// 读取目录与获取信息之间文件被删除
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (d *LocalDriver) Open(path string) (File, error) {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
+
+func (d *LocalDriver) Create(path string) (io.WriteCloser, error) {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	// 先删除已有文件再创建，避免修改与其共享数据的硬链接
+	if info, err := os.Lstat(fullPath); err == nil && !info.IsDir() {
+		if err := os.Remove(fullPath); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (d *LocalDriver) Mkdir(path string) error {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(fullPath, os.ModePerm)
+}
+
+func (d *LocalDriver) Rename(oldPath, newPath string) error {
+	oldFullPath, err := d.resolve(oldPath)
+	if err != nil {
+		return err
+	}
+	newFullPath, err := d.resolve(newPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(newFullPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(oldFullPath, newFullPath)
+}
+
+// Link 创建硬链接，源文件与目标文件共享同一份数据
+func (d *LocalDriver) Link(srcPath, destPath string) error {
+	srcFullPath, err := d.resolve(srcPath)
+	if err != nil {
+		return err
+	}
+	destFullPath, err := d.resolve(destPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(destFullPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Link(srcFullPath, destFullPath)
+}
+
+func (d *LocalDriver) Remove(path string) error {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(fullPath)
+}
+
+func (d *LocalDriver) RemoveAll(path string) error {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(fullPath)
+}
+
+func (d *LocalDriver) Walk(path string, fn WalkFunc) error {
+	fullPath, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(fullPath, func(p string, info fs.FileInfo, err error) error {
+		rel, relErr := sandbox.Rel(d.root, p)
+		if relErr != nil {
+			return relErr
+		}
+		return fn(rel, info, err)
+	})
+}
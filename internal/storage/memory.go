@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"FileNest/internal/utils/sandbox"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryDriver 内存存储驱动，用于测试，进程退出后数据丢失
+type MemoryDriver struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	name    string
+	isDir   bool
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryDriver 创建内存存储驱动
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{
+		nodes: map[string]*memNode{
+			"": {name: "/", isDir: true, modTime: time.Now()},
+		},
+	}
+}
+
+func (d *MemoryDriver) Stat(p string) (fs.FileInfo, error) {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	node, ok := d.nodes[key]
+	if !ok {
+		return nil, notExist("stat", p)
+	}
+	return node.info(), nil
+}
+
+func (d *MemoryDriver) List(p string) ([]fs.FileInfo, error) {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	node, ok := d.nodes[key]
+	if !ok {
+		return nil, notExist("readdir", p)
+	}
+	if !node.isDir {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: errors.New("not a directory")}
+	}
+	return d.children(key), nil
+}
+
+func (d *MemoryDriver) Open(p string) (File, error) {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	node, ok := d.nodes[key]
+	if !ok {
+		return nil, notExist("open", p)
+	}
+	if node.isDir {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errors.New("is a directory")}
+	}
+	return memFile{Reader: bytes.NewReader(node.data)}, nil
+}
+
+func (d *MemoryDriver) Create(p string) (io.WriteCloser, error) {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, &fs.PathError{Op: "create", Path: p, Err: errors.New("is a directory")}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if node, ok := d.nodes[key]; ok && node.isDir {
+		return nil, &fs.PathError{Op: "create", Path: p, Err: errors.New("is a directory")}
+	}
+	if err := d.mkdirAll(path.Dir(key)); err != nil {
+		return nil, err
+	}
+	d.nodes[key] = &memNode{name: path.Base(key), modTime: time.Now()}
+	return &memWriter{driver: d, key: key}, nil
+}
+
+func (d *MemoryDriver) Mkdir(p string) error {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.mkdirAll(key)
+}
+
+func (d *MemoryDriver) Rename(oldPath, newPath string) error {
+	oldKey, err := sandbox.Clean(oldPath)
+	if err != nil {
+		return err
+	}
+	newKey, err := sandbox.Clean(newPath)
+	if err != nil {
+		return err
+	}
+	if oldKey == "" || newKey == "" {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrInvalid}
+	}
+	if newKey == oldKey || strings.HasPrefix(newKey, oldKey+"/") {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrInvalid}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	node, ok := d.nodes[oldKey]
+	if !ok {
+		return notExist("rename", oldPath)
+	}
+	if target, ok := d.nodes[newKey]; ok && (target.isDir || node.isDir) {
+		return &fs.PathError{Op: "rename", Path: newPath, Err: fs.ErrExist}
+	}
+	if err := d.mkdirAll(path.Dir(newKey)); err != nil {
+		return err
+	}
+
+	for key, n := range d.nodes {
+		if key == oldKey || strings.HasPrefix(key, oldKey+"/") {
+			delete(d.nodes, key)
+			d.nodes[newKey+strings.TrimPrefix(key, oldKey)] = n
+		}
+	}
+	node.name = path.Base(newKey)
+	return nil
+}
+
+func (d *MemoryDriver) Remove(p string) error {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	node, ok := d.nodes[key]
+	if !ok {
+		return notExist("remove", p)
+	}
+	if node.isDir && len(d.children(key)) > 0 {
+		return &fs.PathError{Op: "remove", Path: p, Err: errors.New("directory not empty")}
+	}
+	if key == "" {
+		return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrInvalid}
+	}
+	delete(d.nodes, key)
+	return nil
+}
+
+func (d *MemoryDriver) RemoveAll(p string) error {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k := range d.nodes {
+		if k == "" {
+			continue
+		}
+		if key == "" || k == key || strings.HasPrefix(k, key+"/") {
+			delete(d.nodes, k)
+		}
+	}
+	return nil
+}
+
+func (d *MemoryDriver) Walk(p string, fn WalkFunc) error {
+	key, err := sandbox.Clean(p)
+	if err != nil {
+		return err
+	}
+
+	info, err := d.Stat(key)
+	if err != nil {
+		err = fn(key, nil, err)
+	} else {
+		err = d.walk(key, info, fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (d *MemoryDriver) walk(key string, info fs.FileInfo, fn WalkFunc) error {
+	if !info.IsDir() {
+		return fn(key, info, nil)
+	}
+
+	children, err := d.List(key)
+	err1 := fn(key, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, child := range children {
+		err = d.walk(path.Join(key, child.Name()), child, fn)
+		if err != nil {
+			if !child.IsDir() || err != fs.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mkdirAll 创建目录及其父目录，调用方需持有写锁
+func (d *MemoryDriver) mkdirAll(key string) error {
+	if key == "." {
+		key = ""
+	}
+	for current := key; ; current = path.Dir(current) {
+		if current == "." {
+			current = ""
+		}
+		if node, ok := d.nodes[current]; ok {
+			if !node.isDir {
+				return &fs.PathError{Op: "mkdir", Path: current, Err: errors.New("not a directory")}
+			}
+		}
+		if current == "" {
+			break
+		}
+	}
+
+	for current := key; current != "" && current != "."; current = path.Dir(current) {
+		if _, ok := d.nodes[current]; !ok {
+			d.nodes[current] = &memNode{name: path.Base(current), isDir: true, modTime: time.Now()}
+		}
+	}
+	return nil
+}
+
+// children 返回目录下的直接子项，调用方需持有读锁
+func (d *MemoryDriver) children(key string) []fs.FileInfo {
+	var infos []fs.FileInfo
+	for k, node := range d.nodes {
+		if k == "" {
+			continue
+		}
+		parent := path.Dir(k)
+		if parent == "." {
+			parent = ""
+		}
+		if parent == key {
+			infos = append(infos, node.info())
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos
+}
+
+func (n *memNode) info() fs.FileInfo {
+	return memFileInfo{
+		name:    n.name,
+		size:    int64(len(n.data)),
+		isDir:   n.isDir,
+		modTime: n.modTime,
+	}
+}
+
+func notExist(op, p string) error {
+	return &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+}
+
+// memWriter 写入缓冲，Close 时提交到驱动
+type memWriter struct {
+	driver *MemoryDriver
+	key    string
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *memWriter) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+
+	w.driver.mu.Lock()
+	defer w.driver.mu.Unlock()
+	if err := w.driver.mkdirAll(path.Dir(w.key)); err != nil {
+		return err
+	}
+	w.driver.nodes[w.key] = &memNode{
+		name:    path.Base(w.key),
+		data:    w.buf.Bytes(),
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	isDir   bool
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.isDir }
+func (i memFileInfo) Sys() any           { return nil }
+
+func (i memFileInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
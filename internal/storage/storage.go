@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"fmt"
+)
+
+// New 根据配置创建文件存储驱动
+func New(cfg *config.StorageConfig) (Driver, error) {
+	switch cfg.Type {
+	case "", config.StorageLocal:
+		return NewLocalDriver(consts.UploadDir)
+	case config.StorageS3:
+		return NewS3Driver(cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,437 @@
+package tus
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/auth"
+	"FileNest/internal/model"
+	"FileNest/internal/service"
+	"FileNest/internal/storage"
+	"FileNest/internal/utils/sandbox"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Version 支持的 tus 协议版本
+	Version = "1.0.0"
+	// Extensions 支持的 tus 扩展
+	Extensions = "creation,termination,checksum"
+	// ChecksumAlgorithms checksum 扩展支持的摘要算法
+	ChecksumAlgorithms = "md5,sha1,sha256"
+
+	offsetContentType = "application/offset+octet-stream"
+	// statusChecksumMismatch checksum 扩展定义的状态码
+	statusChecksumMismatch = 460
+)
+
+// Methods tus 使用的 HTTP 方法
+var Methods = []string{"OPTIONS", "HEAD", "POST", "PATCH", "DELETE"}
+
+// RequestHeaders 浏览器跨域使用 tus 时需要允许的请求头
+var RequestHeaders = []string{"Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Defer-Length", "Upload-Metadata", "Upload-Checksum", "X-HTTP-Method-Override"}
+
+// ResponseHeaders 浏览器跨域使用 tus 时需要读取的响应头
+var ResponseHeaders = []string{"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Checksum-Algorithm", "Upload-Offset", "Upload-Length", "Upload-Metadata", "Location"}
+
+// Handler tus 1.0 断点续传协议处理器，支持 creation、termination 与 checksum 扩展
+//
+// 上传完成后通过 FileService.SaveFile 保存到文件树，与其他上传方式共享权限、配额与缓存逻辑。
+// Upload-Metadata 中 filename（或 name）为文件名，path 为目标目录，override 为 true 时覆盖同名文件。
+type Handler struct {
+	prefix      string
+	fileService service.FileService
+	store       *store
+	// mu 保护 busy，busy 为正在处理 PATCH 或 DELETE 的上传 ID，同一上传同时只处理一个请求，处理结束即删除
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// NewHandler 创建挂载在 prefix 下的 tus 处理器，已接收的内容存放在临时存储 temp 中
+func NewHandler(fileService service.FileService, temp storage.Driver, prefix string) *Handler {
+	return &Handler{
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		fileService: fileService,
+		store:       newStore(temp),
+		busy:        make(map[string]bool),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", Version)
+
+	// 不支持 PATCH、DELETE 的环境可以通过 POST 加 X-HTTP-Method-Override 发送
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == http.MethodPost {
+		method = strings.ToUpper(override)
+	}
+
+	if method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		http.Error(w, "不支持的 tus 协议版本", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.prefix), "/")
+	switch {
+	case id == "" && method == http.MethodPost:
+		h.create(w, r)
+	case id != "" && method == http.MethodHead:
+		h.head(w, r, id)
+	case id != "" && method == http.MethodPatch:
+		h.patch(w, r, id)
+	case id != "" && method == http.MethodDelete:
+		h.terminate(w, r, id)
+	default:
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+	}
+}
+
+// options 返回服务支持的协议版本与扩展
+func (h *Handler) options(w http.ResponseWriter) {
+	w.Header().Set("Tus-Version", Version)
+	w.Header().Set("Tus-Extension", Extensions)
+	w.Header().Set("Tus-Checksum-Algorithm", ChecksumAlgorithms)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// create 创建上传（creation 扩展），创建前即校验权限、同名文件与配额
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "不支持延迟指定文件大小", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "Upload-Length 无效", http.StatusBadRequest)
+		return
+	}
+
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	metadata, err := parseMetadata(rawMetadata)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = metadata["name"]
+	}
+	if fileName == "" {
+		http.Error(w, "Upload-Metadata 中缺少文件名", http.StatusBadRequest)
+		return
+	}
+
+	u := &upload{
+		Path:     metadata["path"],
+		FileName: fileName,
+		Override: metadata["override"] == "true",
+		Length:   length,
+		Metadata: rawMetadata,
+	}
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		u.UserID = principal.User.ID
+	}
+	glog.Infof("收到 tus 上传请求，文件名: %s, 路径: %s, 大小: %d", u.FileName, u.Path, u.Length)
+
+	if err := h.fileService.CheckUpload(r.Context(), u.Path, u.FileName, u.Length, u.Override); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := h.store.create(u); err != nil {
+		glog.Errorf("创建 tus 上传失败: %s", err)
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// 空文件无需 PATCH，直接保存
+	if u.Length == 0 {
+		if err := h.finish(r.Context(), u); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Location", h.prefix+"/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// head 查询已接收的字节数
+func (h *Handler) head(w http.ResponseWriter, r *http.Request, id string) {
+	u, err := h.load(r.Context(), id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.Metadata != "" {
+		w.Header().Set("Upload-Metadata", u.Metadata)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// patch 从 Upload-Offset 处追加数据，接收完整后保存到文件树
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	unlock, ok := h.lock(id)
+	if !ok {
+		http.Error(w, "上传正在处理中", http.StatusLocked)
+		return
+	}
+	defer unlock()
+
+	u, err := h.load(r.Context(), id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("Content-Type") != offsetContentType {
+		http.Error(w, "Content-Type 必须为 "+offsetContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Upload-Offset 无效", http.StatusBadRequest)
+		return
+	}
+	if offset != u.Offset {
+		http.Error(w, fmt.Sprintf("Upload-Offset 不匹配，当前为 %d", u.Offset), http.StatusConflict)
+		return
+	}
+	if u.Done {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	hasher, expected, err := parseChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n, status, err := h.write(u, r.Body, hasher, expected)
+	if err != nil {
+		glog.Warnf("tus 上传写入失败: %s, ID: %s", err, id)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	u.Offset += n
+	if err := h.store.save(u); err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if u.Offset == u.Length {
+		if err := h.finish(r.Context(), u); err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// write 将请求内容保存为从 u.Offset 开始的分段，返回写入的字节数
+//
+// 超出文件大小或摘要不匹配时丢弃本次写入的内容；未校验摘要时，连接中断前收到的内容会保留，客户端可从新的偏移量续传。
+func (h *Handler) write(u *upload, body io.Reader, hasher hash.Hash, expected []byte) (int64, int, error) {
+	partPath := h.store.partPath(u.ID, u.Offset)
+	writer, err := h.store.temp.Create(partPath)
+	if err != nil {
+		return 0, http.StatusInternalServerError, fmt.Errorf("创建上传文件失败: %w", err)
+	}
+
+	remaining := u.Length - u.Offset
+	reader := io.LimitReader(body, remaining+1)
+	if hasher != nil {
+		reader = io.TeeReader(reader, hasher)
+	}
+	n, copyErr := io.Copy(writer, reader)
+	if err := writer.Close(); err != nil {
+		h.store.temp.Remove(partPath)
+		return 0, http.StatusInternalServerError, fmt.Errorf("写入上传文件失败: %w", err)
+	}
+
+	var status int
+	var rejectErr error
+	switch {
+	case n > remaining:
+		status, rejectErr = http.StatusRequestEntityTooLarge, fmt.Errorf("上传内容超出 Upload-Length")
+	case hasher != nil && copyErr != nil:
+		status, rejectErr = http.StatusBadRequest, fmt.Errorf("接收上传内容失败: %w", copyErr)
+	case hasher != nil && !bytes.Equal(hasher.Sum(nil), expected):
+		status, rejectErr = statusChecksumMismatch, fmt.Errorf("上传内容摘要不匹配")
+	}
+
+	// 删除未确认的分段，已保存的分段总大小始终与偏移量一致
+	if rejectErr != nil || n == 0 {
+		if err := h.store.temp.Remove(partPath); err != nil {
+			return 0, http.StatusInternalServerError, fmt.Errorf("写入上传文件失败: %w", err)
+		}
+	}
+	if rejectErr != nil {
+		return 0, status, rejectErr
+	}
+	if copyErr != nil {
+		glog.Warnf("tus 上传连接中断，已接收 %d 字节, ID: %s", n, u.ID)
+	}
+	return n, http.StatusNoContent, nil
+}
+
+// finish 上传完成后保存到文件树，保存失败时删除该上传
+func (h *Handler) finish(ctx context.Context, u *upload) error {
+	reader, closeData, err := h.store.open(u.ID)
+	if err != nil {
+		return err
+	}
+	filePath, err := h.fileService.SaveFile(ctx, u.Path, u.FileName, reader, u.Override)
+	closeData()
+	if err != nil {
+		glog.Errorf("保存 tus 上传失败: %s, ID: %s", err, u.ID)
+		if removeErr := h.store.remove(u.ID); removeErr != nil {
+			glog.Warnf("删除 tus 上传失败: %s, ID: %s", removeErr, u.ID)
+		}
+		return err
+	}
+
+	// 保留状态，客户端在收到响应前断开时仍可通过 HEAD 确认已完成
+	u.Done = true
+	if err := h.store.removeData(u.ID); err != nil {
+		glog.Warnf("清理 tus 上传数据失败: %s, ID: %s", err, u.ID)
+	}
+	if err := h.store.save(u); err != nil {
+		glog.Warnf("保存 tus 上传状态失败: %s, ID: %s", err, u.ID)
+	}
+
+	glog.Infof("tus 上传完成: %s", filePath)
+	return nil
+}
+
+// terminate 终止上传并删除已接收的数据（termination 扩展）
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request, id string) {
+	unlock, ok := h.lock(id)
+	if !ok {
+		http.Error(w, "上传正在处理中", http.StatusLocked)
+		return
+	}
+	defer unlock()
+
+	if _, err := h.load(r.Context(), id); err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.remove(id); err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	glog.Infof("tus 上传已终止, ID: %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// load 读取上传状态，只有创建上传的用户可以访问
+func (h *Handler) load(ctx context.Context, id string) (*upload, error) {
+	u, err := h.store.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if principal := auth.FromContext(ctx); principal != nil && principal.User.ID != u.UserID {
+		return nil, errNotFound
+	}
+	return u, nil
+}
+
+// lock 获取上传的处理锁，已被占用时返回 false；释放后不保留任何状态，过期清理的上传无需另行删除
+func (h *Handler) lock(id string) (func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[id] {
+		return nil, false
+	}
+	h.busy[id] = true
+	return func() {
+		h.mu.Lock()
+		delete(h.busy, id)
+		h.mu.Unlock()
+	}, true
+}
+
+// parseMetadata 解析 Upload-Metadata，格式为逗号分隔的 "key base64(value)"
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata 中 %s 的值不是合法的 Base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseChecksum 解析 Upload-Checksum，格式为 "算法 base64(摘要)"，未携带时返回 nil
+func parseChecksum(header string) (hash.Hash, []byte, error) {
+	if header == "" {
+		return nil, nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(header, " ")
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Upload-Checksum 摘要不是合法的 Base64")
+	}
+
+	var hasher hash.Hash
+	switch algorithm {
+	case "md5":
+		hasher = md5.New()
+	case "sha1":
+		hasher = sha1.New()
+	case "sha256":
+		hasher = sha256.New()
+	default:
+		return nil, nil, fmt.Errorf("不支持的摘要算法: %s", algorithm)
+	}
+	return hasher, expected, nil
+}
+
+// writeError 按错误类型返回对应的状态码，无法识别时使用 fallback
+func writeError(w http.ResponseWriter, err error, fallback int) {
+	status := fallback
+	var quotaErr *model.QuotaExceededError
+	switch {
+	case errors.Is(err, errNotFound):
+		status = http.StatusNotFound
+	case sandbox.IsPathError(err):
+		status = http.StatusBadRequest
+	case errors.Is(err, fs.ErrPermission):
+		status = http.StatusForbidden
+	case errors.As(err, &quotaErr):
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,218 @@
+package tus
+
+import (
+	"FileNest/common/glog"
+	"FileNest/internal/cache"
+	"FileNest/internal/config"
+	"FileNest/internal/consts"
+	"FileNest/internal/service"
+	"FileNest/internal/storage"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// TestMain 在临时目录中运行测试，日志不会写入源码目录
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "filenest-tus-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	glog.Install()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestHandler 创建使用临时目录存储、miniredis 保存状态的 tus 处理器
+func newTestHandler(t *testing.T) (*Handler, *storage.LocalDriver, *storage.LocalDriver) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	config.Redis.Host = mr.Host()
+	config.Redis.Port, _ = strconv.Atoi(mr.Port())
+	config.Redis.Password = ""
+	config.Redis.DB = 0
+	if err := cache.InitRedis(); err != nil {
+		t.Fatalf("连接 Redis 失败: %v", err)
+	}
+
+	store, err := storage.NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp, err := storage.NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(service.NewFileServiceWithStorage(store, temp), temp, "/api/tus"), store, temp
+}
+
+func serve(h *Handler, method, target string, header map[string]string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", Version)
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// createUpload 创建上传并返回其地址
+func createUpload(t *testing.T, h *Handler, fileName string, length int) string {
+	t.Helper()
+	w := serve(h, http.MethodPost, "/api/tus", map[string]string{
+		"Upload-Length":   strconv.Itoa(length),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte(fileName)),
+	}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建上传返回 %d: %s", w.Code, w.Body.String())
+	}
+	return w.Header().Get("Location")
+}
+
+func patch(h *Handler, location string, offset int, body string, header map[string]string) *httptest.ResponseRecorder {
+	headers := map[string]string{
+		"Content-Type":  offsetContentType,
+		"Upload-Offset": strconv.Itoa(offset),
+	}
+	for key, value := range header {
+		headers[key] = value
+	}
+	return serve(h, http.MethodPatch, location, headers, body)
+}
+
+// partCount 返回上传在临时存储中已保存的分段数
+func partCount(t *testing.T, temp storage.Driver, location string) int {
+	t.Helper()
+	entries, err := temp.List(path.Join(consts.TusUploadDir, path.Base(location)))
+	if err != nil {
+		t.Fatalf("读取上传目录失败: %v", err)
+	}
+	return len(entries)
+}
+
+func readAll(t *testing.T, d storage.Driver, p string) string {
+	t.Helper()
+	f, err := d.Open(p)
+	if err != nil {
+		t.Fatalf("打开 %s 失败: %v", p, err)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUploadInParts(t *testing.T) {
+	h, store, temp := newTestHandler(t)
+	location := createUpload(t, h, "hello.txt", 11)
+
+	w := patch(h, location, 0, "hello ", nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "6" {
+		t.Fatalf("第一段返回 %d, 偏移量 %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if n := partCount(t, temp, location); n != 1 {
+		t.Fatalf("临时存储中有 %d 个分段，期望 1 个", n)
+	}
+
+	w = serve(h, http.MethodHead, location, nil, "")
+	if w.Header().Get("Upload-Offset") != "6" {
+		t.Fatalf("HEAD 返回偏移量 %q", w.Header().Get("Upload-Offset"))
+	}
+
+	w = patch(h, location, 6, "world", nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("第二段返回 %d, 偏移量 %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if got := readAll(t, store, "hello.txt"); got != "hello world" {
+		t.Fatalf("保存的内容为 %q", got)
+	}
+	if _, err := temp.Stat(path.Join(consts.TusUploadDir, path.Base(location))); err == nil {
+		t.Fatal("上传完成后临时数据未删除")
+	}
+}
+
+func TestRejectedPartIsDiscarded(t *testing.T) {
+	h, _, temp := newTestHandler(t)
+	location := createUpload(t, h, "a.txt", 4)
+
+	sum := sha256.Sum256([]byte("other"))
+	w := patch(h, location, 0, "abcd", map[string]string{
+		"Upload-Checksum": "sha256 " + base64.StdEncoding.EncodeToString(sum[:]),
+	})
+	if w.Code != statusChecksumMismatch {
+		t.Fatalf("摘要不匹配返回 %d", w.Code)
+	}
+	w = patch(h, location, 0, "abcde", nil)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("超出 Upload-Length 返回 %d", w.Code)
+	}
+	if n := partCount(t, temp, location); n != 0 {
+		t.Fatalf("被拒绝的内容留下了 %d 个分段", n)
+	}
+
+	w = serve(h, http.MethodHead, location, nil, "")
+	if w.Header().Get("Upload-Offset") != "0" {
+		t.Fatalf("被拒绝后偏移量为 %q", w.Header().Get("Upload-Offset"))
+	}
+}
+
+func TestTerminateRemovesData(t *testing.T) {
+	h, _, temp := newTestHandler(t)
+	location := createUpload(t, h, "a.txt", 10)
+	if w := patch(h, location, 0, "abc", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("写入返回 %d", w.Code)
+	}
+
+	if w := serve(h, http.MethodDelete, location, nil, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("终止上传返回 %d", w.Code)
+	}
+	if _, err := temp.Stat(path.Join(consts.TusUploadDir, path.Base(location))); err == nil {
+		t.Fatal("终止后临时数据未删除")
+	}
+	if w := serve(h, http.MethodHead, location, nil, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("终止后 HEAD 返回 %d", w.Code)
+	}
+}
+
+func TestUploadLockReleased(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	location := createUpload(t, h, "a.txt", 10)
+
+	unlock, ok := h.lock(path.Base(location))
+	if !ok {
+		t.Fatal("获取处理锁失败")
+	}
+	if w := patch(h, location, 0, "abc", nil); w.Code != http.StatusLocked {
+		t.Fatalf("处理中的上传返回 %d", w.Code)
+	}
+	unlock()
+
+	if w := patch(h, location, 0, "abc", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("释放后写入返回 %d", w.Code)
+	}
+	if w := serve(h, http.MethodDelete, location, nil, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("终止上传返回 %d", w.Code)
+	}
+	// 处理结束即释放，不为已结束或过期清理的上传保留锁
+	if len(h.busy) != 0 {
+		t.Fatalf("处理结束后仍保留 %d 个锁", len(h.busy))
+	}
+}
@@ -0,0 +1,193 @@
+package tus
+
+import (
+	"FileNest/internal/cache"
+	"FileNest/internal/consts"
+	"FileNest/internal/storage"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// errNotFound 上传不存在或已过期
+var errNotFound = errors.New("上传不存在或已过期")
+
+// upload 一次 tus 上传的状态
+type upload struct {
+	ID       string
+	UserID   uint   // 创建上传的用户，内部调用时为 0
+	Path     string // 目标目录
+	FileName string // 文件名
+	Override bool   // 是否覆盖同名文件
+	Length   int64  // 文件总大小
+	Offset   int64  // 已接收的字节数
+	Metadata string // 客户端传入的 Upload-Metadata 原文
+	Done     bool   // 已保存到文件树
+}
+
+// store 保存上传状态与已接收的数据
+//
+// 状态存放在 Redis 中，在最后一次更新后保留 cache.TusUploadExpiration；
+// 数据存放在临时存储的 consts.TusUploadDir/<ID> 目录中，每个 PATCH 请求接收的内容保存为一个分段，
+// 分段以起始偏移量命名，按名称排序即为文件内容的顺序。
+type store struct {
+	temp storage.Driver
+}
+
+func newStore(temp storage.Driver) *store {
+	return &store{temp: temp}
+}
+
+// create 创建上传，分配 ID 并创建空的数据目录
+func (s *store) create(u *upload) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	u.ID = id
+
+	if err := s.temp.Mkdir(s.dataDir(id)); err != nil {
+		return fmt.Errorf("创建上传目录失败: %w", err)
+	}
+	if err := s.save(u); err != nil {
+		s.temp.RemoveAll(s.dataDir(id))
+		return err
+	}
+	return nil
+}
+
+// get 读取上传状态
+func (s *store) get(id string) (*upload, error) {
+	if !validID(id) {
+		return nil, errNotFound
+	}
+	values, err := cache.HGetAll(cache.TusUploadKey(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errNotFound
+	}
+
+	u := &upload{
+		ID:       id,
+		Path:     values["path"],
+		FileName: values["file_name"],
+		Override: values["override"] == "1",
+		Metadata: values["metadata"],
+		Done:     values["done"] == "1",
+	}
+	userID, _ := strconv.ParseUint(values["user_id"], 10, 64)
+	u.UserID = uint(userID)
+	if u.Length, err = strconv.ParseInt(values["length"], 10, 64); err != nil {
+		return nil, fmt.Errorf("上传状态损坏: %s", id)
+	}
+	if u.Offset, err = strconv.ParseInt(values["offset"], 10, 64); err != nil {
+		return nil, fmt.Errorf("上传状态损坏: %s", id)
+	}
+	return u, nil
+}
+
+// save 保存上传状态并刷新过期时间
+func (s *store) save(u *upload) error {
+	key := cache.TusUploadKey(u.ID)
+	err := cache.HSet(key,
+		"user_id", u.UserID,
+		"path", u.Path,
+		"file_name", u.FileName,
+		"override", boolFlag(u.Override),
+		"length", u.Length,
+		"offset", u.Offset,
+		"metadata", u.Metadata,
+		"done", boolFlag(u.Done),
+	)
+	if err != nil {
+		return err
+	}
+	return cache.Expire(key, time.Duration(cache.TusUploadExpiration)*time.Second)
+}
+
+// remove 删除上传状态与数据
+func (s *store) remove(id string) error {
+	if err := s.removeData(id); err != nil {
+		return err
+	}
+	return cache.Del(cache.TusUploadKey(id))
+}
+
+// removeData 只删除已接收的数据，保留状态供客户端查询
+func (s *store) removeData(id string) error {
+	if err := s.temp.RemoveAll(s.dataDir(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除上传文件失败: %w", err)
+	}
+	return nil
+}
+
+// open 按顺序打开已接收的分段，返回拼接后的内容；调用方负责调用 close
+func (s *store) open(id string) (io.Reader, func(), error) {
+	entries, err := s.temp.List(s.dataDir(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取上传目录失败: %w", err)
+	}
+	files := make([]io.Closer, 0, len(entries))
+	closeAll := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file, err := s.temp.Open(filepath.Join(s.dataDir(id), entry.Name()))
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("打开上传文件失败: %w", err)
+		}
+		files = append(files, file)
+		readers = append(readers, file)
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
+
+// dataDir 上传数据在临时存储中的目录
+func (s *store) dataDir(id string) string {
+	return filepath.Join(consts.TusUploadDir, id)
+}
+
+// partPath 从 offset 开始的分段在临时存储中的路径，补零使按名称排序与按偏移量排序一致
+func (s *store) partPath(id string, offset int64) string {
+	return filepath.Join(s.dataDir(id), fmt.Sprintf("%020d", offset))
+}
+
+// newID 生成随机的上传 ID
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validID 上传 ID 只能是 newID 生成的格式，避免拼接出任意路径
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,48 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+)
+
+const (
+	// Attachment 作为附件下载
+	Attachment = "attachment"
+	// Inline 在浏览器中直接打开
+	Inline = "inline"
+)
+
+// ServeFile 以 disposition 方式发送文件内容
+//
+// 响应带有由修改时间与大小生成的 ETag 和 Last-Modified，支持 If-None-Match、If-Modified-Since、
+// If-Range 与多段 Range 请求。Content-Type 由 http.ServeContent 按扩展名或内容识别。
+func ServeFile(w http.ResponseWriter, r *http.Request, file io.ReadSeeker, info fs.FileInfo, disposition string) {
+	header := w.Header()
+	header.Set("Content-Disposition", ContentDisposition(disposition, info.Name()))
+	header.Set("ETag", ETag(info))
+	// 文件随时可能被修改，浏览器每次使用缓存前都需要用 ETag 重新验证
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("X-Content-Type-Options", "nosniff")
+	if disposition == Inline {
+		// 预览 HTML、SVG 等文件时禁止执行脚本
+		header.Set("Content-Security-Policy", "sandbox")
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// ContentDisposition 生成 Content-Disposition 响应头，非 ASCII 文件名按 RFC 2231 编码
+func ContentDisposition(disposition, fileName string) string {
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); value != "" {
+		return value
+	}
+	return disposition
+}
+
+// ETag 由修改时间与大小生成的强 ETag，文件内容变化时两者至少有一个改变，可用于 If-Range
+func ETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
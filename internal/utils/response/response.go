@@ -30,3 +30,21 @@ func Error(c *gin.Context, message string) {
 		Data:    nil,
 	})
 }
+
+// Unauthorized 未登录或令牌无效
+func Unauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, Response{
+		Code:    http.StatusUnauthorized,
+		Message: message,
+		Data:    nil,
+	})
+}
+
+// Forbidden 没有权限
+func Forbidden(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, Response{
+		Code:    http.StatusForbidden,
+		Message: message,
+		Data:    nil,
+	})
+}
@@ -0,0 +1,178 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 路径校验失败的原因，可通过 errors.Is 判断
+var (
+	// ErrTraversal 路径中包含 ".." 上级目录引用
+	ErrTraversal = errors.New("路径包含上级目录引用")
+	// ErrAbsolutePath 客户端传入了绝对路径
+	ErrAbsolutePath = errors.New("不允许使用绝对路径")
+	// ErrSymlinkEscape 符号链接指向根目录之外
+	ErrSymlinkEscape = errors.New("符号链接指向根目录之外")
+	// ErrInvalidName 文件名为空或包含路径分隔符
+	ErrInvalidName = errors.New("文件名不合法")
+)
+
+// PathError 非法路径错误
+type PathError struct {
+	Path string // 客户端传入的原始路径
+	Err  error  // 具体原因
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("非法路径 %q: %s", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// IsPathError 判断是否为非法路径错误
+func IsPathError(err error) bool {
+	var pathErr *PathError
+	return errors.As(err, &pathErr)
+}
+
+// Clean 规范化客户端传入的相对路径，根目录返回空字符串
+//
+// 客户端路径总是相对于根目录，"" 和 "/" 均表示根目录本身；
+// 其余以 "/" 开头的路径、带盘符的路径以及包含 ".." 的路径都会被拒绝。
+func Clean(p string) (string, error) {
+	if p == "" || p == "/" || p == "." {
+		return "", nil
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", &PathError{Path: p, Err: ErrTraversal}
+	}
+	if filepath.IsAbs(p) || strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\`) || filepath.VolumeName(p) != "" {
+		return "", &PathError{Path: p, Err: ErrAbsolutePath}
+	}
+
+	for _, segment := range strings.FieldsFunc(p, isSeparator) {
+		if segment == ".." {
+			return "", &PathError{Path: p, Err: ErrTraversal}
+		}
+	}
+
+	cleaned := filepath.Clean(filepath.FromSlash(p))
+	if cleaned == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(cleaned), nil
+}
+
+// CleanName 校验单个文件名，不允许包含路径分隔符或 "."、".."
+func CleanName(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, 0) || strings.ContainsAny(name, `/\`) {
+		return "", &PathError{Path: name, Err: ErrInvalidName}
+	}
+	return name, nil
+}
+
+// Resolve 将客户端路径解析为根目录下经过校验的绝对路径
+//
+// 除了静态检查外，还会沿着路径中已经存在的部分解析符号链接，
+// 确保最终落点仍在根目录内。目标本身可以不存在（如新建文件）。
+func Resolve(root, p string) (string, error) {
+	rel, err := Clean(p)
+	if err != nil {
+		return "", err
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("解析根目录失败: %w", err)
+	}
+	target := filepath.Join(absRoot, filepath.FromSlash(rel))
+
+	realRoot, err := evalExisting(absRoot)
+	if err != nil {
+		return "", fmt.Errorf("解析根目录失败: %w", err)
+	}
+
+	realTarget, err := evalExisting(target)
+	if errors.Is(err, errDanglingLink) {
+		return "", &PathError{Path: p, Err: ErrSymlinkEscape}
+	}
+	if err != nil {
+		return "", fmt.Errorf("解析路径失败: %w", err)
+	}
+	if !within(realRoot, realTarget) {
+		return "", &PathError{Path: p, Err: ErrSymlinkEscape}
+	}
+
+	return target, nil
+}
+
+// Rel 将根目录下的绝对路径转换回客户端使用的相对路径
+func Rel(root, abs string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &PathError{Path: abs, Err: ErrTraversal}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// errDanglingLink 路径中存在指向不存在目标的符号链接，无法判断其落点
+var errDanglingLink = errors.New("dangling symlink")
+
+// evalExisting 解析路径中已存在部分的符号链接，并拼接上尚不存在的部分
+func evalExisting(path string) (string, error) {
+	var missing []string
+	current := path
+	for {
+		real, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				real = filepath.Join(real, missing[i])
+			}
+			return real, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, lerr := os.Lstat(current); lerr == nil {
+			return "", errDanglingLink
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
+	}
+}
+
+// within 判断 target 是否位于 root 之内（含 root 本身）
+func within(root, target string) bool {
+	if root == target {
+		return true
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
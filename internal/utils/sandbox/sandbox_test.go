@@ -0,0 +1,124 @@
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"", "", nil},
+		{"/", "", nil},
+		{".", "", nil},
+		{"a/b", "a/b", nil},
+		{"a//b/./c/", "a/b/c", nil},
+		{"..", "", ErrTraversal},
+		{"a/../b", "", ErrTraversal},
+		{`a\..\..\b`, "", ErrTraversal},
+		{"a\x00b", "", ErrTraversal},
+		{"/etc/passwd", "", ErrAbsolutePath},
+		{`\windows`, "", ErrAbsolutePath},
+	}
+	for _, c := range cases {
+		got, err := Clean(c.in)
+		if c.err != nil {
+			if !errors.Is(err, c.err) || !IsPathError(err) {
+				t.Errorf("Clean(%q) 错误 = %v, 期望 %v", c.in, err, c.err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("Clean(%q) = %q, %v, 期望 %q", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "a\x00"} {
+		if _, err := CleanName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("CleanName(%q) 应被拒绝, 实际 %v", name, err)
+		}
+	}
+	if got, err := CleanName("报告.txt"); err != nil || got != "报告.txt" {
+		t.Errorf("CleanName 合法文件名失败: %q, %v", got, err)
+	}
+}
+
+func TestResolveTraversal(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{"../x", "a/../../x", "/etc/passwd"} {
+		if _, err := Resolve(root, p); !IsPathError(err) {
+			t.Errorf("Resolve(%q) 应被拒绝, 实际 %v", p, err)
+		}
+	}
+
+	got, err := Resolve(root, "a/新建/b.txt")
+	if err != nil {
+		t.Fatalf("不存在的路径应允许解析: %v", err)
+	}
+	absRoot, _ := filepath.Abs(root)
+	if want := filepath.Join(absRoot, "a", "新建", "b.txt"); got != want {
+		t.Fatalf("Resolve = %q, 期望 %q", got, want)
+	}
+}
+
+func TestResolveSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "inside"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"escape":   outside,
+		"internal": filepath.Join(root, "inside"),
+		"dangling": filepath.Join(root, "missing"),
+		"relative": "../" + filepath.Base(outside),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("无法创建符号链接: %v", err)
+		}
+	}
+
+	cases := []struct {
+		path   string
+		escape bool
+	}{
+		{"escape", true},
+		{"escape/file.txt", true},
+		{"escape/new/dir", true},
+		{"relative/file.txt", true},
+		{"dangling", true},
+		{"dangling/file.txt", true},
+		{"internal", false},
+		{"internal/file.txt", false},
+	}
+	for _, c := range cases {
+		_, err := Resolve(root, c.path)
+		if c.escape && !errors.Is(err, ErrSymlinkEscape) {
+			t.Errorf("Resolve(%q) 应返回 ErrSymlinkEscape, 实际 %v", c.path, err)
+		}
+		if !c.escape && err != nil {
+			t.Errorf("Resolve(%q) 不应报错: %v", c.path, err)
+		}
+	}
+}
+
+func TestRel(t *testing.T) {
+	root := t.TempDir()
+	if got, err := Rel(root, filepath.Join(root, "a", "b")); err != nil || got != "a/b" {
+		t.Errorf("Rel = %q, %v", got, err)
+	}
+	if got, err := Rel(root, root); err != nil || got != "" {
+		t.Errorf("Rel(root) = %q, %v", got, err)
+	}
+	if _, err := Rel(root, filepath.Dir(root)); !errors.Is(err, ErrTraversal) {
+		t.Errorf("根目录之外应返回 ErrTraversal, 实际 %v", err)
+	}
+}
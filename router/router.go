@@ -2,8 +2,12 @@ package router
 
 import (
 	"FileNest/common/glog"
+	"FileNest/common/middlewares"
 	"FileNest/internal/controller"
 	"FileNest/internal/service"
+	"FileNest/internal/storage"
+	"FileNest/internal/tus"
+	"net/http"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -16,38 +20,119 @@ import (
   @date: 2024/9/22
 **/
 
-// Install 安装路由
-func Install(app *gin.Engine) {
+// Install 安装路由，temp 为文件服务使用的临时存储
+func Install(app *gin.Engine, temp storage.Driver, fileService service.FileService, userService service.UserService, tokenService service.TokenService, aclService service.ACLService, uploadGCService service.UploadGCService, fetchService service.FetchService) {
 
 	RegisterGlobalMiddleware(app)
 
 	index := app.Group("/")
 
-	fileController := controller.NewFileController(service.NewFileService())
+	fileController := controller.NewFileController(fileService)
+	davController := controller.NewDavController(fileService, "/dav")
+	tusController := controller.NewTusController(fileService, temp, "/api/tus")
+	authController := controller.NewAuthController(userService)
+	userController := controller.NewUserController(userService)
+	tokenController := controller.NewTokenController(tokenService)
+	aclController := controller.NewACLController(aclService)
+	groupController := controller.NewGroupController(aclService)
+	uploadGCController := controller.NewUploadGCController(uploadGCService)
+	fetchController := controller.NewFetchController(fetchService)
 
 	api := index.Group("/api")
+	authRequired := middlewares.Auth(userService)
 
-	file := api.Group("/file")
+	auth := api.Group("/auth")
+	auth.POST("/login", authController.Login)
+	auth.POST("/refresh", authController.Refresh)
+	auth.POST("/logout", authRequired, authController.Logout)
+	auth.GET("/me", authRequired, authController.Me)
+
+	user := api.Group("/user", authRequired)
+	user.POST("/password", userController.ChangePassword)
+	user.GET("/list", middlewares.RequireAdmin(), userController.ListUsers)
+	user.POST("/create", middlewares.RequireAdmin(), userController.CreateUser)
+
+	admin := api.Group("/admin", authRequired, middlewares.RequireAdmin())
+	admin.GET("/upload-gc", uploadGCController.GetUploadGC)
+	admin.POST("/upload-gc", uploadGCController.RunUploadGC)
+
+	// API 令牌只能由交互式登录的用户管理
+	token := api.Group("/token", authRequired)
+	token.POST("/create", tokenController.CreateToken)
+	token.GET("/list", tokenController.ListTokens)
+	token.DELETE("/revoke", tokenController.RevokeToken)
+
+	acl := api.Group("/acl", authRequired)
+	acl.GET("", aclController.GetACL)
+	acl.PUT("", aclController.SetACL)
+	acl.DELETE("", aclController.RemoveACL)
+
+	group := api.Group("/group", authRequired)
+	group.GET("/list", groupController.ListGroups)
+	group.POST("/create", middlewares.RequireAdmin(), groupController.CreateGroup)
+	group.POST("/member", middlewares.RequireAdmin(), groupController.AddMember)
+	group.DELETE("/member", middlewares.RequireAdmin(), groupController.RemoveMember)
+
+	// 文件接口同时接受 API 令牌
+	file := api.Group("/file", middlewares.AuthWithToken(userService, tokenService))
 	file.GET("/list", fileController.GetFileList)
 	file.GET("/stats", fileController.GetFileStats)
 	file.GET("/search", fileController.SearchFiles)
 	file.GET("/favorites", fileController.GetFavorites)
 	file.POST("/create-folder", fileController.CreateFolder)
 	file.POST("/upload", fileController.UploadFile)
-	file.POST("/upload-chunk", fileController.UploadChunk)
-	file.POST("/merge-chunks", fileController.MergeChunks)
+	file.POST("/upload-folder", fileController.UploadFolder)
+	file.PUT("/content", fileController.UploadContent)
+	file.GET("/upload-status", fileController.GetUploadStatus)
+	file.GET("/upload-events", fileController.UploadEvents)
+	file.POST("/instant-upload", fileController.InstantUpload)
+	file.POST("/upload-session", fileController.CreateUploadSession)
+	file.GET("/upload-session", fileController.GetUploadSession)
+	file.POST("/upload-session/chunk", fileController.UploadSessionChunk)
+	file.POST("/upload-session/merge", fileController.MergeUploadSession)
+	file.DELETE("/upload-session", fileController.AbortUploadSession)
+	file.POST("/fetch", fetchController.CreateFetchJob)
+	file.GET("/fetch", fetchController.GetFetchJob)
+	file.DELETE("/fetch", fetchController.CancelFetchJob)
 	file.POST("/favorite", fileController.AddFavorite)
 	file.GET("/download", fileController.DownloadFile)
+	file.HEAD("/download", fileController.DownloadFile)
+	file.POST("/archive", fileController.DownloadArchive)
 	file.DELETE("/delete", fileController.DeleteFile)
 	file.DELETE("/favorite", fileController.RemoveFavorite)
 	file.POST("/rename", fileController.RenameFile)
 	file.POST("/copy", fileController.CopyFile)
 	file.POST("/move", fileController.MoveFile)
+
+	// tus 断点续传协议，OPTIONS 用于客户端探测服务能力，无需登录
+	tusGroup := api.Group("/tus")
+	tusGroup.OPTIONS("", tusController.ServeTus)
+	tusGroup.OPTIONS("/:id", tusController.ServeTus)
+	tusAuth := tusGroup.Group("", middlewares.AuthWithToken(userService, tokenService))
+	for _, method := range tus.Methods {
+		if method == http.MethodOptions {
+			continue
+		}
+		tusAuth.Handle(method, "", tusController.ServeTus)
+		tusAuth.Handle(method, "/:id", tusController.ServeTus)
+	}
+
+	// WebDAV，与 /api/file 共享同一文件树
+	dav := index.Group("/dav", middlewares.BasicAuth(userService, tokenService, "FileNest"))
+	for _, method := range controller.DavMethods {
+		dav.Handle(method, "", davController.ServeDAV)
+		dav.Handle(method, "/*path", davController.ServeDAV)
+	}
 }
 
 // RegisterGlobalMiddleware 注册全局中间件
 func RegisterGlobalMiddleware(app *gin.Engine) {
-	app.Use(cors.Default())
+	// 在 cors.Default 的基础上允许 tus 协议头，否则浏览器中的 tus 客户端无法跨域续传
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AddAllowHeaders(tus.RequestHeaders...)
+	corsConfig.AddExposeHeaders(tus.ResponseHeaders...)
+	app.Use(cors.New(corsConfig))
 
 	app.Use(ginzap.Ginzap(glog.GetLogger(), time.RFC3339, true))
 
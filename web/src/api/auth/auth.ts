@@ -0,0 +1,15 @@
+import { get, post } from '@/utils/request'
+import { getRefreshToken } from '@/utils/auth'
+import type { LoginResult, User } from '@/types/auth'
+
+export const login = (username: string, password: string) => {
+  return post<LoginResult>('/auth/login', { username, password })
+}
+
+export const logout = () => {
+  return post('/auth/logout', { refreshToken: getRefreshToken() }, { showError: false })
+}
+
+export const getCurrentUser = () => {
+  return get<User>('/auth/me')
+}
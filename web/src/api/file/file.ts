@@ -1,7 +1,8 @@
 import { get, post, del, download } from '@/utils/request'
 import type { AxiosProgressEvent } from 'axios'
-import type { FileInfo, Favorite, FileStats } from '@/types/file'
+import type { FileInfo, Favorite, FileStats, UploadSession } from '@/types/file'
 import { uploadConfig } from '@/config/upload'
+import { storage } from '@/utils/storage'
 
 export interface UploadParams {
   indexChunk: number
@@ -45,41 +46,100 @@ export const downloadFile = (path: string) => {
 }
 
 /**
- * 上传文件分块
+ * 计算分块或文件的 SHA-256，非安全上下文中浏览器不提供 crypto.subtle，此时返回空字符串
  */
-export const uploadFileChunk = async ({
-  file,
+const chunkHash = async (chunk: Blob) => {
+  if (!window.crypto?.subtle) {
+    return ''
+  }
+  const digest = await window.crypto.subtle.digest('SHA-256', await chunk.arrayBuffer())
+  return Array.from(new Uint8Array(digest), (b) => b.toString(16).padStart(2, '0')).join('')
+}
+
+// 计算整个文件的摘要需要将文件读入内存，超过该大小不尝试秒传
+const INSTANT_UPLOAD_MAX_SIZE = 256 * 1024 * 1024
+
+/**
+ * 秒传：服务端已有相同内容时直接完成上传，返回是否成功
+ */
+export const instantUpload = async (file: File, path: string, override: boolean) => {
+  if (file.size > INSTANT_UPLOAD_MAX_SIZE) {
+    return false
+  }
+  try {
+    const hash = await chunkHash(file)
+    if (!hash) {
+      return false
+    }
+    const { data } = await post<{ uploaded: boolean; path: string }>(
+      '/file/instant-upload',
+      { path, fileName: file.name, hash, size: file.size, override },
+      { showError: false }
+    )
+    return data.uploaded
+  } catch {
+    // 秒传失败时正常上传
+    return false
+  }
+}
+
+/**
+ * 创建分块上传会话
+ */
+export const createUploadSession = (params: {
+  path: string
+  fileName: string
+  size: number
+  chunkSize: number
+  override: boolean
+}) => {
+  return post<UploadSession>('/file/upload-session', params)
+}
+
+/**
+ * 获取上传会话，用于断点续传
+ */
+export const getUploadSession = (id: string) => {
+  return get<UploadSession>('/file/upload-session', { id }, { showError: false })
+}
+
+/**
+ * 取消上传会话
+ */
+export const abortUploadSession = (id: string) => {
+  return del('/file/upload-session', { id })
+}
+
+/**
+ * 上传会话的分块
+ */
+export const uploadSessionChunk = async ({
+  sessionId,
   chunk,
-  path,
-  fileName,
   chunkIndex,
-  totalChunks,
-  override = false,
   onProgress,
   onSuccess,
   onError
 }: {
-  file: File
+  sessionId: string
   chunk: Blob
-  path: string
-  fileName: string
   chunkIndex: number
-  totalChunks: number
-  override?: boolean
   onProgress?: (progress: number) => void
   onSuccess?: () => void
   onError?: (error: string) => void
 }) => {
   const formData = new FormData()
   formData.append('file', chunk)
-  formData.append('fileName', fileName)
-  formData.append('path', path)
-  formData.append('override', override.toString())
+  formData.append('sessionId', sessionId)
   formData.append('chunkIndex', chunkIndex.toString())
-  formData.append('totalChunks', totalChunks.toString())
 
   try {
-    await post('/file/upload-chunk', formData, {
+    const hash = await chunkHash(chunk)
+    if (hash) {
+      formData.append('hashAlgorithm', 'sha256')
+      formData.append('hash', hash)
+    }
+    await post('/file/upload-session/chunk', formData, {
       headers: {
         'Content-Type': 'multipart/form-data'
       },
@@ -96,31 +156,53 @@ export const uploadFileChunk = async ({
   }
 }
 
+// 本地记录未完成的上传会话，页面刷新后可以继续上传
+const sessionStorageKey = (file: File, path: string) =>
+  `upload_session:${path}/${file.name}:${file.size}:${file.lastModified}`
+
 /**
- * 合并文件分块
+ * 打开上传会话：优先继续本地记录的未完成会话，否则创建新会话
  */
-export const mergeFileChunks = async ({
-  path,
-  fileName,
-  totalChunks,
-  override = false,
+const openUploadSession = async (file: File, path: string, override: boolean) => {
+  const key = sessionStorageKey(file, path)
+  const savedId = storage.get(key)
+  if (savedId) {
+    try {
+      const { data } = await getUploadSession(savedId)
+      if (data.status === 'uploading' && data.size === file.size && data.override === override) {
+        return data
+      }
+    } catch {
+      // 会话已过期，重新创建
+    }
+    storage.remove(key)
+  }
+
+  const { data } = await createUploadSession({
+    path,
+    fileName: file.name,
+    size: file.size,
+    chunkSize: uploadConfig.chunkSize,
+    override
+  })
+  storage.set(key, data.id)
+  return data
+}
+
+/**
+ * 合并上传会话的分块
+ */
+export const mergeUploadSession = async ({
+  sessionId,
   onSuccess,
   onError
 }: {
-  path: string
-  fileName: string
-  totalChunks: number
-  override?: boolean
+  sessionId: string
   onSuccess?: () => void
   onError?: (error: string) => void
 }) => {
   try {
-    await post('/file/merge-chunks', {
-      fileName,
-      path,
-      totalChunks,
-      override
-    })
+    await post('/file/upload-session/merge', { sessionId })
     onSuccess?.()
   } catch (error: any) {
     onError?.(error.response?.data?.message || '合并文件失败')
@@ -138,6 +220,12 @@ export const uploadFile = async ({
   onSuccess,
   onError
 }: UploadFileParams) => {
+  if (await instantUpload(file, path, override)) {
+    onProgress?.(100)
+    onSuccess?.()
+    return
+  }
+
   // 如果文件小于阈值或未启用分块上传，直接上传
   if (!uploadConfig.enableChunked || file.size <= uploadConfig.chunkThreshold) {
     const formData = new FormData()
@@ -165,37 +253,40 @@ export const uploadFile = async ({
     return
   }
 
-  // 文件分块
-  const totalChunks = Math.ceil(file.size / uploadConfig.chunkSize)
-  
   try {
+    // 断点续传：继续未完成的会话，跳过服务端已收到的分块
+    const session = await openUploadSession(file, path, override)
+    const { id: sessionId, chunkSize, totalChunks } = session
+    const receivedChunks = new Set(session.chunks.map(({ index }) => index))
+
     // 创建进度追踪器
-    const chunkProgress = new Array(totalChunks).fill(0)
+    const chunkProgress = Array.from({ length: totalChunks }, (_, index) =>
+      receivedChunks.has(index) ? 100 : 0
+    )
     const updateTotalProgress = () => {
       const totalProgress = Math.round(
-        chunkProgress.reduce((acc, curr) => acc + curr, 0) / totalChunks
+        chunkProgress.reduce((acc, curr) => acc + curr, 0) / Math.max(totalChunks, 1)
       )
       onProgress?.(totalProgress)
     }
 
     // 并发控制
-    let completedChunks = 0
+    let completedChunks = receivedChunks.size
+    updateTotalProgress()
     
-    // 创建所有分块的上传任务
-    const uploadTasks = Array.from({ length: totalChunks }, (_, index) => {
-      const start = index * uploadConfig.chunkSize
-      const end = Math.min(start + uploadConfig.chunkSize, file.size)
+    // 创建未上传分块的上传任务
+    const pendingIndexes = Array.from({ length: totalChunks }, (_, index) => index)
+      .filter(index => !receivedChunks.has(index))
+    const uploadTasks = pendingIndexes.map(index => {
+      const start = index * chunkSize
+      const end = Math.min(start + chunkSize, file.size)
       const chunk = file.slice(start, end)
       
       return async () => {
-        await uploadFileChunk({
-          file,
+        await uploadSessionChunk({
+          sessionId,
           chunk,
-          path,
-          fileName: file.name,
           chunkIndex: index,
-          totalChunks,
-          override,
           onProgress: (progress) => {
             chunkProgress[index] = progress
             updateTotalProgress()
@@ -236,12 +327,12 @@ export const uploadFile = async ({
 
     // 所有分块上传完成后，请求合并文件
     if (completedChunks === totalChunks) {
-      await mergeFileChunks({
-        path,
-        fileName: file.name,
-        totalChunks,
-        override,
-        onSuccess,
+      await mergeUploadSession({
+        sessionId,
+        onSuccess: () => {
+          storage.remove(sessionStorageKey(file, path))
+          onSuccess?.()
+        },
         onError
       })
     }
@@ -250,14 +341,6 @@ export const uploadFile = async ({
   }
 }
 
-/**
- * 获取文件下载链接
- * @param path 文件路径
- */
-export const getDownloadUrl = (path: string) => {
-  return `/api/file/download?path=${encodeURIComponent(path)}`
-}
-
 // 获取文件统计信息
 export const getFileStats = (params: GetFileStatsParams) => {
   return get<FileStats>('/file/stats', params)
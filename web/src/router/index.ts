@@ -1,9 +1,16 @@
 import { createRouter, createWebHashHistory } from 'vue-router'
 import HomeView from '../views/HomeView.vue'
+import { useUserStore } from '@/stores/user'
 
 const router = createRouter({
   history: createWebHashHistory(import.meta.env.BASE_URL),
   routes: [
+    {
+      path: '/login',
+      name: 'login',
+      component: () => import('../views/LoginView.vue'),
+      meta: { public: true }
+    },
     {
       path: '/',
       name: 'home',
@@ -20,4 +27,16 @@ const router = createRouter({
   ]
 })
 
+// 未登录时跳转到登录页，登录后回到原页面
+router.beforeEach(async (to) => {
+  if (to.meta.public) {
+    return true
+  }
+  const userStore = useUserStore()
+  if (await userStore.restore()) {
+    return true
+  }
+  return { name: 'login', query: { redirect: to.fullPath } }
+})
+
 export default router
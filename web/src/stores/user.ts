@@ -0,0 +1,47 @@
+import { ref, computed } from 'vue'
+import { defineStore } from 'pinia'
+import { login as loginApi, logout as logoutApi, getCurrentUser } from '@/api/auth/auth'
+import { getAccessToken, setTokens, clearTokens } from '@/utils/auth'
+import type { User } from '@/types/auth'
+
+export const useUserStore = defineStore('user', () => {
+  const user = ref<User | null>(null)
+  const isLoggedIn = computed(() => !!user.value)
+
+  // 登录并保存令牌
+  const login = async (username: string, password: string) => {
+    const { data } = await loginApi(username, password)
+    setTokens(data)
+    user.value = data.user
+  }
+
+  // 使用本地保存的令牌恢复登录状态，令牌无效时返回 false
+  const restore = async () => {
+    if (user.value) {
+      return true
+    }
+    if (!getAccessToken()) {
+      return false
+    }
+    try {
+      const { data } = await getCurrentUser()
+      user.value = data
+      return true
+    } catch {
+      clearTokens()
+      return false
+    }
+  }
+
+  // 退出登录，服务端吊销失败时也清除本地令牌
+  const logout = async () => {
+    try {
+      await logoutApi()
+    } finally {
+      clearTokens()
+      user.value = null
+    }
+  }
+
+  return { user, isLoggedIn, login, restore, logout }
+})
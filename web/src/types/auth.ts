@@ -0,0 +1,17 @@
+export interface User {
+  id: number
+  username: string
+  role: string
+  disabled: boolean
+}
+
+export interface TokenPair {
+  accessToken: string
+  refreshToken: string
+  tokenType: string
+  expiresIn: number
+}
+
+export interface LoginResult extends TokenPair {
+  user: User
+}
@@ -18,4 +18,28 @@ export interface Favorite {
   path: string
   isDir: boolean
   createTime: string
-} 
\ No newline at end of file
+}
+
+export interface ChunkInfo {
+  index: number
+  size: number
+}
+
+export interface UploadSession {
+  id: string
+  path: string
+  fileName: string
+  override: boolean
+  size: number
+  chunkSize: number
+  totalChunks: number
+  status: string
+  error?: string
+  filePath?: string
+  chunks: ChunkInfo[]
+  receivedChunks: number
+  receivedSize: number
+  progress: number
+  createdAt: string
+  expiresAt: string
+}
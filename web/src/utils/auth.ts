@@ -0,0 +1,53 @@
+import axios from 'axios'
+import { storage } from '@/utils/storage'
+import type { TokenPair } from '@/types/auth'
+
+const ACCESS_TOKEN_KEY = 'access_token'
+const REFRESH_TOKEN_KEY = 'refresh_token'
+
+export const getAccessToken = (): string | null => storage.get(ACCESS_TOKEN_KEY)
+
+export const getRefreshToken = (): string | null => storage.get(REFRESH_TOKEN_KEY)
+
+export const setTokens = (tokens: TokenPair) => {
+  storage.set(ACCESS_TOKEN_KEY, tokens.accessToken)
+  storage.set(REFRESH_TOKEN_KEY, tokens.refreshToken)
+}
+
+export const clearTokens = () => {
+  storage.remove(ACCESS_TOKEN_KEY)
+  storage.remove(REFRESH_TOKEN_KEY)
+}
+
+// 同一时间只发起一次刷新，其余请求等待同一个结果
+let refreshing: Promise<string> | null = null
+
+/**
+ * 使用刷新令牌换取新的访问令牌，失败时清除本地令牌
+ *
+ * 不经过 request 实例，避免刷新请求本身再次触发 401 处理。
+ */
+export const refreshAccessToken = () => {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const refreshToken = getRefreshToken()
+      if (!refreshToken) {
+        throw new Error('请先登录')
+      }
+      try {
+        const { data } = await axios.post('/api/auth/refresh', { refreshToken })
+        if (data.code !== 1000) {
+          throw new Error(data.message || '登录已过期')
+        }
+        setTokens(data.data)
+        return data.data.accessToken as string
+      } catch (error) {
+        clearTokens()
+        throw error
+      }
+    })().finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
+}
@@ -1,5 +1,6 @@
 import axios, { type AxiosRequestConfig, type AxiosResponse } from 'axios'
 import { createDiscreteApi } from 'naive-ui'
+import { getAccessToken, refreshAccessToken } from '@/utils/auth'
 
 const { message, loadingBar } = createDiscreteApi(['message', 'loadingBar'])
 
@@ -13,6 +14,10 @@ const request = axios.create({
 request.interceptors.request.use(
     (config) => {
         loadingBar.start()
+        const token = getAccessToken()
+        if (token) {
+            config.headers.Authorization = `Bearer ${token}`
+        }
         return config
     },
     (error) => {
@@ -26,6 +31,10 @@ request.interceptors.request.use(
 request.interceptors.response.use(
     (response) => {
         loadingBar.finish()
+        // 下载接口直接返回文件内容
+        if (response.config.responseType === 'blob') {
+            return response
+        }
         const { code, message: msg, data } = response.data
 
         // 这里可以根据后端的响应结构定制
@@ -53,8 +62,20 @@ request.interceptors.response.use(
 
         return Promise.reject(new Error(msg || '操作失败'))
     },
-    (error) => {
+    async (error) => {
         loadingBar.error()
+        const config = error.config as (AxiosRequestConfig & { _retried?: boolean }) | undefined
+        if (error.response?.status === 401 && config && !config._retried && !isAuthRequest(config.url)) {
+            // 访问令牌过期时刷新一次后重试，刷新失败则回到登录页
+            config._retried = true
+            try {
+                await refreshAccessToken()
+                return request(config)
+            } catch {
+                redirectToLogin()
+                return Promise.reject(error)
+            }
+        }
         if (error.response) {
             switch (error.response.status) {
                 case 401:
@@ -81,6 +102,18 @@ request.interceptors.response.use(
     }
 )
 
+// 登录与刷新接口返回 401 表示凭据错误，不再尝试刷新
+const isAuthRequest = (url?: string) => url === '/auth/login' || url === '/auth/refresh'
+
+const redirectToLogin = async () => {
+    // 动态导入避免与路由模块循环依赖
+    const { default: router } = await import('@/router')
+    if (router.currentRoute.value.name !== 'login') {
+        message.warning('登录已过期，请重新登录')
+        router.push({ name: 'login', query: { redirect: router.currentRoute.value.fullPath } })
+    }
+}
+
 export interface Response<T = any> {
     code: number
     message: string
@@ -177,18 +210,11 @@ export async function del<T = any>(
 // 下载文件专用方法
 export const download = async (url: string, params: Record<string, any>) => {
     try {
-        // 创建一个新的 axios 实例，专门用于下载
-        const downloadInstance = axios.create({
-            baseURL: '/api',
-            timeout: 30000,
-            responseType: 'blob'
-        })
-
-        const response = await downloadInstance.get(url, {
+        // 使用 request 实例以携带访问令牌，过期时同样会刷新后重试
+        const response = await request.get(url, {
             params,
-            headers: {
-                'Content-Type': 'application/json'
-            }
+            responseType: 'blob',
+            timeout: 0
         })
 
         if (response.data instanceof Blob) {